@@ -0,0 +1,87 @@
+package yamlpatch
+
+// tryAddAppending performs an add operation whose path has a "-" before its
+// last segment, as in /spec/containers/-/env/-. A "-" that is looked up in an
+// array of the document appends a new element to it, and the element is
+// created holding the value at the rest of the path: each later "-" creates an
+// array with a single element, and every other segment a map with a single
+// key. The path is followed through the document up to that point, and where
+// a key of a map is missing, it is created holding the structure for the rest
+// of the path, as when the "-" after it creates an array. A "-" that is looked
+// up in a map is a key like any other. It returns false when the path has no
+// "-" before its last segment that appends to an array or creates one.
+func tryAddAppending(doc Container, op *Operation) (bool, error) {
+	if op.Path.ContainsExtendedSyntax() {
+		return false, nil
+	}
+
+	parts, key, err := op.Path.Decompose()
+	if err != nil || indexOf(parts, "-") < 0 {
+		return false, nil
+	}
+
+	var val interface{}
+	if !op.Value.Empty() {
+		val = op.Value.plain()
+	}
+
+	con := doc
+	if h, ok := con.(*rootHolder); ok {
+		con = h.root.Container()
+	}
+
+	for i, part := range parts {
+		if con == nil {
+			return false, nil
+		}
+
+		if _, ok := con.(*nodeSlice); ok && part == "-" {
+			elem := nested(append(parts[i+1:], key), val)
+			return true, con.Add("-", NewNode(&elem))
+		}
+
+		node, err := con.Get(decodePatchKey(part))
+		if err != nil {
+			return false, nil
+		}
+
+		if node == nil {
+			if _, ok := con.(*nodeMap); !ok || indexOf(parts[i+1:], "-") < 0 {
+				return false, nil
+			}
+
+			elem := nested(append(parts[i+1:], key), val)
+			return true, con.Add(decodePatchKey(part), NewNode(&elem))
+		}
+
+		con = node.Container()
+	}
+
+	return false, nil
+}
+
+// nested returns the value held at the end of the given path segments within
+// a structure created to hold it
+func nested(segments []string, val interface{}) interface{} {
+	if len(segments) == 0 {
+		return val
+	}
+
+	child := nested(segments[1:], val)
+
+	if segments[0] == "-" {
+		return []interface{}{child}
+	}
+
+	return map[interface{}]interface{}{decodePatchKey(segments[0]): child}
+}
+
+func indexOf(parts []string, s string) int {
+	for i, part := range parts {
+		if part == s {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,69 @@
+package yamlpatch
+
+import (
+	"fmt"
+	"strings"
+)
+
+// defaultAppendToken is the path segment that refers to the end of an array
+const defaultAppendToken = "-"
+
+// checkAppendToken returns an error when the token cannot be used as a path
+// segment
+func checkAppendToken(token string) error {
+	if strings.Contains(token, "/") {
+		return fmt.Errorf("append token cannot contain '/': %s", token)
+	}
+
+	return nil
+}
+
+// withAppendToken returns a copy of the operation, whose paths have been
+// expanded within the given container, that refers to the end of an array
+// with "-" wherever its path and from use the given token to
+func (o *Operation) withAppendToken(c Container, token string) *Operation {
+	if token == "" || token == defaultAppendToken {
+		return o
+	}
+
+	op := *o
+	op.Path = replaceAppendToken(c, o.Path, token)
+	op.From = replaceAppendToken(c, o.From, token)
+
+	return &op
+}
+
+// replaceAppendToken returns the path with "-" in place of each segment that
+// is the token and refers to the end of an array: one that is looked up in an
+// array of the document, or one beyond the document, where it creates an
+// array as "-" does. A segment that is the token and is looked up in a map is
+// a key like any other.
+func replaceAppendToken(c Container, path OpPath, token string) OpPath {
+	parts := strings.Split(string(path), "/")
+
+	con := c
+	for i := 1; i < len(parts); i++ {
+		if con == nil {
+			if parts[i] == token {
+				parts[i] = defaultAppendToken
+			}
+			continue
+		}
+
+		if _, ok := con.(*nodeSlice); ok && parts[i] == token {
+			parts[i] = defaultAppendToken
+			con = nil
+			continue
+		}
+
+		node, err := con.Get(decodePatchKey(parts[i]))
+		if node == nil || err != nil {
+			con = nil
+			continue
+		}
+
+		con = node.Container()
+	}
+
+	return OpPath(strings.Join(parts, "/"))
+}
@@ -0,0 +1,356 @@
+package yamlpatch
+
+import (
+	"fmt"
+	"sort"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// ApplyOptions controls how a Patch is applied to a document and how the
+// result is marshaled
+type ApplyOptions struct {
+	// SortKeys recursively sorts the keys of every map in the output
+	// lexically, producing a canonical form suitable for hashing. Scalars and
+	// the order of array elements are unaffected.
+	SortKeys bool
+
+	// OnOperation, when set, is called after each operation is performed
+	// with the operation's index within the patch and the error it produced,
+	// if any. It is called for the failing operation before the error is
+	// returned, but not for operations that are skipped, which
+	// ApplyWithReport reports. It does not affect how the patch is applied.
+	OnOperation func(index int, op Operation, err error)
+
+	// BeforeOperation, when set, is called before each operation is
+	// performed with the operation's index within the patch, so that it and
+	// OnOperation bracket each step. It is not called for operations that
+	// are skipped before they are performed, and one skipped as it is
+	// performed, such as an add with on_existing: skip whose key exists or
+	// one disabled by a variable, is reported skipped without a call to
+	// OnOperation. It does not affect how the patch is applied.
+	BeforeOperation func(index int, op Operation)
+
+	// MaxDepth is the maximum nesting depth of maps and slices allowed in the
+	// document being patched. When it is 0, DefaultMaxDepth is used. Documents
+	// that are nested more deeply, or that contain cycles, are rejected, as
+	// are operations whose values would nest the document more deeply and
+	// results that are nested more deeply.
+	MaxDepth int
+
+	// QuoteStrings emits every string value in the output double-quoted,
+	// including strings that would otherwise be emitted as block scalars.
+	// Numbers, bools and nulls are left unquoted, as are map keys.
+	QuoteStrings bool
+
+	// QuoteKeys double-quotes the keys of maps in the output, for strict
+	// downstream parsers: every key that is a string with QuoteAllKeys, or
+	// only those that are not identifiers with QuoteNonIdentifierKeys. Keys
+	// that are numbers or bools are left unquoted, as quoting them would make
+	// them strings. It is independent of QuoteStrings, which quotes values,
+	// but is an option that styles individual nodes like it. By default keys
+	// are quoted only when they must be.
+	QuoteKeys KeyQuoting
+
+	// CaseInsensitiveKeys matches the keys of paths to map keys regardless of
+	// case, so that /metadata/name also refers to a Name or NAME key. A path
+	// whose key matches more than one map key fails. Keys within key=value
+	// and recursive search paths are still matched exactly.
+	CaseInsensitiveKeys bool
+
+	// AppendToken is the path segment that refers to the end of an array, as
+	// in /items/+ with a token of +, for documents whose conventions make "-"
+	// awkward. When it is empty, "-" is used. Like "-", the token appends only
+	// where it is looked up in an array, and refers to a key of maps. A path
+	// that still uses "-" also appends to arrays. The token cannot contain '/'.
+	AppendToken string
+
+	// BestEffort skips operations that fail rather than stopping at the first
+	// of them. The document is returned with every other operation performed,
+	// along with a *MultiError holding an *ApplyError for each operation that
+	// failed. The effects a failing operation had before it failed, such as
+	// those on the paths of a path list preceding the one that failed, remain.
+	BestEffort bool
+
+	// OmitEmpty leaves empty maps and sequences out of the output rather than
+	// emitting them as {} and []. It applies to every empty collection in the
+	// document when it is marshaled, whether the patch emptied it or it was
+	// empty in the source. Collections left empty by omitting their contents
+	// are omitted too. The root of the document is always emitted.
+	OmitEmpty bool
+
+	// YAMLVersion is the version of the YAML specification by which plain
+	// scalars in the document are resolved. When it is empty, YAML11 is used.
+	YAMLVersion YAMLVersion
+
+	// RejectIntOverflow rejects documents containing an integer too large for
+	// 64 bits, which would otherwise be decoded as a float, losing precision
+	RejectIntOverflow bool
+
+	// RejectConflicts fails the patch before any operation is performed when
+	// two of its operations modify the same path, as reported by Conflicts,
+	// rather than letting the later operation win
+	RejectConflicts bool
+
+	// NoLineWrap leaves every string of the output on a single line rather
+	// than wrapping long strings onto continuation lines at go-yaml's width
+	// of 80. The rest of the output is emitted as it is without it.
+	NoLineWrap bool
+
+	// RejectNonFinite fails operations whose value is, or contains, a float
+	// that is NaN or infinite, such as .nan or .inf, which many parsers of
+	// the output cannot represent. Values already in the document, and those
+	// of test operations, are not checked.
+	RejectNonFinite bool
+
+	// MaxOutputBytes is the largest output, in bytes, that applying a patch
+	// may produce. A larger output fails with an *OutputSizeError instead of
+	// being returned. For a stream, the limit applies to the whole stream.
+	// When it is 0, the output is unlimited.
+	MaxOutputBytes int
+
+	// PreserveBlankLines keeps the blank lines that separate the top-level
+	// keys of the source document, emitting a blank line before a key of the
+	// output when one preceded it in the source and the key before it is
+	// the same in both. go-yaml v2 emits keys sorted, so only blank lines
+	// between keys whose order is unchanged are kept.
+	PreserveBlankLines bool
+
+	// StripDirectives leaves the %YAML and %TAG directives of the source
+	// document, or of each document of a stream, out of the output. By
+	// default they are kept.
+	StripDirectives bool
+
+	// OnlyChanged leaves the documents of a stream that the patch did not
+	// change out of the output, which then holds only the changed documents,
+	// in their order within the stream, for producing focused diffs. A
+	// document changes when its value differs from the source's or an
+	// operation gives it a comment. It has no effect on a document applied
+	// on its own.
+	OnlyChanged bool
+
+	// Environment selects the value of operations whose value is given as
+	// per-environment overrides by their value_by_env, {default: x,
+	// overrides: {prod: y}}: the override for the environment, or the default
+	// when there is none. When it is empty, the default is always used.
+	Environment string
+
+	// NoClobber fails any operation that would overwrite the value of an
+	// existing key, for generation flows that should only create values:
+	// add, copy, move and checksum operations must place their value at an
+	// absent key or insert it into an array, and a rename operation cannot
+	// overwrite. A patch with a replace operation, which always overwrites,
+	// fails before any operation is performed. Operations that change values
+	// in place, such as transform, are not affected.
+	NoClobber bool
+
+	// Logger, when set, receives debug traces of each operation as it is
+	// performed: the paths it expands to and its outcome
+	Logger Logger
+}
+
+// Logger receives debug traces of how a patch is applied
+type Logger interface {
+	Debugf(format string, args ...interface{})
+}
+
+func (o ApplyOptions) maxDepth() int {
+	if o.MaxDepth == 0 {
+		return DefaultMaxDepth
+	}
+
+	return o.MaxDepth
+}
+
+// applyContext holds the state of a single application of a patch
+type applyContext struct {
+	opts ApplyOptions
+
+	// vars holds the values bound by capture operations
+	vars map[string]interface{}
+
+	// root is the root of the document the patch is being applied to, which
+	// operations with the empty path "" replace
+	root *Node
+
+	// before, when set, is called with the operation's index and the document
+	// before each operation is performed
+	before func(index int, root *Node) error
+
+	// failed is the index of the operation that failed, or -1
+	failed int
+
+	// current is the index of the operation being performed
+	current int
+
+	// skipped records the operations that were skipped
+	skipped []SkipInfo
+
+	// raws holds the values given as value_raw
+	raws []rawValue
+
+	// comments holds the comments given by comment operations
+	comments comments
+
+	// sorted holds the paths of the maps whose keys sort_keys operations
+	// sorted
+	sorted []string
+
+	// document is the index of the document within its stream, and last
+	// that of the stream's last document
+	document, last int
+
+	// stream is whether the document is one of a stream, whose operations
+	// naming documents are performed once every document has been patched
+	stream bool
+}
+
+// skip records that the operation with the given index was skipped
+func (c *applyContext) skip(index int, op Operation, reason SkipReason) {
+	c.debugf("operation %d: skipping %s %s: %s", index, op.Op, op.Path, reason)
+	c.skipped = append(c.skipped, SkipInfo{Operation: index, Reason: reason})
+}
+
+// debugf traces a step of the application to the options' Logger, if any
+func (c *applyContext) debugf(format string, args ...interface{}) {
+	if c.opts.Logger != nil {
+		c.opts.Logger.Debugf(format, args...)
+	}
+}
+
+func newApplyContext(opts ApplyOptions) *applyContext {
+	return &applyContext{
+		opts:   opts,
+		vars:   map[string]interface{}{},
+		failed: -1,
+	}
+}
+
+// marshal marshals the document, restoring the given explicit tags of the
+// source document to the scalars that are untouched
+func (o ApplyOptions) marshal(root *Node, tags explicitTags) ([]byte, error) {
+	switch o.QuoteKeys {
+	case QuoteNoKeys, QuoteAllKeys, QuoteNonIdentifierKeys:
+	default:
+		return nil, fmt.Errorf("unsupported key quoting %s: only %s or %s is supported", o.QuoteKeys, QuoteAllKeys, QuoteNonIdentifierKeys)
+	}
+
+	var v interface{} = root
+
+	if o.SortKeys || o.OmitEmpty {
+		plain := root.plain()
+
+		if o.OmitEmpty {
+			plain, _ = omitEmpty(plain)
+		}
+
+		if o.SortKeys {
+			plain = sortKeys(plain)
+		}
+
+		v = plain
+	}
+
+	bs, err := o.codec().marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.NoLineWrap {
+		bs = editScalars(bs, unwrapScalar)
+	}
+
+	if o.styled() {
+		return o.restyle(bs, tags)
+	}
+
+	if len(tags) > 0 {
+		bs = editScalars(bs, tags.restoreScalar)
+	}
+
+	return bs, nil
+}
+
+// omitEmpty removes the empty maps and sequences within v, returning what
+// remains and whether it is itself an empty collection
+func omitEmpty(v interface{}) (interface{}, bool) {
+	switch it := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[interface{}]interface{}, len(it))
+		for k, v := range it {
+			if v, empty := omitEmpty(v); !empty {
+				m[k] = v
+			}
+		}
+		return m, len(m) == 0
+	case []interface{}:
+		s := make([]interface{}, 0, len(it))
+		for _, v := range it {
+			if v, empty := omitEmpty(v); !empty {
+				s = append(s, v)
+			}
+		}
+		return s, len(s) == 0
+	}
+
+	return v, false
+}
+
+// sortKeys converts every map within v into a yaml.MapSlice whose keys are in
+// lexical order, as keyLess orders them
+func sortKeys(v interface{}) interface{} {
+	switch it := v.(type) {
+	case map[interface{}]interface{}:
+		ms := make(yaml.MapSlice, 0, len(it))
+		for k, v := range it {
+			ms = append(ms, yaml.MapItem{Key: k, Value: sortKeys(v)})
+		}
+
+		sort.Slice(ms, func(i, j int) bool {
+			return keyLess(ms[i].Key, ms[j].Key)
+		})
+
+		return ms
+	case []interface{}:
+		s := make([]interface{}, len(it))
+		for i := range it {
+			s[i] = sortKeys(it[i])
+		}
+		return s
+	}
+
+	return v
+}
+
+// keyLess reports whether the map key a is ordered before b: lexically by
+// their text, and keys written the same, such as 1 and "1", by their kind, so
+// that the order is total and the same however the keys are iterated
+func keyLess(a, b interface{}) bool {
+	sa, sb := fmt.Sprint(a), fmt.Sprint(b)
+	if sa != sb {
+		return sa < sb
+	}
+
+	return keyRank(a) < keyRank(b)
+}
+
+// keyRank orders the kinds of map keys written the same: null, then bools,
+// integers, floats and strings
+func keyRank(k interface{}) int {
+	switch k.(type) {
+	case nil:
+		return 0
+	case bool:
+		return 1
+	case int, int8, int16, int32, int64:
+		return 2
+	case uint, uint8, uint16, uint32, uint64:
+		return 3
+	case float32, float64:
+		return 4
+	case string:
+		return 5
+	}
+
+	return 6
+}
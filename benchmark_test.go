@@ -0,0 +1,126 @@
+package yamlpatch_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	yamlpatch "github.com/krishicks/yaml-patch"
+)
+
+// benchmarkDoc returns a document of n services, each a map with nested maps
+// and sequences, resembling a typical deployment manifest
+func benchmarkDoc(n int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("services:\n")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&buf, `  service%d:
+    image: registry.example.com/service%d:1.0.%d
+    replicas: %d
+    env:
+      LOG_LEVEL: info
+      PORT: "80%02d"
+    ports:
+    - 80
+    - 443
+`, i, i, i, i%5+1, i%100)
+	}
+
+	return buf.Bytes()
+}
+
+var benchmarkPatch = yamlpatch.Patch{
+	{Op: "replace", Path: "/services/service0/replicas", Value: nodeOf(3)},
+	{Op: "add", Path: "/services/service0/env/DEBUG", Value: nodeOf("true")},
+	{Op: "remove", Path: "/services/service0/ports/0"},
+	{Op: "copy", From: "/services/service0/image", Path: "/services/service0/previous"},
+	{Op: "test", Path: "/services/service0/env/LOG_LEVEL", Value: nodeOf("info")},
+}
+
+func nodeOf(v interface{}) *yamlpatch.Node {
+	return yamlpatch.NewNode(&v)
+}
+
+var benchmarkSizes = []int{1, 10, 100, 1000}
+
+func BenchmarkApply(b *testing.B) {
+	for _, n := range benchmarkSizes {
+		doc := benchmarkDoc(n)
+
+		b.Run(fmt.Sprintf("services=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(doc)))
+
+			for i := 0; i < b.N; i++ {
+				_, err := benchmarkPatch.Apply(doc)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkApplySequential(b *testing.B) {
+	for _, n := range benchmarkSizes {
+		doc := benchmarkDoc(n)
+
+		b.Run(fmt.Sprintf("services=%d/bytes", n), func(b *testing.B) {
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				out := doc
+				for j := 0; j < 10; j++ {
+					patch := yamlpatch.Patch{{Op: "add", Path: yamlpatch.OpPath(fmt.Sprintf("/services/service0/env/VAR%d", j)), Value: nodeOf(j)}}
+
+					var err error
+					out, err = patch.Apply(out)
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("services=%d/node", n), func(b *testing.B) {
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				node, err := yamlpatch.ParseDocument(doc)
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				for j := 0; j < 10; j++ {
+					patch := yamlpatch.Patch{{Op: "add", Path: yamlpatch.OpPath(fmt.Sprintf("/services/service0/env/VAR%d", j)), Value: nodeOf(j)}}
+
+					err = patch.ApplyToNode(node, yamlpatch.ApplyOptions{})
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+
+				_, err = yamlpatch.MarshalNode(node, yamlpatch.ApplyOptions{})
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("services=%d/patches", n), func(b *testing.B) {
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				patches := make([]yamlpatch.Patch, 10)
+				for j := range patches {
+					patches[j] = yamlpatch.Patch{{Op: "add", Path: yamlpatch.OpPath(fmt.Sprintf("/services/service0/env/VAR%d", j)), Value: nodeOf(j)}}
+				}
+
+				_, err := yamlpatch.ApplyPatches(doc, patches, yamlpatch.ApplyOptions{})
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
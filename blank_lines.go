@@ -0,0 +1,98 @@
+package yamlpatch
+
+import (
+	"bytes"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// topLevelKey is a key of the top-level map of a document
+type topLevelKey struct {
+	key string
+
+	// line is the index of the first line of the entry, including any
+	// comment above the key
+	line int
+
+	// blankBefore is whether the entry is preceded by a blank line
+	blankBefore bool
+}
+
+// topLevelKeys returns the keys of the top-level map of the first document in
+// bs in the order they appear, or nil when it is not a map
+func topLevelKeys(bs []byte) []topLevelKey {
+	var doc yamlv3.Node
+	if yamlv3.Unmarshal(bs, &doc) != nil || len(doc.Content) == 0 {
+		return nil
+	}
+
+	m := doc.Content[0]
+	if m.Kind != yamlv3.MappingNode {
+		return nil
+	}
+
+	lines := bytes.Split(bs, []byte("\n"))
+
+	var keys []topLevelKey
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		k := m.Content[i]
+
+		line := k.Line - 1
+		if k.HeadComment != "" {
+			line -= strings.Count(k.HeadComment, "\n") + 1
+		}
+
+		keys = append(keys, topLevelKey{
+			key:         k.Value,
+			line:        line,
+			blankBefore: line > 0 && line <= len(lines) && len(bytes.TrimSpace(lines[line-1])) == 0,
+		})
+	}
+
+	return keys
+}
+
+// preserveBlankLines inserts a blank line before each top-level key of the
+// marshaled document that was preceded by one in the source document, when
+// the key before it is the same in both
+func preserveBlankLines(doc, bs []byte) []byte {
+	src := topLevelKeys(doc)
+	if len(src) == 0 {
+		return bs
+	}
+
+	// previous holds the key before each key of the source that is preceded
+	// by a blank line
+	previous := map[string]string{}
+	for i := 1; i < len(src); i++ {
+		if src[i].blankBefore {
+			previous[src[i].key] = src[i-1].key
+		}
+	}
+
+	out := topLevelKeys(bs)
+
+	blank := map[int]bool{}
+	for i := 1; i < len(out); i++ {
+		if prev, ok := previous[out[i].key]; ok && prev == out[i-1].key && !out[i].blankBefore {
+			blank[out[i].line] = true
+		}
+	}
+
+	if len(blank) == 0 {
+		return bs
+	}
+
+	lines := bytes.Split(bs, []byte("\n"))
+
+	spaced := make([][]byte, 0, len(lines)+len(blank))
+	for i, line := range lines {
+		if blank[i] {
+			spaced = append(spaced, nil)
+		}
+		spaced = append(spaced, line)
+	}
+
+	return bytes.Join(spaced, []byte("\n"))
+}
@@ -0,0 +1,77 @@
+package yamlpatch
+
+import "fmt"
+
+// onErrorRollback is the on_error policy of a block operation that restores
+// the document when one of its operations fails and continues with the patch
+const onErrorRollback = "rollback"
+
+// tryBlock performs the nested operations of a block operation in order
+// against the whole document. When one fails and the block's on_error is
+// rollback, the document and the variables bound by capture operations are
+// restored to what they were at the start of the block, and the block
+// succeeds, so that the patch continues with the next operation. Otherwise
+// the block fails with the nested operation's error.
+func tryBlock(op *Operation, ctx *applyContext) error {
+	switch op.OnError {
+	case "", onErrorRollback:
+	default:
+		return fmt.Errorf("yamlpatch %s operation has an unknown on_error policy: %s", op.Op, op.OnError)
+	}
+
+	if ctx.root == nil {
+		return fmt.Errorf("yamlpatch %s operation does not apply: no document to perform it on", op.Op)
+	}
+
+	root := ctx.root
+	saved := root.Clone()
+
+	vars := make(map[string]interface{}, len(ctx.vars))
+	for k, v := range ctx.vars {
+		vars[k] = v
+	}
+
+	// The block's operations stop at the first failure whatever the options,
+	// and its indices are not those of the patch the options' callbacks see
+	block := newApplyContext(ctx.opts)
+	block.opts.BestEffort = false
+	block.opts.OnOperation = nil
+	block.opts.BeforeOperation = nil
+	block.opts.RejectConflicts = false
+	block.vars = ctx.vars
+
+	block.comments = make(comments, len(ctx.comments))
+	for node, c := range ctx.comments {
+		block.comments[node] = c
+	}
+
+	// Restoring the document replaces its nodes, so the comments are found
+	// again by the pointers of their values
+	commented := ctx.comments.byPath(root)
+	block.sorted = append([]string(nil), ctx.sorted...)
+
+	err := op.Operations.applyTo(root, block)
+	for _, s := range block.skipped {
+		nested := append([]int{s.Operation}, s.Nested...)
+		ctx.skipped = append(ctx.skipped, SkipInfo{Operation: ctx.current, Nested: nested, Reason: s.Reason})
+	}
+	ctx.raws = append(ctx.raws, block.raws...)
+
+	if err == nil {
+		ctx.comments = block.comments
+		ctx.sorted = block.sorted
+		return nil
+	}
+
+	if op.OnError != onErrorRollback {
+		return fmt.Errorf("yamlpatch %s operation failed at operation %d: %s", op.Op, block.failed, err)
+	}
+
+	ctx.debugf("  rolled back block after operation %d failed: %s", block.failed, err)
+
+	*root = *saved
+	ctx.vars = vars
+	ctx.comments = commented.at(root)
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package yamlpatch_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("block operations", func() {
+	It("performs the operations of the block in order", func() {
+		actual, err := applyPatch(`---
+- op: block
+  operations:
+  - op: add
+    path: /b
+    value: 2
+  - op: copy
+    from: /b
+    path: /c
+`, "a: 1\n")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actual).To(Equal("a: 1\nb: 2\nc: 2\n"))
+	})
+
+	It("unbinds the variables captured within a rolled back block", func() {
+		_, err := applyPatch(`---
+- op: add
+  path: /b
+  value: 2
+- op: block
+  on_error: rollback
+  operations:
+  - op: replace
+    path: /a
+    value: 10
+  - op: capture
+    path: /a
+    as: a
+  - op: remove
+    path: /missing
+- op: add
+  path: /c
+  value: "{{var:a}}"
+`, "a: 1\n")
+		Expect(err).To(MatchError("yamlpatch add operation does not apply: undefined variable: a"))
+	})
+
+	It("rolls back the block and continues when on_error is rollback", func() {
+		actual, err := applyPatch(`---
+- op: block
+  on_error: rollback
+  operations:
+  - op: replace
+    path: /a
+    value: 10
+  - op: remove
+    path: /missing
+- op: add
+  path: /b
+  value: 2
+`, "a: 1\n")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actual).To(Equal("a: 1\nb: 2\n"))
+	})
+
+	It("fails with the error of the failing operation by default", func() {
+		_, err := applyPatch(`---
+- op: block
+  operations:
+  - op: add
+    path: /b
+    value: 2
+  - op: remove
+    path: /missing
+`, "a: 1\n")
+		Expect(err).To(MatchError(HavePrefix("yamlpatch block operation failed at operation 1: ")))
+	})
+
+	It("rejects unknown on_error policies", func() {
+		_, err := applyPatch(`[{op: block, on_error: ignore, operations: []}]`, "a: 1\n")
+		Expect(err).To(MatchError("yamlpatch block operation has an unknown on_error policy: ignore"))
+	})
+})
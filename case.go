@@ -0,0 +1,98 @@
+package yamlpatch
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// foldKeys replaces the keys within the operation's path and from that match
+// keys in the document case-insensitively with the keys as they appear in the
+// document. The last key of a rename operation's path is the new key, and is
+// kept as it is.
+func (o *Operation) foldKeys(c Container) error {
+	if o.Op == opRename {
+		i := strings.LastIndex(string(o.Path), "/")
+		if i > 0 {
+			parent, err := foldPathKeys(c, o.Path[:i])
+			if err != nil {
+				return err
+			}
+			o.Path = parent + o.Path[i:]
+		}
+	} else {
+		path, err := foldPathKeys(c, o.Path)
+		if err != nil {
+			return err
+		}
+		o.Path = path
+	}
+
+	if o.From != "" {
+		from, err := foldPathKeys(c, o.From)
+		if err != nil {
+			return err
+		}
+		o.From = from
+	}
+
+	return nil
+}
+
+// foldPathKeys returns the pointer with each key that names a map entry
+// case-insensitively replaced by the entry's key. Keys that match no entry,
+// and keys beneath them, are left as they are. It is an error for a key to
+// match more than one entry, even when one of them matches exactly.
+func foldPathKeys(c Container, path OpPath) (OpPath, error) {
+	if !strings.HasPrefix(string(path), "/") {
+		return path, nil
+	}
+
+	parts := strings.Split(string(path), "/")[1:]
+
+	for i, part := range parts {
+		m, ok := c.(*nodeMap)
+		if !ok {
+			if c == nil {
+				break
+			}
+
+			node, err := c.Get(decodePatchKey(part))
+			if err != nil || node == nil {
+				break
+			}
+
+			c = node.Container()
+			continue
+		}
+
+		seg := decodePatchKey(part)
+
+		var matches []string
+		for k := range *m {
+			if s, ok := k.(string); ok && strings.EqualFold(s, seg) {
+				matches = append(matches, s)
+			}
+		}
+
+		if len(matches) > 1 {
+			sort.Strings(matches)
+			return "", fmt.Errorf("path %s matches more than one key: %s", path, strings.Join(matches, ", "))
+		}
+
+		if len(matches) == 0 {
+			break
+		}
+
+		parts[i] = encodePatchKey(matches[0])
+
+		node := (*m)[matches[0]]
+		if node == nil {
+			break
+		}
+
+		c = node.Container()
+	}
+
+	return OpPath("/" + strings.Join(parts, "/")), nil
+}
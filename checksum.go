@@ -0,0 +1,37 @@
+package yamlpatch
+
+import "fmt"
+
+// tryChecksum sets the value at the operation's path to the hex-encoded
+// SHA-256 hash of the canonical form of the value at its from, as
+// DocumentSHA256 computes it, so that the path changes whenever the content
+// at from does, but not when only its formatting or key order does. This is
+// the "roll on config change" annotation of Kubernetes workloads.
+func tryChecksum(doc Container, op *Operation) error {
+	if op.From == "" {
+		return fmt.Errorf("yamlpatch checksum operation is missing a from path: %s", op.Path)
+	}
+
+	con, key, err := findContainer(doc, &op.From)
+	if err != nil {
+		return pathError(err, "yamlpatch checksum operation does not apply: doc is missing from path: %s", op.From)
+	}
+
+	val, err := con.Get(key)
+	if val == nil || err != nil {
+		return fmt.Errorf("yamlpatch checksum operation does not apply: doc is missing from path: %s", op.From)
+	}
+
+	sum, err := defaultCodec.canonicalSHA256(val.plain())
+	if err != nil {
+		return fmt.Errorf("yamlpatch checksum operation does not apply: %s", err)
+	}
+
+	con, key, err = findContainer(doc, &op.Path)
+	if err != nil {
+		return pathError(err, "yamlpatch checksum operation does not apply: doc is missing destination path: %s", op.Path)
+	}
+
+	var v interface{} = sum
+	return con.Set(key, NewNode(&v))
+}
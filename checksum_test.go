@@ -0,0 +1,51 @@
+package yamlpatch_test
+
+import (
+	yamlpatch "github.com/krishicks/yaml-patch"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("checksum", func() {
+	It("sets the path to the canonical SHA-256 hash of the value at from", func() {
+		sum, err := yamlpatch.DocumentSHA256([]byte("{a: 1, b: [c, d]}"))
+		Expect(err).NotTo(HaveOccurred())
+
+		actual, err := applyPatch(`[{op: checksum, from: /data, path: /metadata/checksum}]`, "data: {b: [c, d], a: 1}\nmetadata: {}\n")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actual).To(Equal("data:\n  a: 1\n  b:\n  - c\n  - d\nmetadata:\n  checksum: " + sum + "\n"))
+	})
+
+	It("replaces an existing value at the path", func() {
+		actual, err := applyPatch(`[{op: checksum, from: /data, path: /sum}]`, "data: x\nsum: old\n")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actual).NotTo(ContainSubstring("old"))
+	})
+
+	It("gives values that differ only in key order the same hash", func() {
+		ops := `[{op: checksum, from: /data, path: /sum}, {op: remove, path: /data}]`
+
+		first, err := applyPatch(ops, "data: {a: 1, b: 2}\n")
+		Expect(err).NotTo(HaveOccurred())
+
+		second, err := applyPatch(ops, "data:\n  b: 2\n  a: 1\n")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(first).To(Equal(second))
+
+		third, err := applyPatch(ops, "data: {a: 1, b: 3}\n")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(third).NotTo(Equal(first))
+	})
+
+	DescribeTable("fails",
+		func(ops, message string) {
+			_, err := applyPatch(ops, "data: {a: 1}\n")
+			Expect(err).To(MatchError(message))
+		},
+		Entry("without a from path", `[{op: checksum, path: /sum}]`, "yamlpatch checksum operation is missing a from path: /sum"),
+		Entry("for a missing from path", `[{op: checksum, from: /missing, path: /sum}]`, "yamlpatch checksum operation does not apply: doc is missing from path: /missing"),
+		Entry("for a missing destination path", `[{op: checksum, from: /data, path: /missing/sum}]`, "yamlpatch checksum operation does not apply: doc is missing destination path: /missing/sum"),
+	)
+})
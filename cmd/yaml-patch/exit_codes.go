@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// Exit codes
+const (
+	// exitUsage is used for invalid flags, inputs that cannot be read and
+	// outputs that cannot be written
+	exitUsage = 1
+
+	// exitDecode is used for ops files and documents that are not valid
+	exitDecode = 2
+
+	// exitApply is used when an operation cannot be applied to the document
+	exitApply = 3
+)
+
+// fail prints the error's message to stderr and exits with its code
+func fail(e *exitError) {
+	fmt.Fprintln(os.Stderr, e.msg)
+	os.Exit(e.code)
+}
+
+// exitError is an error that the CLI exits with the given code for
+type exitError struct {
+	code int
+	msg  string
+}
+
+func (e *exitError) Error() string {
+	return e.msg
+}
+
+// exitErrorf returns an exitError with the formatted message
+func exitErrorf(code int, format string, args ...interface{}) error {
+	return &exitError{code: code, msg: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+)
+
+// gzipMagic are the bytes every gzip stream begins with
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// decompressGzip returns the decompressed contents of gzip-compressed bytes,
+// or the bytes unchanged when they are not gzip-compressed
+func decompressGzip(bs []byte) ([]byte, error) {
+	if !bytes.HasPrefix(bs, gzipMagic) {
+		return bs, nil
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(bs))
+	if err != nil {
+		return nil, fmt.Errorf("failed decompressing gzip: %s", err)
+	}
+	defer r.Close()
+
+	out, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed decompressing gzip: %s", err)
+	}
+
+	return out, nil
+}
+
+// readFile returns the contents of the file at the path, decompressed when
+// it is gzip-compressed, to read ops files and their includes with
+func readFile(path string) ([]byte, error) {
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	bs, err = decompressGzip(bs)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+
+	return bs, nil
+}
+
+// compressGzip returns the bytes gzip-compressed
+func compressGzip(bs []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	w := gzip.NewWriter(&buf)
+
+	_, err := w.Write(bs)
+	if err != nil {
+		return nil, err
+	}
+
+	err = w.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"bytes"
+	"runtime"
+)
+
+const (
+	lineEndingsLF     = "lf"
+	lineEndingsCRLF   = "crlf"
+	lineEndingsNative = "native"
+)
+
+// convertLineEndings returns the content with every line ending converted to
+// the given style: lf, crlf, or native for the current platform's convention
+func convertLineEndings(content []byte, style string) []byte {
+	if style == lineEndingsNative {
+		style = lineEndingsLF
+		if runtime.GOOS == "windows" {
+			style = lineEndingsCRLF
+		}
+	}
+
+	lf := bytes.Replace(content, []byte("\r\n"), []byte("\n"), -1)
+	if style == lineEndingsCRLF {
+		return bytes.Replace(lf, []byte("\n"), []byte("\r\n"), -1)
+	}
+
+	return lf
+}
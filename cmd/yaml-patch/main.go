@@ -1,63 +1,315 @@
 package main
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"os"
+	"strings"
 
-	flags "github.com/jessevdk/go-flags"
 	yamlpatch "github.com/ACCELERATOR-SANDBOX/yaml-patch"
+	flags "github.com/jessevdk/go-flags"
 )
 
 type opts struct {
-	OpsFiles []FileFlag `long:"ops-file" short:"o" value-name:"PATH" description:"Path to file with one or more operations"`
+	OpsFiles   []FileFlag      `long:"ops-file" short:"o" value-name:"PATH" description:"Path to file with one or more operations"`
+	Sets       []SetFlag       `long:"set" value-name:"PATH=VALUE" description:"Value to set at a path, applied after any ops files"`
+	SetStrings []SetStringFlag `long:"set-string" value-name:"PATH=VALUE" description:"String value to set at a path, applied after any --set values"`
+	Strict     bool            `long:"strict" description:"Reject operations that contain unrecognized fields"`
+	OpsRange   *RangeFlag      `long:"ops-range" value-name:"START:END" description:"Apply only the operations of the ops files, numbered from 0 across them, from START up to but not including END"`
+	Reverse    bool            `long:"reverse" description:"Apply the operations last to first, including any --set values"`
+
+	RejectConflicts bool `long:"reject-conflicts" description:"Fail when two operations of an ops file modify the same path"`
+	Debug           bool `long:"debug" description:"Print a trace of each operation performed to stderr"`
+
+	Env string `long:"env" value-name:"ENV" description:"Environment whose overrides are used for values given by value_by_env as {default: ..., overrides: {ENV: ...}}"`
+
+	MaxDepth    int    `long:"max-depth" value-name:"DEPTH" description:"Maximum nesting depth allowed in the document (default: 10000)"`
+	LineEndings string `long:"line-endings" choice:"lf" choice:"crlf" choice:"native" default:"lf" description:"Line endings to use in the output"`
+
+	Out   string `long:"out" short:"O" value-name:"PATH" description:"Path to write the result to instead of stdout"`
+	Mkdir bool   `long:"mkdir" description:"Create missing parent directories of the --out path"`
+	Mode  string `long:"mode" value-name:"MODE" description:"Octal permissions of the --out file (default: those of the file replaced, or 0644)"`
+	Gzip  bool   `long:"gzip" description:"Gzip-compress the output"`
+
+	Doc   FileFlag `long:"doc" short:"d" value-name:"PATH" description:"Path to the document to patch instead of stdin"`
+	Base  FileFlag `long:"base" value-name:"PATH" description:"Path to the document to patch when stdin, or the --doc file, is empty"`
+	Watch bool     `long:"watch" description:"Apply again whenever the document or an ops file changes, until interrupted (requires --doc)"`
+
+	Values       FileFlag `long:"values" value-name:"PATH" description:"Path to a YAML map whose values resolve the {{ placeholders }} of the ops files and document, by dotted lookup"`
+	AllowMissing bool     `long:"allow-missing" description:"Leave placeholders that name no value unresolved instead of failing (requires --values)"`
+
+	NDJSON        bool `long:"ndjson" description:"Treat the document as line-delimited JSON, patching each line as a JSON document"`
+	SkipMalformed bool `long:"skip-malformed" description:"Skip lines that are not valid JSON, reporting them, instead of failing (requires --ndjson)"`
 }
 
 func main() {
+	err := run()
+	if err != nil {
+		fail(err.(*exitError))
+	}
+}
+
+// run parses the flags and patches the document once or, with --watch, each
+// time the inputs change. Every error it returns is an *exitError.
+func run() error {
 	var o opts
 	_, err := flags.Parse(&o)
 
 	if err != nil {
 		if flagsErr, ok := err.(*flags.Error); ok && flagsErr.Type == flags.ErrHelp {
-			os.Exit(0)
-		} else {
-			log.Fatalf("error: %s\n", err)
-			os.Exit(1)
+			return nil
 		}
+		return exitErrorf(exitUsage, "error: %s", err)
 	}
 
+	var mode os.FileMode
+	if o.Mode != "" {
+		if o.Out == "" {
+			return exitErrorf(exitUsage, "error: --mode requires --out")
+		}
+
+		mode, err = parseFileMode(o.Mode)
+		if err != nil {
+			return exitErrorf(exitUsage, "error: %s", err)
+		}
+	}
+
+	if o.Watch && o.Doc == "" {
+		return exitErrorf(exitUsage, "error: --watch requires --doc")
+	}
+
+	if o.SkipMalformed && !o.NDJSON {
+		return exitErrorf(exitUsage, "error: --skip-malformed requires --ndjson")
+	}
+
+	if o.AllowMissing && o.Values == "" {
+		return exitErrorf(exitUsage, "error: --allow-missing requires --values")
+	}
+
+	patch := func() error {
+		return patchDocument(o, mode)
+	}
+
+	if o.Watch {
+		paths := []string{o.Doc.Path()}
+		if o.Base != "" {
+			paths = append(paths, o.Base.Path())
+		}
+		if o.Values != "" {
+			paths = append(paths, o.Values.Path())
+		}
+		for _, opsFile := range o.OpsFiles {
+			paths = append(paths, opsFile.Path())
+		}
+
+		err = watch(paths, patch)
+		if err != nil {
+			return exitErrorf(exitUsage, "error watching inputs: %s", err)
+		}
+		return nil
+	}
+
+	return patch()
+}
+
+// applyOptions returns the options to apply patches with
+func (o opts) applyOptions() yamlpatch.ApplyOptions {
+	applyOpts := yamlpatch.ApplyOptions{
+		MaxDepth:        o.MaxDepth,
+		RejectConflicts: o.RejectConflicts,
+		Environment:     o.Env,
+	}
+
+	if o.Debug {
+		applyOpts.Logger = stderrLogger{}
+	}
+
+	return applyOpts
+}
+
+// stderrLogger prints debug traces to stderr
+type stderrLogger struct{}
+
+func (stderrLogger) Debugf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "debug: "+format+"\n", args...)
+}
+
+// patchDocument reads the document, applies the ops files and set values to
+// it, and writes the result
+func patchDocument(o opts, mode os.FileMode) error {
 	placeholderWrapper := yamlpatch.NewPlaceholderWrapper("{{", "}}")
 
+	decoder := yamlpatch.FileDecoder{
+		Strict:     o.Strict,
+		ReadFile:   readFile,
+		Preprocess: placeholderWrapper.Wrap,
+	}
+
+	// The placeholders of every file decoded, including those an ops file
+	// includes, are resolved before they are wrapped
+	var vals *values
+	var unresolved []string
+	if o.Values != "" {
+		var err error
+		vals, err = loadValues(o.Values.Path(), placeholderWrapper)
+		if err != nil {
+			return exitErrorf(exitDecode, "error decoding values file: %s", err)
+		}
+
+		decoder.Preprocess = func(bs []byte) []byte {
+			bs, missing := vals.resolve(bs)
+			unresolved = append(unresolved, missing...)
+			return placeholderWrapper.Wrap(bs)
+		}
+	}
+
 	var patches []yamlpatch.Patch
+	offset := 0
 	for _, opsFile := range o.OpsFiles {
-		var bs []byte
-		bs, err = ioutil.ReadFile(opsFile.Path())
+		patch, err := decoder.Decode(opsFile.Path())
 		if err != nil {
-			log.Fatalf("error reading opsfile: %s", err)
+			if os.IsNotExist(err) {
+				return exitErrorf(exitUsage, "error reading opsfile: %s", err)
+			}
+			return exitErrorf(exitDecode, "error decoding opsfile: %s", err)
 		}
 
+		if len(unresolved) > 0 && !o.AllowMissing {
+			return exitErrorf(exitDecode, "error resolving opsfile: %s: unresolved placeholders: %s", opsFile.Path(), strings.Join(unresolved, ", "))
+		}
+
+		if o.OpsRange != nil {
+			start, end := o.OpsRange.Start-offset, len(patch)
+			if o.OpsRange.End != -1 {
+				end = o.OpsRange.End - offset
+			}
+
+			offset += len(patch)
+			patch = patch.Slice(start, end)
+		}
+
+		patches = append(patches, patch)
+	}
+
+	if len(o.Sets) > 0 || len(o.SetStrings) > 0 {
 		var patch yamlpatch.Patch
-		patch, err = yamlpatch.DecodePatch(placeholderWrapper.Wrap(bs))
-		if err != nil {
-			log.Fatalf("error decoding opsfile: %s", err)
+		sets := o.Sets
+		for _, set := range o.SetStrings {
+			sets = append(sets, set.SetFlag)
+		}
+
+		for _, set := range sets {
+			op, err := set.Operation(o.applyOptions())
+			if err != nil {
+				return exitErrorf(exitUsage, "error: %s", err)
+			}
+			patch = append(patch, op)
 		}
 
 		patches = append(patches, patch)
 	}
 
-	doc, err := ioutil.ReadAll(os.Stdin)
+	if o.Reverse {
+		patches = []yamlpatch.Patch{yamlpatch.MergePatches(patches...).Reversed()}
+	}
+
+	doc, err := readDocument(o.Doc)
+	if err != nil {
+		return exitErrorf(exitUsage, "error reading document: %s", err)
+	}
+
+	// The base document is patched only in place of an empty one, so that
+	// operations can bootstrap a document that does not exist yet
+	if o.Base != "" && len(bytes.TrimSpace(doc)) == 0 {
+		doc, err = ioutil.ReadFile(o.Base.Path())
+		if err != nil {
+			return exitErrorf(exitUsage, "error reading base document: %s", err)
+		}
+	}
+
+	doc, err = decompressGzip(doc)
 	if err != nil {
-		log.Fatalf("error reading from stdin: %s", err)
+		return exitErrorf(exitDecode, "error decoding document: %s", err)
+	}
+
+	if vals != nil {
+		doc, unresolved = vals.resolve(doc)
+		if len(unresolved) > 0 && !o.AllowMissing {
+			return exitErrorf(exitDecode, "error resolving document: unresolved placeholders: %s", strings.Join(unresolved, ", "))
+		}
 	}
 
-	mdoc := placeholderWrapper.Wrap(doc)
-	for _, patch := range patches {
-		mdoc, err = patch.Apply(mdoc)
+	var output []byte
+	if o.NDJSON {
+		output, err = patchNDJSON(o, yamlpatch.MergePatches(patches...), doc)
+		if err != nil {
+			return err
+		}
+	} else {
+		mdoc, err := yamlpatch.ApplyPatches(placeholderWrapper.Wrap(doc), patches, o.applyOptions())
+		var applyErr *yamlpatch.ApplyError
+		if errors.As(err, &applyErr) && applyErr.Operation == -1 {
+			return exitErrorf(exitDecode, "error decoding document: %s", err)
+		}
 		if err != nil {
-			log.Fatalf("error applying patch: %s", err)
+			return exitErrorf(exitApply, "error applying patch: %s", err)
 		}
+
+		output = placeholderWrapper.Unwrap(mdoc)
+	}
+
+	output = convertLineEndings(output, o.LineEndings)
+
+	if o.Gzip {
+		output, err = compressGzip(output)
+		if err != nil {
+			return exitErrorf(exitUsage, "error compressing output: %s", err)
+		}
+	}
+
+	if o.Out != "" {
+		err = writeFileAtomic(o.Out, output, mode, o.Mkdir)
+		if err != nil {
+			return exitErrorf(exitUsage, "error writing output: %s", err)
+		}
+		return nil
+	}
+
+	fmt.Printf("%s", output)
+	return nil
+}
+
+// patchNDJSON applies the patch to each line of the line-delimited JSON
+// document. Malformed lines that are skipped are reported on stderr.
+func patchNDJSON(o opts, patch yamlpatch.Patch, doc []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	err := patch.ApplyNDJSON(bytes.NewReader(doc), &buf, yamlpatch.NDJSONOptions{
+		ApplyOptions:  o.applyOptions(),
+		SkipMalformed: o.SkipMalformed,
+	})
+
+	if multi, ok := err.(*yamlpatch.MultiError); ok {
+		for _, skipped := range multi.Errors() {
+			fmt.Fprintf(os.Stderr, "warning: skipped %s\n", skipped)
+		}
+	} else if errors.Is(err, yamlpatch.ErrMalformedJSON) {
+		return nil, exitErrorf(exitDecode, "error decoding document: %s", err)
+	} else if err != nil {
+		return nil, exitErrorf(exitApply, "error applying patch: %s", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// readDocument reads the document from the file, or from stdin when no file
+// is given
+func readDocument(f FileFlag) ([]byte, error) {
+	if f == "" {
+		return ioutil.ReadAll(os.Stdin)
 	}
 
-	fmt.Printf("%s", placeholderWrapper.Unwrap(mdoc))
+	return ioutil.ReadFile(f.Path())
 }
@@ -1,8 +1,17 @@
 package main_test
 
 import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
 	"github.com/onsi/gomega/gexec"
 )
 
@@ -11,4 +20,494 @@ var _ = Describe("yaml-patch", func() {
 		_, err := gexec.Build("github.com/ACCELERATOR-SANDBOX/yaml-patch/cmd/yaml-patch")
 		Expect(err).NotTo(HaveOccurred())
 	})
+
+	Describe("running", func() {
+		var pathToCLI string
+
+		BeforeEach(func() {
+			var err error
+			pathToCLI, err = gexec.Build("github.com/ACCELERATOR-SANDBOX/yaml-patch/cmd/yaml-patch")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		run := func(stdin string, args ...string) *gexec.Session {
+			cmd := exec.Command(pathToCLI, args...)
+			cmd.Stdin = strings.NewReader(stdin)
+
+			session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit())
+
+			return session
+		}
+
+		It("sets values given with --set", func() {
+			session := run(`---
+spec:
+  replicas: 1
+metadata:
+  labels: {}
+`, "--set", "/spec/replicas=3", "--set", "/metadata/labels/env=prod", "--set", "/metadata/version:string=2")
+
+			Expect(session.ExitCode()).To(Equal(0))
+			Expect(session.Out.Contents()).To(MatchYAML(`---
+spec:
+  replicas: 3
+metadata:
+  labels:
+    env: prod
+  version: "2"
+`))
+		})
+
+		It("sets values at paths with key=value segments given with --set", func() {
+			session := run(`{items: [{name: web, image: a}, {name: db, image: b}]}`, "--set", "/items/name=web/image=nginx:1=2", "--set", "/items/name=db/url=http://db/?a=b", "--set", "/env=A=B")
+
+			Expect(session.ExitCode()).To(Equal(0))
+			Expect(session.Out.Contents()).To(MatchYAML(`---
+items:
+- {name: web, image: "nginx:1=2"}
+- {name: db, image: b, url: "http://db/?a=b"}
+env: A=B
+`))
+		})
+
+		It("replaces existing elements of arrays given with --set rather than inserting", func() {
+			session := run(`{spec: {containers: [a, b]}}`, "--set", "/spec/containers/0=x", "--set", "/spec/containers/2=c", "--set", "/spec/containers/-=d")
+
+			Expect(session.ExitCode()).To(Equal(0))
+			Expect(session.Out.Contents()).To(MatchYAML(`{spec: {containers: [x, b, c, d]}}`))
+		})
+
+		It("sets collections given inline with --set and literal strings with --set-string", func() {
+			session := run(`{spec: {}}`, "--set", "/spec/ports=[80, 443]", "--set", `/spec/env={"A": 1}`, "--set-string", "/spec/version=1.10", "--set-string", "/spec/list=[a]")
+
+			Expect(session.ExitCode()).To(Equal(0))
+			Expect(session.Out.Contents()).To(MatchYAML(`---
+spec:
+  ports: [80, 443]
+  env: {A: 1}
+  version: "1.10"
+  list: "[a]"
+`))
+		})
+
+		It("exits 1 for an inline collection that cannot be parsed", func() {
+			Expect(run(`{}`, "--set", "/ports=[80").ExitCode()).To(Equal(1))
+		})
+
+		It("reads gzip-compressed input and compresses the output given --gzip", func() {
+			var in bytes.Buffer
+			w := gzip.NewWriter(&in)
+			_, err := w.Write([]byte(`foo: bar`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(w.Close()).To(Succeed())
+
+			session := run(in.String(), "--set", "/foo=baz", "--gzip")
+			Expect(session.ExitCode()).To(Equal(0))
+
+			r, err := gzip.NewReader(bytes.NewReader(session.Out.Contents()))
+			Expect(err).NotTo(HaveOccurred())
+			out, err := ioutil.ReadAll(r)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(out).To(MatchYAML(`foo: baz`))
+		})
+
+		It("exits 2 for truncated gzip-compressed input", func() {
+			var in bytes.Buffer
+			w := gzip.NewWriter(&in)
+			_, err := w.Write([]byte(`foo: bar`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(w.Close()).To(Succeed())
+
+			session := run(in.String()[:in.Len()-4], "--set", "/foo=baz")
+			Expect(session.ExitCode()).To(Equal(2))
+			Expect(string(session.Err.Contents())).To(ContainSubstring("failed decompressing gzip"))
+		})
+
+		It("reads gzip-compressed ops files and their includes", func() {
+			tmpDir, err := ioutil.TempDir("", "yaml-patch")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(tmpDir)
+
+			var compressed bytes.Buffer
+			w := gzip.NewWriter(&compressed)
+			_, err = w.Write([]byte(`[{op: add, path: /env, value: base}]`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(w.Close()).To(Succeed())
+
+			Expect(ioutil.WriteFile(filepath.Join(tmpDir, "base.yml.gz"), compressed.Bytes(), 0644)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(tmpDir, "ops.yml"), []byte(`include: [base.yml.gz]`), 0644)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(tmpDir, "truncated.yml.gz"), compressed.Bytes()[:compressed.Len()-4], 0644)).To(Succeed())
+
+			session := run(`foo: bar`, "-o", filepath.Join(tmpDir, "ops.yml"))
+			Expect(session.ExitCode()).To(Equal(0))
+			Expect(session.Out.Contents()).To(MatchYAML(`{foo: bar, env: base}`))
+
+			session = run(`foo: bar`, "-o", filepath.Join(tmpDir, "truncated.yml.gz"))
+			Expect(session.ExitCode()).To(Equal(2))
+			Expect(string(session.Err.Contents())).To(ContainSubstring("failed decompressing gzip"))
+		})
+
+		It("reads CRLF input and writes CRLF output given --line-endings crlf", func() {
+			session := run("---\r\nfoo: bar\r\nbaz: qux\r\n", "--set", "/foo=waldo", "--line-endings", "crlf")
+
+			Expect(session.ExitCode()).To(Equal(0))
+			Expect(string(session.Out.Contents())).To(Equal("baz: qux\r\nfoo: waldo\r\n"))
+		})
+
+		It("applies the operations of files included by an ops file", func() {
+			tmpDir, err := ioutil.TempDir("", "yaml-patch")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(tmpDir)
+
+			Expect(ioutil.WriteFile(filepath.Join(tmpDir, "base.yml"), []byte(`[{op: add, path: /env, value: base}]`), 0644)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(tmpDir, "prod.yml"), []byte(`---
+include: [base.yml]
+operations:
+- op: replace
+  path: /env
+  value: prod
+`), 0644)).To(Succeed())
+
+			session := run(`foo: bar`, "-o", filepath.Join(tmpDir, "prod.yml"))
+
+			Expect(session.ExitCode()).To(Equal(0))
+			Expect(session.Out.Contents()).To(MatchYAML(`{foo: bar, env: prod}`))
+		})
+
+		It("applies only the operations within --ops-range, numbered across ops files", func() {
+			tmpDir, err := ioutil.TempDir("", "yaml-patch")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(tmpDir)
+
+			first := filepath.Join(tmpDir, "first.yml")
+			second := filepath.Join(tmpDir, "second.yml")
+			Expect(ioutil.WriteFile(first, []byte(`[{op: add, path: /a, value: 0}, {op: add, path: /b, value: 1}]`), 0644)).To(Succeed())
+			Expect(ioutil.WriteFile(second, []byte(`[{op: add, path: /c, value: 2}, {op: add, path: /d, value: 3}]`), 0644)).To(Succeed())
+
+			session := run(`{}`, "-o", first, "-o", second, "--ops-range", "1:3")
+
+			Expect(session.ExitCode()).To(Equal(0))
+			Expect(session.Out.Contents()).To(MatchYAML(`{b: 1, c: 2}`))
+
+			Expect(run(`{}`, "-o", first, "--ops-range", "3:1").ExitCode()).To(Equal(1))
+		})
+
+		Describe("--base", func() {
+			var base string
+
+			BeforeEach(func() {
+				f, err := ioutil.TempFile("", "base")
+				Expect(err).NotTo(HaveOccurred())
+				defer f.Close()
+
+				_, err = f.WriteString("kind: ConfigMap\ndata: {}\n")
+				Expect(err).NotTo(HaveOccurred())
+
+				base = f.Name()
+			})
+
+			AfterEach(func() {
+				Expect(os.Remove(base)).To(Succeed())
+			})
+
+			It("patches the base document when stdin is empty", func() {
+				session := run("\n", "--base", base, "--set", "/data/env=prod")
+
+				Expect(session.ExitCode()).To(Equal(0))
+				Expect(session.Out.Contents()).To(MatchYAML(`{kind: ConfigMap, data: {env: prod}}`))
+			})
+
+			It("patches stdin instead when it is not empty", func() {
+				session := run(`{kind: Secret, data: {}}`, "--base", base, "--set", "/data/env=prod")
+
+				Expect(session.ExitCode()).To(Equal(0))
+				Expect(session.Out.Contents()).To(MatchYAML(`{kind: Secret, data: {env: prod}}`))
+			})
+
+			It("exits 1 when the base document does not exist", func() {
+				session := run("", "--base", base+".missing", "--set", "/a=1")
+
+				Expect(session.ExitCode()).To(Equal(1))
+				Expect(session.Err).To(gbytes.Say("--base"))
+			})
+		})
+
+		It("selects the override for the environment given with --env", func() {
+			tmpDir, err := ioutil.TempDir("", "yaml-patch")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(tmpDir)
+
+			opsFile := filepath.Join(tmpDir, "ops.yml")
+			Expect(ioutil.WriteFile(opsFile, []byte(`[{op: replace, path: /replicas, value_by_env: {default: 2, overrides: {prod: 5}}}]`), 0644)).To(Succeed())
+
+			session := run(`{replicas: 1}`, "--env", "prod", "-o", opsFile)
+
+			Expect(session.ExitCode()).To(Equal(0))
+			Expect(session.Out.Contents()).To(MatchYAML(`{replicas: 5}`))
+		})
+
+		Describe("--values", func() {
+			var dir, values, opsFile string
+
+			BeforeEach(func() {
+				var err error
+				dir, err = ioutil.TempDir("", "values")
+				Expect(err).NotTo(HaveOccurred())
+
+				values = filepath.Join(dir, "values.yml")
+				Expect(ioutil.WriteFile(values, []byte("image: {tag: v1.2}\nports: [80, 443]\n"), 0644)).To(Succeed())
+
+				opsFile = filepath.Join(dir, "ops.yml")
+				Expect(ioutil.WriteFile(opsFile, []byte(`---
+- op: replace
+  path: /spec/image
+  value: app:{{ image.tag }}
+- op: add
+  path: /spec/ports
+  value: {{ ports }}
+- op: add
+  path: /spec/name
+  value: "{{path:/kind}}"
+`), 0644)).To(Succeed())
+			})
+
+			AfterEach(func() {
+				Expect(os.RemoveAll(dir)).To(Succeed())
+			})
+
+			It("resolves the placeholders of the ops files and document by dotted lookup", func() {
+				session := run("kind: Deployment\nspec: {image: app, port: {{ ports.0 }}}\n", "--values", values, "-o", opsFile)
+
+				Expect(session.ExitCode()).To(Equal(0))
+				Expect(session.Out.Contents()).To(MatchYAML(`{kind: Deployment, spec: {image: "app:v1.2", port: 80, ports: [80, 443], name: Deployment}}`))
+			})
+
+			It("exits 2 for a placeholder that names no value", func() {
+				session := run("spec: {image: app, env: '{{ env }}'}\n", "--values", values, "-o", opsFile)
+
+				Expect(session.ExitCode()).To(Equal(2))
+				Expect(session.Err).To(gbytes.Say("unresolved placeholders: {{ env }}"))
+			})
+
+			It("leaves a placeholder that names no value with --allow-missing", func() {
+				session := run("kind: Pod\nspec: {image: app, env: {{ env }}}\n", "--values", values, "--allow-missing", "-o", opsFile)
+
+				Expect(session.ExitCode()).To(Equal(0))
+				Expect(session.Out.Contents()).To(ContainSubstring("env: {{ env }}"))
+			})
+
+			It("keeps strings that make up a whole scalar strings", func() {
+				Expect(ioutil.WriteFile(values, []byte(`{map: "a: b", comment: "#x", bool: "true", octal: "0123", tag: v1}`), 0644)).To(Succeed())
+
+				session := run("a: {{ map }}\nb: {{ comment }}\nc: '{{ bool }}'\nd: [{{ octal }}]\ne: app:{{ tag }}\n", "--values", values)
+
+				Expect(session.ExitCode()).To(Equal(0))
+				Expect(session.Out.Contents()).To(MatchYAML(`{a: "a: b", b: "#x", c: "true", d: ["0123"], e: "app:v1"}`))
+			})
+
+			It("exits 1 for --allow-missing without --values", func() {
+				Expect(run("{}", "--allow-missing").ExitCode()).To(Equal(1))
+			})
+		})
+
+		It("applies the operations last to first with --reverse", func() {
+			session := run(`items: []`, "--set", "/items/-=a", "--set", "/items/-=b", "--reverse")
+
+			Expect(session.ExitCode()).To(Equal(0))
+			Expect(session.Out.Contents()).To(MatchYAML(`{items: [b, a]}`))
+		})
+
+		Describe("--out", func() {
+			var tmpDir string
+
+			BeforeEach(func() {
+				var err error
+				tmpDir, err = ioutil.TempDir("", "yaml-patch")
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			AfterEach(func() {
+				Expect(os.RemoveAll(tmpDir)).To(Succeed())
+			})
+
+			It("writes the result to the file with the given mode, creating directories given --mkdir", func() {
+				out := filepath.Join(tmpDir, "nested", "doc.yml")
+
+				session := run(`foo: bar`, "--set", "/foo=baz", "--out", out, "--mkdir", "--mode", "0600")
+				Expect(session.ExitCode()).To(Equal(0))
+				Expect(session.Out.Contents()).To(BeEmpty())
+
+				contents, err := ioutil.ReadFile(out)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(contents).To(MatchYAML(`foo: baz`))
+
+				stat, err := os.Stat(out)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(stat.Mode().Perm()).To(Equal(os.FileMode(0600)))
+
+				entries, err := ioutil.ReadDir(filepath.Dir(out))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(entries).To(HaveLen(1))
+			})
+
+			It("keeps the mode of a file it replaces", func() {
+				out := filepath.Join(tmpDir, "doc.yml")
+				Expect(ioutil.WriteFile(out, []byte(`foo: bar`), 0640)).To(Succeed())
+				Expect(os.Chmod(out, 0640)).To(Succeed())
+
+				Expect(run(`foo: bar`, "--set", "/foo=baz", "-O", out).ExitCode()).To(Equal(0))
+
+				stat, err := os.Stat(out)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(stat.Mode().Perm()).To(Equal(os.FileMode(0640)))
+			})
+
+			It("exits 1 when the parent directory is missing and --mkdir is not given", func() {
+				out := filepath.Join(tmpDir, "missing", "doc.yml")
+
+				Expect(run(`foo: bar`, "--set", "/foo=baz", "--out", out).ExitCode()).To(Equal(1))
+			})
+
+			It("exits 1 for an invalid mode", func() {
+				Expect(run(`foo: bar`, "--out", filepath.Join(tmpDir, "doc.yml"), "--mode", "999").ExitCode()).To(Equal(1))
+			})
+		})
+
+		Describe("--watch", func() {
+			var tmpDir string
+
+			BeforeEach(func() {
+				var err error
+				tmpDir, err = ioutil.TempDir("", "yaml-patch")
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			AfterEach(func() {
+				Expect(os.RemoveAll(tmpDir)).To(Succeed())
+			})
+
+			It("applies the patch again when the document changes", func() {
+				doc := filepath.Join(tmpDir, "doc.yml")
+				out := filepath.Join(tmpDir, "out.yml")
+				Expect(ioutil.WriteFile(doc, []byte(`foo: bar`), 0644)).To(Succeed())
+
+				cmd := exec.Command(pathToCLI, "--watch", "--doc", doc, "--out", out, "--set", "/added=true")
+				session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+				defer session.Kill()
+
+				readOut := func() string {
+					bs, _ := ioutil.ReadFile(out)
+					return string(bs)
+				}
+
+				Eventually(readOut, "5s").Should(ContainSubstring("foo: bar"))
+
+				Expect(ioutil.WriteFile(doc, []byte(`foo: baz`), 0644)).To(Succeed())
+				Eventually(readOut, "5s").Should(ContainSubstring("foo: baz"))
+				Expect(readOut()).To(ContainSubstring("added: true"))
+
+				Expect(ioutil.WriteFile(doc, []byte(`foo: [`), 0644)).To(Succeed())
+				Eventually(session.Err, "5s").Should(gbytes.Say("error decoding document"))
+				Consistently(session, "200ms").ShouldNot(gexec.Exit())
+			})
+
+			It("exits 1 without --doc", func() {
+				Expect(run(`foo: bar`, "--watch").ExitCode()).To(Equal(1))
+			})
+		})
+
+		It("prints a trace of each operation with --debug", func() {
+			session := run(`a: 1`, "--set", "/b=2", "--debug")
+
+			Expect(session.ExitCode()).To(Equal(0))
+			Expect(session.Out).To(gbytes.Say(`b: 2`))
+			Expect(session.Err).To(gbytes.Say(`debug: operation 0: set /b`))
+		})
+
+		Describe("--ndjson", func() {
+			It("patches each line as a JSON document", func() {
+				session := run(`{"name":"a","count":1}
+{"name":"b","count":2}
+`, "--ndjson", "--set", "/count=0")
+
+				Expect(session.ExitCode()).To(Equal(0))
+				Expect(string(session.Out.Contents())).To(Equal(`{"count":0,"name":"a"}
+{"count":0,"name":"b"}
+`))
+			})
+
+			It("exits 2 for a malformed line", func() {
+				session := run(`{"count":1}
+{"count":
+`, "--ndjson", "--set", "/count=0")
+
+				Expect(session.ExitCode()).To(Equal(2))
+				Expect(session.Err).To(gbytes.Say("line 2: invalid JSON"))
+			})
+
+			It("skips and reports malformed lines with --skip-malformed", func() {
+				session := run(`{"count":1}
+not json
+{"count":2}
+`, "--ndjson", "--skip-malformed", "--set", "/count=0")
+
+				Expect(session.ExitCode()).To(Equal(0))
+				Expect(string(session.Out.Contents())).To(Equal("{\"count\":0}\n{\"count\":0}\n"))
+				Expect(session.Err).To(gbytes.Say("warning: skipped line 2: invalid JSON"))
+			})
+
+			It("exits 1 for --skip-malformed without --ndjson", func() {
+				Expect(run(`foo: bar`, "--skip-malformed").ExitCode()).To(Equal(1))
+			})
+		})
+
+		Describe("exit codes", func() {
+			var tmpDir string
+
+			BeforeEach(func() {
+				var err error
+				tmpDir, err = ioutil.TempDir("", "yaml-patch")
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			AfterEach(func() {
+				Expect(os.RemoveAll(tmpDir)).To(Succeed())
+			})
+
+			opsFile := func(contents string) string {
+				path := filepath.Join(tmpDir, "ops.yml")
+				Expect(ioutil.WriteFile(path, []byte(contents), 0644)).To(Succeed())
+				return path
+			}
+
+			It("exits 1 for usage errors", func() {
+				Expect(run(`foo: bar`, "--unknown-flag").ExitCode()).To(Equal(1))
+			})
+
+			It("exits 2 for an ops file that cannot be decoded", func() {
+				Expect(run(`foo: bar`, "-o", opsFile(`{not: a list}`)).ExitCode()).To(Equal(2))
+			})
+
+			It("exits 2 for a document that cannot be decoded", func() {
+				Expect(run(`foo: [bar`, "--set", "/foo=bar").ExitCode()).To(Equal(2))
+			})
+
+			It("exits 3 for conflicting operations with --reject-conflicts", func() {
+				ops := opsFile(`[{op: add, path: /a, value: 1}, {op: replace, path: /a, value: 2}]`)
+
+				Expect(run(`foo: bar`, "-o", ops).ExitCode()).To(Equal(0))
+
+				session := run(`foo: bar`, "-o", ops, "--reject-conflicts")
+				Expect(session.ExitCode()).To(Equal(3))
+				Expect(session.Err).To(gbytes.Say("operations 0 and 1 both modify /a"))
+			})
+
+			It("exits 3 for an operation that cannot be applied", func() {
+				Expect(run(`foo: bar`, "-o", opsFile(`[{op: remove, path: /missing}]`)).ExitCode()).To(Equal(3))
+			})
+		})
+	})
 })
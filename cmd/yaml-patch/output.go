@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// defaultFileMode is the mode given to output files that do not already exist
+// when no mode is specified
+const defaultFileMode = 0644
+
+// parseFileMode parses a file mode given in octal, e.g. 0600
+func parseFileMode(s string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("invalid file mode '%s'", s)
+	}
+
+	return os.FileMode(mode), nil
+}
+
+// writeFileAtomic writes the content to a temporary file beside the path and
+// renames it into place, so the file at the path is never left partially
+// written. The file is given the mode when it is non-zero, or else keeps the
+// mode of the file being replaced. Missing parent directories are created when
+// mkdir is set.
+func writeFileAtomic(path string, content []byte, mode os.FileMode, mkdir bool) error {
+	dir := filepath.Dir(path)
+
+	if mkdir {
+		err := os.MkdirAll(dir, 0755)
+		if err != nil {
+			return err
+		}
+	}
+
+	if mode == 0 {
+		mode = defaultFileMode
+		if stat, err := os.Stat(path); err == nil {
+			mode = stat.Mode().Perm()
+		}
+	}
+
+	tmp, err := ioutil.TempFile(dir, "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(content)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	err = os.Chmod(tmp.Name(), mode)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,24 @@
+package main
+
+import yamlpatch "github.com/ACCELERATOR-SANDBOX/yaml-patch"
+
+// RangeFlag is a flag for a range of operation indices, in the form
+// START:END, where START is included and END is not. Either may be omitted,
+// as in :5 or 3:, to leave the range open at that end.
+type RangeFlag struct {
+	Start int
+	End   int
+}
+
+// UnmarshalFlag implements go-flag's Unmarshaler interface
+func (f *RangeFlag) UnmarshalFlag(value string) error {
+	start, end, err := yamlpatch.ParseRange(value)
+	if err != nil {
+		return err
+	}
+
+	f.Start = start
+	f.End = end
+
+	return nil
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	yamlpatch "github.com/ACCELERATOR-SANDBOX/yaml-patch"
+)
+
+// opSet is the verb of the operations that set values. It replaces the value
+// at the path when there is one and adds it otherwise, so that setting an
+// existing index of an array replaces the element rather than inserting one.
+const opSet = "set"
+
+// pointerDecoder unescapes the tokens of a path
+var pointerDecoder = strings.NewReplacer("~1", "/", "~0", "~")
+
+func init() {
+	yamlpatch.RegisterOperation(opSet, performSet)
+}
+
+// SetFlag is a flag for setting a single value at a path, in the form
+// PATH=VALUE. The type of the value is detected automatically as an int, bool
+// or string, or may be forced by suffixing the path with :int, :bool, :float
+// or :string, as in /spec/version:string=1. Values that begin with '[' or '{'
+// are parsed as inline YAML or JSON collections, as in /ports=[80,443],
+// decoded as the document is when the operation is made.
+type SetFlag struct {
+	Path  string
+	Value interface{}
+
+	// raw is the inline collection, when the value is one
+	raw string
+}
+
+// UnmarshalFlag implements go-flag's Unmarshaler interface
+func (f *SetFlag) UnmarshalFlag(value string) error {
+	return f.unmarshal(value, "")
+}
+
+// SetStringFlag is a SetFlag whose value is always a literal string
+type SetStringFlag struct {
+	SetFlag
+}
+
+// UnmarshalFlag implements go-flag's Unmarshaler interface
+func (f *SetStringFlag) UnmarshalFlag(value string) error {
+	return f.unmarshal(value, "string")
+}
+
+// unmarshal parses the flag's value, forcing the type of the value when typ
+// is given rather than taking it from the path
+func (f *SetFlag) unmarshal(value, typ string) error {
+	path, raw, ok := splitSetValue(value)
+	if !ok {
+		return fmt.Errorf("set value '%s' is not in the form PATH=VALUE", value)
+	}
+
+	if typ == "" {
+		path, typ = splitSetType(path)
+	}
+
+	if !strings.HasPrefix(path, "/") {
+		return fmt.Errorf("set path '%s' is missing leading '/'", path)
+	}
+
+	f.Path = path
+
+	if typ == "" && (strings.HasPrefix(raw, "[") || strings.HasPrefix(raw, "{")) {
+		f.raw = raw
+		return nil
+	}
+
+	v, err := parseSetValue(raw, typ)
+	if err != nil {
+		return fmt.Errorf("invalid set value for %s: %s", path, err)
+	}
+
+	f.Value = v
+
+	return nil
+}
+
+// splitSetValue splits a flag's value into its path and value at the first '='
+// that is not within a key=value segment of the path, as in
+// /items/name=web/image=nginx. An '=' is within a segment when a later '='
+// follows the segment and the path up to the later one is valid.
+func splitSetValue(value string) (string, string, bool) {
+	for i := 0; i < len(value); i++ {
+		if value[i] == '=' && !withinSegment(value, i) {
+			return value[:i], value[i+1:], true
+		}
+	}
+
+	return "", "", false
+}
+
+// withinSegment returns whether the '=' at the given offset of a flag's value
+// is within a key=value segment of its path
+func withinSegment(value string, i int) bool {
+	end := strings.Index(value[i+1:], "/")
+	next := strings.Index(value[i+1:], "=")
+	if end <= 0 || next < end || strings.ContainsAny(value[i+1:i+1+end], "[{\"' ") {
+		return false
+	}
+
+	path, _ := splitSetType(value[:i+1+next])
+	return yamlpatch.ValidatePath(path) == nil
+}
+
+// splitSetType splits the type a path is suffixed with, as in
+// /spec/version:string, from the path
+func splitSetType(path string) (string, string) {
+	if i := strings.LastIndex(path, ":"); i != -1 {
+		switch path[i+1:] {
+		case "int", "bool", "float", "string":
+			return path[:i], path[i+1:]
+		}
+	}
+
+	return path, ""
+}
+
+// Operation returns the operation that sets the value at the path. An inline
+// collection is decoded with the options the document is patched with.
+func (f SetFlag) Operation(opts yamlpatch.ApplyOptions) (yamlpatch.Operation, error) {
+	op := yamlpatch.Operation{
+		Op:   opSet,
+		Path: yamlpatch.OpPath(f.Path),
+	}
+
+	if f.raw != "" {
+		node, err := yamlpatch.ParseDocumentWithOptions([]byte(f.raw), opts)
+		if err != nil {
+			return yamlpatch.Operation{}, fmt.Errorf("invalid set value for %s: failed parsing collection: %s", f.Path, err)
+		}
+
+		op.Value = node
+		return op, nil
+	}
+
+	v := f.Value
+	op.Value = yamlpatch.NewNode(&v)
+
+	return op, nil
+}
+
+// performSet replaces the value at the operation's path when the path
+// resolves and adds it otherwise, as to a missing key of a map
+func performSet(doc *yamlpatch.Node, op yamlpatch.Operation) error {
+	op.Op = "add"
+	if pathResolves(doc, op.Path) {
+		op.Op = "replace"
+	}
+
+	return yamlpatch.Patch{op}.ApplyToNode(doc, yamlpatch.ApplyOptions{})
+}
+
+// pathResolves returns whether there is a value at the path of the document
+func pathResolves(doc *yamlpatch.Node, path yamlpatch.OpPath) bool {
+	node := doc
+	for _, token := range strings.Split(string(path), "/")[1:] {
+		c := node.Container()
+		if c == nil {
+			return false
+		}
+
+		token = pointerDecoder.Replace(token)
+
+		var err error
+		node, err = c.Get(token)
+		if err != nil || node == nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+func parseSetValue(raw, typ string) (interface{}, error) {
+	switch typ {
+	case "int":
+		return strconv.Atoi(raw)
+	case "bool":
+		return strconv.ParseBool(raw)
+	case "float":
+		return strconv.ParseFloat(raw, 64)
+	case "string":
+		return raw, nil
+	}
+
+	if i, err := strconv.Atoi(raw); err == nil {
+		return i, nil
+	}
+
+	if raw == "true" || raw == "false" {
+		return raw == "true", nil
+	}
+
+	return raw, nil
+}
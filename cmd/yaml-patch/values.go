@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	yamlpatch "github.com/ACCELERATOR-SANDBOX/yaml-patch"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// values resolves the placeholders of ops files and documents from the map
+// of a --values file
+type values struct {
+	wrapper *yamlpatch.PlaceholderWrapper
+	values  interface{}
+}
+
+// loadValues reads the values file at the path, which must hold a map
+func loadValues(path string, wrapper *yamlpatch.PlaceholderWrapper) (*values, error) {
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var v interface{}
+	err = yaml.Unmarshal(bs, &v)
+	if err != nil {
+		return nil, err
+	}
+
+	switch v.(type) {
+	case nil, map[interface{}]interface{}:
+	default:
+		return nil, fmt.Errorf("%s is not a map", path)
+	}
+
+	return &values{wrapper: wrapper, values: v}, nil
+}
+
+// resolve returns the input with each placeholder that names a value
+// replaced by it, and the placeholders that remain unresolved. Placeholders
+// that the patch resolves itself, those of captured variables, document
+// paths and for_each, are neither resolved nor reported.
+func (v *values) resolve(input []byte) ([]byte, []string) {
+	bs := v.wrapper.Resolve(input, func(name string, scalar bool) (string, bool) {
+		if yamlpatch.IsReservedPlaceholder(v.wrapper.LeftSide + name + v.wrapper.RightSide) {
+			return "", false
+		}
+
+		val, ok := lookupValue(v.values, name)
+		if !ok {
+			return "", false
+		}
+
+		return formatValue(val, scalar), true
+	})
+
+	var unresolved []string
+	for _, placeholder := range v.wrapper.FindUnresolved(bs) {
+		if !yamlpatch.IsReservedPlaceholder(placeholder) {
+			unresolved = append(unresolved, placeholder)
+		}
+	}
+
+	return bs, unresolved
+}
+
+// lookupValue returns the value at the dotted path, such as image.tag, within
+// the values. Each part of the path is the key of a map or the index of an
+// array.
+func lookupValue(v interface{}, name string) (interface{}, bool) {
+	for _, part := range strings.Split(name, ".") {
+		switch it := v.(type) {
+		case map[interface{}]interface{}:
+			val, ok := yamlpatch.LookupKey(it, part)
+			if !ok {
+				return nil, false
+			}
+			v = val
+		case []interface{}:
+			i, err := strconv.Atoi(part)
+			if err != nil || i < 0 || i >= len(it) {
+				return nil, false
+			}
+			v = it[i]
+		default:
+			return nil, false
+		}
+	}
+
+	return v, true
+}
+
+// formatValue returns the text a placeholder is replaced with: any value
+// other than a string as flow YAML, so that a map or array can be given, and
+// a string as a quoted scalar when the placeholder makes up a whole scalar, so
+// that a string such as "a: b" or "true" stays a string, or as it is when the
+// placeholder is part of a scalar
+func formatValue(v interface{}, scalar bool) string {
+	if s, ok := v.(string); ok && !scalar {
+		return s
+	}
+
+	bs, err := json.Marshal(yamlpatch.JSONValue(v))
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+
+	return string(bs)
+}
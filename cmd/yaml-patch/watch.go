@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce is how long the inputs must be left unchanged before the patch
+// is applied again, so that an editor's successive writes cause a single run
+const watchDebounce = 100 * time.Millisecond
+
+// watch calls run, and calls it again whenever any of the files at the paths
+// changes, printing its errors to stderr rather than exiting. It returns only
+// when the files cannot be watched. The directories containing the files are
+// watched, rather than the files themselves, so that files replaced by
+// renaming over them, as many editors do, are still watched.
+func watch(paths []string, run func() error) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	files := map[string]bool{}
+	for _, path := range paths {
+		path = filepath.Clean(path)
+		files[path] = true
+
+		err = w.Add(filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+	}
+
+	runAndReport := func() {
+		if err := run(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+
+	runAndReport()
+
+	timer := time.NewTimer(watchDebounce)
+	timer.Stop()
+
+	for {
+		select {
+		case event, ok := <-w.Events:
+			if !ok {
+				return nil
+			}
+
+			if files[filepath.Clean(event.Name)] && event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+				timer.Reset(watchDebounce)
+			}
+		case err, ok := <-w.Errors:
+			if !ok {
+				return nil
+			}
+
+			fmt.Fprintf(os.Stderr, "error watching inputs: %s\n", err)
+		case <-timer.C:
+			runAndReport()
+		}
+	}
+}
@@ -0,0 +1,316 @@
+package yamlpatch
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	yaml "gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// YAMLVersion is the version of the YAML specification by which the plain
+// scalars of a document are resolved into values
+type YAMLVersion string
+
+const (
+	// YAML11 resolves plain scalars per YAML 1.1, as go-yaml v2 does: yes,
+	// no, on, off, y and n are bools, and integers with a leading 0 are
+	// octal. It is the default.
+	YAML11 YAMLVersion = "1.1"
+
+	// YAML12 resolves plain scalars per the YAML 1.2 core schema: only true
+	// and false are bools, and integers with a leading 0 are decimal, with
+	// octal written as 0o17. Timestamps are kept as strings, as with YAML11.
+	YAML12 YAMLVersion = "1.2"
+)
+
+// yamlCodec decodes documents into the values that operations are performed
+// on, and encodes them again, with the same semantics whichever versions of
+// go-yaml are in use. Maps are decoded as map[interface{}]interface{},
+// sequences as []interface{}, and integers as int, or as uint64 when too
+// large for an int. Merge keys (<<) are expanded. An integer too large for
+// either is decoded as a float64 unless overflow is rejected.
+type yamlCodec struct {
+	version           YAMLVersion
+	rejectIntOverflow bool
+}
+
+// defaultCodec is the codec for documents decoded without options
+var defaultCodec = yamlCodec{}
+
+func (o ApplyOptions) codec() yamlCodec {
+	return yamlCodec{
+		version:           o.YAMLVersion,
+		rejectIntOverflow: o.RejectIntOverflow,
+	}
+}
+
+// unmarshal decodes the first document in bs
+func (c yamlCodec) unmarshal(bs []byte) (interface{}, error) {
+	switch c.version {
+	case "", YAML11:
+		var v interface{}
+		err := yaml.Unmarshal(bs, &v)
+		if err != nil {
+			return nil, err
+		}
+
+		if c.rejectIntOverflow {
+			var n yamlv3.Node
+			if yamlv3.Unmarshal(bs, &n) == nil {
+				err = checkIntOverflow(&n)
+			}
+		}
+
+		return v, err
+	case YAML12:
+		var n yamlv3.Node
+		err := yamlv3.Unmarshal(bs, &n)
+		if err != nil {
+			return nil, err
+		}
+
+		return c.decodeNode(&n)
+	}
+
+	return nil, fmt.Errorf("unsupported YAML version: %s", c.version)
+}
+
+// marshal encodes the value as a document
+func (c yamlCodec) marshal(v interface{}) ([]byte, error) {
+	return yaml.Marshal(v)
+}
+
+var (
+	decimalIntRegex     = regexp.MustCompile(`^[-+]?[0-9]+$`)
+	leadingZeroIntRegex = regexp.MustCompile(`^[-+]?0[0-9]+$`)
+)
+
+// checkIntOverflow returns an error for the first plain scalar within the
+// node that is an integer too large to be decoded as one
+func checkIntOverflow(n *yamlv3.Node) error {
+	if n.Kind == yamlv3.ScalarNode {
+		return intOverflow(n)
+	}
+
+	for _, child := range n.Content {
+		err := checkIntOverflow(child)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func intOverflow(n *yamlv3.Node) error {
+	if n.Style != 0 || !decimalIntRegex.MatchString(n.Value) {
+		return nil
+	}
+
+	if _, err := strconv.ParseInt(n.Value, 10, 64); err == nil {
+		return nil
+	}
+
+	if _, err := strconv.ParseUint(n.Value, 10, 64); err == nil {
+		return nil
+	}
+
+	return fmt.Errorf("line %d: integer overflows 64 bits: %s", n.Line, n.Value)
+}
+
+// Limits on the expansion of aliases, as go-yaml enforces them, so that a
+// document such as a billion laughs cannot expand into an unbounded value
+const (
+	aliasRatioRangeLow  = 400000
+	aliasRatioRangeHigh = 4000000
+)
+
+// allowedAliasRatio returns the largest share of the nodes decoded so far
+// that may have been decoded through aliases. Small documents may consist
+// almost entirely of aliases, and the share allowed falls as they grow.
+func allowedAliasRatio(decodeCount int) float64 {
+	switch {
+	case decodeCount <= aliasRatioRangeLow:
+		return 0.99
+	case decodeCount >= aliasRatioRangeHigh:
+		return 0.10
+	default:
+		return 0.99 - 0.89*(float64(decodeCount-aliasRatioRangeLow)/float64(aliasRatioRangeHigh-aliasRatioRangeLow))
+	}
+}
+
+// nodeDecoder converts the nodes of one document decoded by go-yaml v3 into
+// the values go-yaml v2 decodes into, tracking the aliases it expands
+type nodeDecoder struct {
+	codec yamlCodec
+
+	// expanding holds the anchored nodes whose aliases are being expanded,
+	// so that an alias within its own anchor's value is rejected rather than
+	// expanded forever
+	expanding map[*yamlv3.Node]bool
+
+	// decodeCount is the number of nodes decoded, and aliasCount that of
+	// those decoded within an alias, which is being expanded when aliasDepth
+	// is above 0
+	decodeCount, aliasCount, aliasDepth int
+}
+
+// decodeNode converts a node decoded by go-yaml v3 into the values go-yaml v2
+// decodes into
+func (c yamlCodec) decodeNode(n *yamlv3.Node) (interface{}, error) {
+	d := &nodeDecoder{codec: c, expanding: map[*yamlv3.Node]bool{}}
+	return d.decode(n)
+}
+
+func (d *nodeDecoder) decode(n *yamlv3.Node) (interface{}, error) {
+	d.decodeCount++
+	if d.aliasDepth > 0 {
+		d.aliasCount++
+	}
+
+	if d.aliasCount > 100 && d.decodeCount > 1000 && float64(d.aliasCount)/float64(d.decodeCount) > allowedAliasRatio(d.decodeCount) {
+		return nil, fmt.Errorf("document contains excessive aliasing")
+	}
+
+	switch n.Kind {
+	case yamlv3.DocumentNode:
+		if len(n.Content) == 0 {
+			return nil, nil
+		}
+		return d.decode(n.Content[0])
+	case yamlv3.AliasNode:
+		return d.decodeAlias(n)
+	case yamlv3.SequenceNode:
+		s := make([]interface{}, len(n.Content))
+		for i, child := range n.Content {
+			v, err := d.decode(child)
+			if err != nil {
+				return nil, err
+			}
+			s[i] = v
+		}
+		return s, nil
+	case yamlv3.MappingNode:
+		m := map[interface{}]interface{}{}
+		err := d.decodeMapping(n, m)
+		return m, err
+	}
+
+	return d.codec.decodeScalar(n)
+}
+
+// decodeAlias decodes the value of the anchor the alias node refers to
+func (d *nodeDecoder) decodeAlias(n *yamlv3.Node) (interface{}, error) {
+	var v interface{}
+
+	err := d.expand(n, func(anchored *yamlv3.Node) error {
+		var err error
+		v, err = d.decode(anchored)
+		return err
+	})
+
+	return v, err
+}
+
+// expand calls fn with the anchored node the alias node refers to, failing
+// when the alias is within the value of its own anchor
+func (d *nodeDecoder) expand(n *yamlv3.Node, fn func(*yamlv3.Node) error) error {
+	if d.expanding[n.Alias] {
+		return fmt.Errorf("line %d: anchor '%s' value contains itself", n.Line, n.Value)
+	}
+
+	d.expanding[n.Alias] = true
+	d.aliasDepth++
+
+	err := fn(n.Alias)
+
+	d.aliasDepth--
+	delete(d.expanding, n.Alias)
+
+	return err
+}
+
+// decodeMapping decodes the entries of a mapping node into m. The entries of
+// merged maps are decoded first, so that the mapping's own entries override
+// them.
+func (d *nodeDecoder) decodeMapping(n *yamlv3.Node, m map[interface{}]interface{}) error {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, val := n.Content[i], n.Content[i+1]
+		if key.ShortTag() != "!!merge" {
+			continue
+		}
+
+		merged := []*yamlv3.Node{val}
+		if val.Kind == yamlv3.SequenceNode {
+			merged = val.Content
+		}
+
+		for j := len(merged) - 1; j >= 0; j-- {
+			err := d.decodeMerged(merged[j], val, m)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].ShortTag() == "!!merge" {
+			continue
+		}
+
+		k, err := d.decode(n.Content[i])
+		if err != nil {
+			return err
+		}
+
+		v, err := d.decode(n.Content[i+1])
+		if err != nil {
+			return err
+		}
+
+		m[k] = v
+	}
+
+	return nil
+}
+
+// decodeMerged decodes the entries of a map merged into m by the merge key
+// whose value is val
+func (d *nodeDecoder) decodeMerged(src, val *yamlv3.Node, m map[interface{}]interface{}) error {
+	if src.Kind == yamlv3.AliasNode {
+		return d.expand(src, func(anchored *yamlv3.Node) error {
+			return d.decodeMerged(anchored, val, m)
+		})
+	}
+
+	if src.Kind != yamlv3.MappingNode {
+		return fmt.Errorf("line %d: map merge requires a map or a sequence of maps", val.Line)
+	}
+
+	return d.decodeMapping(src, m)
+}
+
+func (c yamlCodec) decodeScalar(n *yamlv3.Node) (interface{}, error) {
+	if c.rejectIntOverflow {
+		err := intOverflow(n)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if n.ShortTag() == "!!timestamp" {
+		return n.Value, nil
+	}
+
+	if n.Style == 0 && leadingZeroIntRegex.MatchString(n.Value) {
+		if i, err := strconv.Atoi(n.Value); err == nil {
+			return i, nil
+		}
+	}
+
+	var v interface{}
+	err := n.Decode(&v)
+	return v, err
+}
@@ -0,0 +1,108 @@
+package yamlpatch_test
+
+import (
+	yamlpatch "github.com/krishicks/yaml-patch"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("YAML versions", func() {
+	var patch = yamlpatch.Patch{
+		{Op: "test", Path: "/enabled", Value: nodeOf("on")},
+		{Op: "test", Path: "/mode", Value: nodeOf(644)},
+	}
+
+	doc := []byte(`enabled: on
+mode: 0644
+date: 2001-12-14
+base: &base {a: 1, b: 2}
+merged:
+  <<: *base
+  b: 3
+`)
+
+	It("resolves plain scalars per YAML 1.1 by default", func() {
+		_, err := patch.Apply(doc)
+		Expect(err).To(HaveOccurred())
+
+		actual, err := yamlpatch.Patch{
+			{Op: "test", Path: "/enabled", Value: nodeOf(true)},
+			{Op: "test", Path: "/mode", Value: nodeOf(420)},
+		}.Apply(doc)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(actual)).To(ContainSubstring("enabled: true\n"))
+	})
+
+	It("resolves plain scalars per YAML 1.2 when YAMLVersion is YAML12", func() {
+		opts := yamlpatch.ApplyOptions{YAMLVersion: yamlpatch.YAML12}
+
+		actual, err := patch.ApplyWithOptions(doc, opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actual).To(MatchYAML(`enabled: "on"
+mode: 644
+date: "2001-12-14"
+base: {a: 1, b: 2}
+merged: {a: 1, b: 3}
+`))
+	})
+
+	It("rejects an unsupported version", func() {
+		_, err := patch.ApplyWithOptions(doc, yamlpatch.ApplyOptions{YAMLVersion: "2.0"})
+		Expect(err).To(MatchError(ContainSubstring("unsupported YAML version: 2.0")))
+	})
+
+	Describe("integer overflow", func() {
+		doc := []byte("small: 1\nbig: 99999999999999999999\nquoted: \"99999999999999999999\"\n")
+
+		It("decodes an overflowing integer as a float by default", func() {
+			actual, err := yamlpatch.Patch{}.Apply(doc)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(actual)).To(ContainSubstring("big: 1e+20\n"))
+		})
+
+		It("rejects an overflowing integer when RejectIntOverflow is set", func() {
+			for _, version := range []yamlpatch.YAMLVersion{yamlpatch.YAML11, yamlpatch.YAML12} {
+				_, err := yamlpatch.Patch{}.ApplyWithOptions(doc, yamlpatch.ApplyOptions{YAMLVersion: version, RejectIntOverflow: true})
+				Expect(err).To(MatchError(ContainSubstring("line 2: integer overflows 64 bits: 99999999999999999999")))
+			}
+		})
+
+		It("accepts integers that fit an unsigned 64 bits", func() {
+			_, err := yamlpatch.Patch{}.ApplyWithOptions([]byte("max: 18446744073709551615\n"), yamlpatch.ApplyOptions{RejectIntOverflow: true})
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Describe("aliases", func() {
+		It("expands aliases and merged maps", func() {
+			doc := []byte("base: &base {a: 1}\ncopy: *base\nmerged: {<<: *base, b: 2}\n")
+
+			for _, version := range []yamlpatch.YAMLVersion{yamlpatch.YAML11, yamlpatch.YAML12} {
+				actual, err := yamlpatch.Patch{}.ApplyWithOptions(doc, yamlpatch.ApplyOptions{YAMLVersion: version})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(actual)).To(Equal("base:\n  a: 1\ncopy:\n  a: 1\nmerged:\n  a: 1\n  b: 2\n"))
+			}
+		})
+
+		It("rejects an anchor whose value contains an alias to it", func() {
+			for _, doc := range []string{"a: &x\n  b: *x\n", "a: &x\n  <<: *x\n"} {
+				_, err := yamlpatch.Patch{}.ApplyWithOptions([]byte(doc), yamlpatch.ApplyOptions{YAMLVersion: yamlpatch.YAML12})
+				Expect(err).To(MatchError(ContainSubstring("anchor 'x' value contains itself")))
+			}
+		})
+
+		It("rejects a document that expands excessively through aliases", func() {
+			doc := "a: &a [lol, lol, lol, lol, lol, lol, lol, lol, lol]\n"
+			for _, name := range []string{"b", "c", "d", "e", "f", "g", "h", "i"} {
+				prev := string(rune(name[0] - 1))
+				doc += name + ": &" + name + " [*" + prev + ", *" + prev + ", *" + prev + ", *" + prev + ", *" + prev + ", *" + prev + ", *" + prev + ", *" + prev + ", *" + prev + "]\n"
+			}
+
+			for _, version := range []yamlpatch.YAMLVersion{yamlpatch.YAML11, yamlpatch.YAML12} {
+				_, err := yamlpatch.Patch{}.ApplyWithOptions([]byte(doc), yamlpatch.ApplyOptions{YAMLVersion: version})
+				Expect(err).To(MatchError(ContainSubstring("excessive aliasing")))
+			}
+		})
+	})
+})
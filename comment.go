@@ -0,0 +1,240 @@
+package yamlpatch
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// Comment positions
+const (
+	commentAbove  = "above"
+	commentInline = "inline"
+)
+
+// comment is the text of the comments to emit with the value at a path
+type comment struct {
+	above  string
+	inline string
+}
+
+// comments are the comments to emit with the output, keyed by the nodes of
+// the values they belong to, so that a comment follows its value when later
+// operations insert or remove values before it, or move or rename it, and is
+// dropped when its value is removed or replaced
+type comments map[*Node]comment
+
+// pathComments are comments keyed by the pointers of the values they belong
+// to within a document
+type pathComments map[string]comment
+
+// tryComment records the comment a comment operation gives the value at its
+// path, which is emitted above the value's key, or on the same line with a
+// position of inline. The value is the comment's text, each line of which
+// becomes a line of the comment, and which has a single line when inline. A comment operation without a value removes
+// the comment at its position that earlier operations gave the value, or
+// both comments when it has no position.
+func tryComment(doc Container, op *Operation, ctx *applyContext) error {
+	switch op.Position {
+	case "", commentAbove, commentInline:
+	default:
+		return fmt.Errorf("yamlpatch comment operation has an unknown position: %s", op.Position)
+	}
+
+	node := ctx.root
+	if op.Path != "" {
+		con, key, err := findContainer(doc, &op.Path)
+		if err != nil {
+			return pathError(err, "yamlpatch comment operation does not apply: doc is missing path: %s", op.Path)
+		}
+
+		val, err := con.Get(key)
+		if val == nil || err != nil {
+			return fmt.Errorf("yamlpatch comment operation does not apply: doc is missing key: %s", op.Path)
+		}
+		node = val
+	}
+
+	var text string
+	if !op.Value.Empty() {
+		s, ok := op.Value.plain().(string)
+		if !ok {
+			return fmt.Errorf("yamlpatch comment operation does not apply: value is not a string: %s", op.Path)
+		}
+		text = s
+	}
+
+	// A comment on the same line as the value can only have one line, as
+	// yaml.v3 emits any other lines below the value, detached from it
+	if op.Position == commentInline {
+		text = strings.TrimRight(text, "\n")
+		if strings.Contains(text, "\n") {
+			return fmt.Errorf("yamlpatch comment operation does not apply: inline comment has more than one line: %s", op.Path)
+		}
+	}
+
+	if ctx.comments == nil {
+		ctx.comments = comments{}
+	}
+
+	c := ctx.comments[node]
+	switch op.Position {
+	case commentAbove:
+		c.above = text
+	case commentInline:
+		c.inline = text
+	default:
+		c.above = text
+		if text == "" {
+			c.inline = ""
+		}
+	}
+	if c == (comment{}) {
+		delete(ctx.comments, node)
+	} else {
+		ctx.comments[node] = c
+	}
+
+	return nil
+}
+
+// byPath returns the comments keyed by the pointers their values are at
+// within the document with the given root. The comments of values that are
+// no longer in the document are left out.
+func (cs comments) byPath(root *Node) pathComments {
+	paths := pathComments{}
+
+	var walk func(n *Node, path string)
+	walk = func(n *Node, path string) {
+		if n == nil {
+			return
+		}
+
+		if c, ok := cs[n]; ok {
+			paths[path] = c
+		}
+
+		// Only values that have been reached through their containers can
+		// have been given comments
+		switch it := n.container.(type) {
+		case *nodeMap:
+			for k, v := range *it {
+				walk(v, path+"/"+encodePatchKey(fmt.Sprint(k)))
+			}
+		case *nodeSlice:
+			for i, v := range *it {
+				walk(v, path+"/"+strconv.Itoa(i))
+			}
+		}
+	}
+	walk(root, "")
+
+	return paths
+}
+
+// at returns the comments keyed by the nodes at their pointers within the
+// document with the given root. The comments of pointers that are not found
+// are left out.
+func (ps pathComments) at(root *Node) comments {
+	cs := make(comments, len(ps))
+
+	for path, c := range ps {
+		node := root
+		if path != "" {
+			p := OpPath(path)
+
+			con, key, err := findContainer(&rootHolder{root: root}, &p)
+			if err != nil {
+				continue
+			}
+
+			node, err = con.Get(key)
+			if node == nil || err != nil {
+				continue
+			}
+		}
+
+		cs[node] = c
+	}
+
+	return cs
+}
+
+// apply re-emits a marshaled document, whose root is given, with the comments
+// added. go-yaml v2 cannot emit comments, so the document is decoded into a
+// yaml.v3 Node tree, given the comments and encoded again.
+func (cs comments) apply(bs []byte, root *Node) ([]byte, error) {
+	paths := cs.byPath(root)
+
+	var doc yamlv3.Node
+	err := yamlv3.Unmarshal(bs, &doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed adding comments to doc: %s", err)
+	}
+
+	if c, ok := paths[""]; ok {
+		doc.HeadComment = c.above
+		if len(doc.Content) > 0 {
+			doc.Content[0].LineComment = c.inline
+		}
+	}
+
+	if len(doc.Content) > 0 {
+		paths.add(doc.Content[0], "")
+	}
+
+	var buf bytes.Buffer
+	encoder := yamlv3.NewEncoder(&buf)
+	encoder.SetIndent(2)
+
+	err = encoder.Encode(&doc)
+	if err != nil {
+		return nil, err
+	}
+
+	err = encoder.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// add gives the children of the node, which has the given pointer, their
+// comments. An item of a sequence carries its own comments, and an entry of
+// a map carries its comment above on its key, and the comment inline on its
+// value when it is a scalar or on its key otherwise, so that the comment
+// follows the key rather than the last line of the value.
+func (cs pathComments) add(n *yamlv3.Node, path string) {
+	switch n.Kind {
+	case yamlv3.SequenceNode:
+		for i, item := range n.Content {
+			p := path + "/" + strconv.Itoa(i)
+			if c, ok := cs[p]; ok {
+				item.HeadComment = c.above
+				item.LineComment = c.inline
+			}
+
+			cs.add(item, p)
+		}
+	case yamlv3.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, val := n.Content[i], n.Content[i+1]
+
+			p := path + "/" + encodePatchKey(key.Value)
+			if c, ok := cs[p]; ok {
+				key.HeadComment = c.above
+				if val.Kind == yamlv3.ScalarNode {
+					val.LineComment = c.inline
+				} else {
+					key.LineComment = c.inline
+				}
+			}
+
+			cs.add(val, p)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package yamlpatch_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("comment", func() {
+	It("adds comments above and inline", func() {
+		actual, err := applyPatch(`---
+- op: comment
+  path: /spec
+  value: |-
+    generated by the release pipeline
+    do not edit
+- op: comment
+  path: /spec/replicas
+  position: inline
+  value: scaled for production
+- op: comment
+  path: /ports/1
+  value: metrics
+`, `---
+spec:
+  replicas: 3
+ports:
+- 80
+- 9090
+`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actual).To(Equal(`ports:
+  - 80
+  # metrics
+  - 9090
+# generated by the release pipeline
+# do not edit
+spec:
+  replicas: 3 # scaled for production
+`))
+	})
+
+	It("removes comments given by earlier operations", func() {
+		actual, err := applyPatch(`---
+- {op: comment, path: /a, value: above}
+- {op: comment, path: /a, value: inline, position: inline}
+- {op: comment, path: /a, position: above}
+`, "a: 1\n")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actual).To(Equal("a: 1 # inline\n"))
+
+		actual, err = applyPatch(`---
+- {op: comment, path: /a, value: above}
+- {op: comment, path: /a}
+`, "a: 1\n")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actual).To(Equal("a: 1\n"))
+	})
+
+	It("keeps a comment with its value as later operations change the document", func() {
+		actual, err := applyPatch(`---
+- {op: comment, path: /items/0, value: first}
+- {op: add, path: /items/0, value: z}
+- {op: comment, path: /a, value: renamed, position: inline}
+- {op: rename, from: /a, path: /b}
+- {op: comment, path: /c, value: replaced}
+- {op: replace, path: /c, value: 2}
+`, "items: [x]\na: 1\nc: 1\n")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actual).To(Equal(`b: 1 # renamed
+c: 2
+items:
+  - z
+  # first
+  - x
+`))
+	})
+
+	It("keeps comments given before a block that is rolled back", func() {
+		actual, err := applyPatch(`---
+- {op: comment, path: /items/1, value: second}
+- op: block
+  on_error: rollback
+  operations:
+  - {op: remove, path: /items/0}
+  - {op: remove, path: /missing}
+`, "items: [x, w]\n")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actual).To(Equal(`items:
+  - x
+  # second
+  - w
+`))
+	})
+
+	It("fails for a missing path or an unknown position", func() {
+		_, err := applyPatch(`[{op: comment, path: /missing, value: text}]`, "a: 1\n")
+		Expect(err).To(MatchError("yamlpatch comment operation does not apply: doc is missing key: /missing"))
+
+		_, err = applyPatch(`[{op: comment, path: /a, value: text, position: below}]`, "a: 1\n")
+		Expect(err).To(MatchError("yamlpatch comment operation has an unknown position: below"))
+	})
+
+	It("fails for an inline comment of more than one line", func() {
+		_, err := applyPatch(`[{op: comment, path: /a, value: "first\nsecond", position: inline}]`, "a: 1\n")
+		Expect(err).To(MatchError("yamlpatch comment operation does not apply: inline comment has more than one line: /a"))
+
+		actual, err := applyPatch(`[{op: comment, path: /a, value: "only\n", position: inline}]`, "a: 1\n")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(actual)).To(Equal("a: 1 # only\n"))
+	})
+})
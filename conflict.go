@@ -0,0 +1,75 @@
+package yamlpatch
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ConflictError is the error for two operations of a patch that modify the
+// same path, identified by their indices within the patch
+type ConflictError struct {
+	Path   string
+	First  int
+	Second int
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("operations %d and %d both modify %s", e.First, e.Second, e.Path)
+}
+
+// Conflicts returns an error for each pair of operations in the patch that
+// modify the same path, in the order of the later operation of each pair.
+// Paths are compared as they are written, so paths that only match the same
+// value once expanded, such as /items/0 and /items/name=web, are not found to
+// conflict. Appends to an array (paths ending in "-") do not conflict, nor do
+// operations that only read the document, which are test and capture, or
+// operations that are disabled. A move modifies both its from and its path.
+func (p Patch) Conflicts() []*ConflictError {
+	var conflicts []*ConflictError
+
+	modified := map[string]int{}
+	for i, op := range p {
+		for _, path := range op.modifiedPaths() {
+			first, ok := modified[path]
+			if ok && first != i {
+				conflicts = append(conflicts, &ConflictError{Path: path, First: first, Second: i})
+				continue
+			}
+
+			modified[path] = i
+		}
+	}
+
+	return conflicts
+}
+
+// modifiedPaths returns the paths that the operation modifies, as written
+func (o *Operation) modifiedPaths() []string {
+	if o.Disabled || o.Op == opTest || o.Op == opCapture {
+		return nil
+	}
+
+	paths := []OpPath{o.Path}
+	if len(o.Paths) > 0 {
+		paths = o.Paths
+	}
+
+	if o.Op == opMove {
+		paths = append(paths, o.From)
+	}
+
+	var modified []string
+	for _, path := range paths {
+		if strings.HasSuffix(string(path), "/-") {
+			continue
+		}
+
+		if o.Scope != "" {
+			path = o.Scope + path
+		}
+
+		modified = append(modified, string(path))
+	}
+
+	return modified
+}
@@ -0,0 +1,66 @@
+package yamlpatch_test
+
+import (
+	yamlpatch "github.com/krishicks/yaml-patch"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Conflicts", func() {
+	var patch yamlpatch.Patch
+
+	BeforeEach(func() {
+		var err error
+		patch, err = yamlpatch.DecodePatch([]byte(`---
+- op: add
+  path: /a
+  value: 1
+- op: test
+  path: /a
+  value: 1
+- op: add
+  path: /list/-
+  value: 1
+- op: add
+  path: /list/-
+  value: 2
+- op: replace
+  path: [/b, /a]
+  value: 2
+- op: move
+  from: /c
+  path: /d
+- op: remove
+  path: /c
+- op: remove
+  path: /a
+  disabled: true
+`))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("returns the operations that modify the same path", func() {
+		conflicts := patch.Conflicts()
+		Expect(conflicts).To(HaveLen(2))
+		Expect(*conflicts[0]).To(Equal(yamlpatch.ConflictError{Path: "/a", First: 0, Second: 4}))
+		Expect(*conflicts[1]).To(Equal(yamlpatch.ConflictError{Path: "/c", First: 5, Second: 6}))
+	})
+
+	It("lets the later operation win by default", func() {
+		actual, err := yamlpatch.Patch{
+			{Op: "add", Path: "/a", Value: nodeOf(1)},
+			{Op: "add", Path: "/a", Value: nodeOf(2)},
+		}.Apply([]byte("{}\n"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(actual)).To(Equal("a: 2\n"))
+	})
+
+	It("fails before performing any operation when RejectConflicts is set", func() {
+		_, err := yamlpatch.Patch{
+			{Op: "add", Path: "/a", Value: nodeOf(1)},
+			{Op: "add", Path: "/a", Value: nodeOf(2)},
+		}.ApplyWithOptions([]byte("{}\n"), yamlpatch.ApplyOptions{RejectConflicts: true})
+		Expect(err).To(MatchError("operations 0 and 1 both modify /a"))
+	})
+})
@@ -0,0 +1,70 @@
+package yamlpatch_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	yamlpatch "github.com/krishicks/yaml-patch"
+	yaml "gopkg.in/yaml.v2"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// conformanceTest is a test of the json-patch-tests corpus. Deviation, which
+// the corpus does not have, explains why this library intentionally behaves
+// differently from RFC 6902 for the test.
+type conformanceTest struct {
+	Comment   string          `json:"comment"`
+	Doc       json.RawMessage `json:"doc"`
+	Patch     json.RawMessage `json:"patch"`
+	Expected  json.RawMessage `json:"expected"`
+	Error     string          `json:"error"`
+	Disabled  bool            `json:"disabled"`
+	Deviation string          `json:"deviation"`
+}
+
+var _ = Describe("RFC 6902 conformance", func() {
+	bs, err := ioutil.ReadFile("testdata/json-patch-tests.json")
+	if err != nil {
+		panic(err)
+	}
+
+	var tests []conformanceTest
+	err = json.Unmarshal(bs, &tests)
+	if err != nil {
+		panic(err)
+	}
+
+	for _, test := range tests {
+		test := test
+
+		It(test.Comment, func() {
+			if test.Disabled {
+				Skip("disabled in the corpus")
+			}
+
+			if test.Deviation != "" {
+				Skip(test.Deviation)
+			}
+
+			var actual []byte
+			patch, err := yamlpatch.DecodePatch(test.Patch)
+			if err == nil {
+				actual, err = patch.Apply(test.Doc)
+			}
+
+			if test.Error != "" {
+				Expect(err).To(HaveOccurred(), test.Error)
+				return
+			}
+
+			Expect(err).NotTo(HaveOccurred())
+
+			var actualValue, expectedValue interface{}
+			Expect(yaml.Unmarshal(actual, &actualValue)).To(Succeed())
+			Expect(yaml.Unmarshal(test.Expected, &expectedValue)).To(Succeed())
+			Expect(actualValue).To(Equal(expectedValue))
+		})
+	}
+})
@@ -1,6 +1,7 @@
 package yamlpatch
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -16,56 +17,81 @@ type Container interface {
 
 type nodeMap map[interface{}]*Node
 
+// mapKey returns the key present in the map that the given path segment
+// refers to. YAML allows non-string keys, so when no string key matches, the
+// segment is tried as an integer key. When neither is present the segment is
+// returned as a string key.
+func (n *nodeMap) mapKey(key string) interface{} {
+	if _, ok := (*n)[key]; ok {
+		return key
+	}
+
+	if i, err := strconv.Atoi(key); err == nil {
+		if _, ok := (*n)[i]; ok {
+			return i
+		}
+	}
+
+	return key
+}
+
+func (n *nodeMap) has(key string) bool {
+	_, ok := (*n)[n.mapKey(key)]
+	return ok
+}
+
 func (n *nodeMap) Set(key string, val *Node) error {
-	(*n)[key] = val
+	(*n)[n.mapKey(key)] = val
 	return nil
 }
 
 func (n *nodeMap) Add(key string, val *Node) error {
-	(*n)[key] = val
+	(*n)[n.mapKey(key)] = val
 	return nil
 }
 
 func (n *nodeMap) Get(key string) (*Node, error) {
-	return (*n)[key], nil
+	return (*n)[n.mapKey(key)], nil
 }
 
 func (n *nodeMap) Remove(key string) error {
-	_, ok := (*n)[key]
+	k := n.mapKey(key)
+
+	_, ok := (*n)[k]
 	if !ok {
 		return fmt.Errorf("Unable to remove nonexistent key: %s", key)
 	}
 
-	delete(*n, key)
+	delete(*n, k)
 	return nil
 }
 
 type nodeSlice []*Node
 
+// Set replaces the element at the index. An index equal to the length of the
+// slice, or "-", appends the element. Any other index outside the slice is an
+// error, rather than growing the slice and leaving null elements in the gap.
 func (n *nodeSlice) Set(index string, val *Node) error {
-	i, err := strconv.Atoi(index)
-	if err != nil {
-		return err
+	if index == "-" {
+		*n = append(*n, val)
+		return nil
 	}
 
-	sz := len(*n)
-	if i+1 > sz {
-		sz = i + 1
+	i, err := parseIndex(index)
+	if err != nil {
+		return err
 	}
 
-	ary := make([]*Node, sz)
-
-	cur := *n
-
-	copy(ary, cur)
-
-	if i >= len(ary) {
+	if i < 0 || i > len(*n) {
 		return fmt.Errorf("Unable to access invalid index: %d", i)
 	}
 
-	ary[i] = val
+	if i == len(*n) {
+		*n = append(*n, val)
+		return nil
+	}
 
-	*n = ary
+	(*n)[i] = val
 	return nil
 }
 
@@ -75,11 +101,15 @@ func (n *nodeSlice) Add(index string, val *Node) error {
 		return nil
 	}
 
-	i, err := strconv.Atoi(index)
+	i, err := parseIndex(index)
 	if err != nil {
 		return err
 	}
 
+	if i < 0 || i > len(*n) {
+		return fmt.Errorf("Unable to access invalid index: %d", i)
+	}
+
 	ary := make([]*Node, len(*n)+1)
 
 	cur := *n
@@ -93,7 +123,7 @@ func (n *nodeSlice) Add(index string, val *Node) error {
 }
 
 func (n *nodeSlice) Get(index string) (*Node, error) {
-	i, err := strconv.Atoi(index)
+	i, err := parseIndex(index)
 	if err != nil {
 		return nil, err
 	}
@@ -106,14 +136,14 @@ func (n *nodeSlice) Get(index string) (*Node, error) {
 }
 
 func (n *nodeSlice) Remove(index string) error {
-	i, err := strconv.Atoi(index)
+	i, err := parseIndex(index)
 	if err != nil {
 		return err
 	}
 
 	cur := *n
 
-	if i >= len(cur) {
+	if i < 0 || i >= len(cur) {
 		return fmt.Errorf("Unable to remove invalid index: %d", i)
 	}
 
@@ -127,7 +157,35 @@ func (n *nodeSlice) Remove(index string) error {
 
 }
 
+// parseIndex parses the index of an array element in a path. As RFC 6901
+// requires, an index other than 0 cannot begin with 0, and a + sign is not
+// allowed.
+func parseIndex(index string) (int, error) {
+	if strings.HasPrefix(index, "+") || (len(index) > 1 && index[0] == '0') {
+		return 0, fmt.Errorf("invalid array index: %s", index)
+	}
+
+	return strconv.Atoi(index)
+}
+
+// splice replaces the elements from start up to but not including end with the
+// given elements. The range must lie within the slice.
+func (n *nodeSlice) splice(start, end int, vals []*Node) {
+	cur := *n
+
+	ary := make([]*Node, 0, len(cur)-(end-start)+len(vals))
+	ary = append(ary, cur[:start]...)
+	ary = append(ary, vals...)
+	ary = append(ary, cur[end:]...)
+
+	*n = ary
+}
+
 func findContainer(c Container, path *OpPath) (Container, string, error) {
+	if _, ok := c.(*rootHolder); ok && *path == "" {
+		return c, "", nil
+	}
+
 	parts, key, err := path.Decompose()
 	if err != nil {
 		return nil, "", err
@@ -135,7 +193,17 @@ func findContainer(c Container, path *OpPath) (Container, string, error) {
 
 	foundContainer := c
 
-	for _, part := range parts {
+	for i, part := range parts {
+		if foundContainer == nil {
+			return nil, "", fmt.Errorf("path does not exist: %s", path)
+		}
+
+		if _, ok := foundContainer.(*nodeSlice); ok {
+			if _, err := parseIndex(part); err != nil {
+				return nil, "", &typeMismatchError{path: joinPath(parts[:i]), expected: "map", found: "array"}
+			}
+		}
+
 		node, err := foundContainer.Get(decodePatchKey(part))
 		if err != nil {
 			return nil, "", err
@@ -146,11 +214,80 @@ func findContainer(c Container, path *OpPath) (Container, string, error) {
 		}
 
 		foundContainer = node.Container()
+
+		if foundContainer == nil {
+			return nil, "", &typeMismatchError{path: joinPath(parts[:i+1]), expected: "map or array", found: kindName(*node.raw)}
+		}
+	}
+
+	if h, ok := foundContainer.(*rootHolder); ok {
+		foundContainer = h.root.Container()
+	}
+
+	if foundContainer == nil {
+		return nil, "", fmt.Errorf("path does not exist: %s", path)
 	}
 
 	return foundContainer, decodePatchKey(key), nil
 }
 
+// typeMismatchError is the error for a path that cannot be traversed because
+// a value along it is not of a kind that can be descended into
+type typeMismatchError struct {
+	path     string
+	expected string
+	found    string
+}
+
+func (e *typeMismatchError) Error() string {
+	return fmt.Sprintf("cannot descend into %s: expected %s but found %s", e.path, e.expected, e.found)
+}
+
+// pathError returns the error for an operation whose path could not be found,
+// with the type mismatch that prevented the path being traversed appended
+// when that was the cause
+func pathError(cause error, format string, args ...interface{}) error {
+	msg := fmt.Sprintf(format, args...)
+
+	if mismatch, ok := cause.(*typeMismatchError); ok {
+		return fmt.Errorf("%s: %s", msg, mismatch)
+	}
+
+	return errors.New(msg)
+}
+
+// joinPath returns the path made of the given segments, which are still
+// escaped
+func joinPath(parts []string) string {
+	if len(parts) == 0 {
+		return "/"
+	}
+
+	return "/" + strings.Join(parts, "/")
+}
+
+// kindName returns the name of the kind of a decoded value
+func kindName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case map[interface{}]interface{}:
+		return "map"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case int, int64, uint, uint64:
+		return "integer"
+	case float64:
+		return "float"
+	}
+
+	return fmt.Sprintf("%T", v)
+}
+
 // From http://tools.ietf.org/html/rfc6901#section-4 :
 //
 // Evaluation of each reference token begins by decoding any escaped
@@ -160,8 +297,13 @@ func findContainer(c Container, path *OpPath) (Container, string, error) {
 
 var (
 	rfc6901Decoder = strings.NewReplacer("~1", "/", "~0", "~")
+	rfc6901Encoder = strings.NewReplacer("~", "~0", "/", "~1")
 )
 
 func decodePatchKey(k string) string {
 	return rfc6901Decoder.Replace(k)
 }
+
+func encodePatchKey(k string) string {
+	return rfc6901Encoder.Replace(k)
+}
@@ -0,0 +1,187 @@
+package yamlpatch
+
+import (
+	"fmt"
+	"strings"
+)
+
+// documentPathPrefix begins a path that names a document of a stream, as in
+// $1/config/x, the pointer /config/x of the stream's second document
+const documentPathPrefix = "$"
+
+// isDocumentPath returns whether the path names a document of a stream
+func isDocumentPath(path OpPath) bool {
+	return strings.HasPrefix(string(path), documentPathPrefix)
+}
+
+// splitDocumentPath splits a path of the form $N/pointer into the index N of
+// the document and the pointer within it. $N alone refers to the whole
+// document.
+func splitDocumentPath(path OpPath) (int, OpPath, error) {
+	rest := strings.TrimPrefix(string(path), documentPathPrefix)
+
+	end := strings.Index(rest, "/")
+	if end < 0 {
+		end = len(rest)
+	}
+
+	index, err := parseIndex(rest[:end])
+	if err != nil || index < 0 {
+		return 0, "", fmt.Errorf("path %s does not begin with a document index such as $0", path)
+	}
+
+	pointer := OpPath(rest[end:])
+
+	err = ValidatePath(string(pointer))
+	if err != nil {
+		return 0, "", err
+	}
+
+	return index, pointer, nil
+}
+
+// isCrossDocument returns whether the operation moves or copies a value
+// between documents of a stream
+func (o *Operation) isCrossDocument() bool {
+	return isDocumentPath(o.Path) || isDocumentPath(o.From)
+}
+
+// validateDocumentPaths returns an error when the operation names documents
+// of a stream in a way that is not supported: only copy and move operations
+// can, and then both their from and path must
+func (o *Operation) validateDocumentPaths() error {
+	if !o.isCrossDocument() {
+		return nil
+	}
+
+	if o.Op != opCopy && o.Op != opMove {
+		return fmt.Errorf("yamlpatch %s operation cannot name a document: only copy and move operations can", o.Op)
+	}
+
+	if !isDocumentPath(o.Path) || !isDocumentPath(o.From) {
+		return fmt.Errorf("yamlpatch %s operation names a document in only one of from and path", o.Op)
+	}
+
+	for _, path := range []OpPath{o.From, o.Path} {
+		_, _, err := splitDocumentPath(path)
+		if err != nil {
+			return fmt.Errorf("yamlpatch %s operation has an invalid path: %s", o.Op, err)
+		}
+	}
+
+	return nil
+}
+
+// performCrossDocument copies or moves the value at the operation's from,
+// within one document of the stream, to its path, within another or the
+// same. Documents that are empty, or could not be decoded, have a nil root.
+// A move between documents removes the value from its source only once it
+// has been placed, so that an operation that fails leaves both documents as
+// they were. Between documents, a path that is an index of an array inserts
+// the value before the element at the index, as an add operation does,
+// rather than replacing it.
+func (o *Operation) performCrossDocument(roots []*Node, ctx *applyContext) error {
+	fail := func(format string, args ...interface{}) error {
+		return fmt.Errorf("yamlpatch %s operation does not apply: %s", o.Op, fmt.Sprintf(format, args...))
+	}
+
+	disabled, err := o.disabledByVariable(ctx)
+	if err != nil || disabled {
+		return err
+	}
+
+	src, from, err := splitDocumentPath(o.From)
+	if err != nil {
+		return fail("%s", err)
+	}
+
+	dst, path, err := splitDocumentPath(o.Path)
+	if err != nil {
+		return fail("%s", err)
+	}
+
+	for _, i := range []int{src, dst} {
+		if i >= len(roots) || roots[i] == nil {
+			return fail("stream has no document %d", i)
+		}
+	}
+
+	srcDoc := &rootHolder{root: roots[src]}
+	dstDoc := &rootHolder{root: roots[dst]}
+
+	if src == dst {
+		local := *o
+		local.From, local.Path = from, path
+
+		if ctx.opts.NoClobber {
+			if con, key, err := findContainer(srcDoc, &local.Path); err == nil {
+				err = o.checkClobberAt(con, key)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		if o.Op == opMove {
+			return tryMove(srcDoc, &local)
+		}
+		return tryCopy(srcDoc, &local)
+	}
+
+	srcCon, srcKey, err := findContainer(srcDoc, &from)
+	if err != nil {
+		return pathError(err, "yamlpatch %s operation does not apply: document %d is missing from path: %s", o.Op, src, from)
+	}
+
+	val, err := srcCon.Get(srcKey)
+	if err != nil {
+		return err
+	}
+
+	if val == nil {
+		return fail("document %d is missing from path: %s", src, from)
+	}
+
+	val = val.Clone()
+
+	if o.Transform != "" {
+		val, err = transformNode(o, val)
+		if err != nil {
+			return fail("%s: %s", err, o.From)
+		}
+	}
+
+	err = checkValueDepth(val.plain(), path, ctx.opts.maxDepth())
+	if err != nil {
+		return fail("%s", err)
+	}
+
+	dstCon, dstKey, err := findContainer(dstDoc, &path)
+	if err != nil {
+		return pathError(err, "yamlpatch %s operation does not apply: document %d is missing destination path: %s", o.Op, dst, path)
+	}
+
+	if ctx.opts.NoClobber {
+		err = o.checkClobberAt(dstCon, dstKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = dstCon.Add(dstKey, val)
+	if err != nil {
+		return err
+	}
+
+	if o.Op != opMove {
+		return nil
+	}
+
+	return srcCon.Remove(srcKey)
+}
+
+// crossDocumentError is returned for an operation naming documents that is
+// performed outside of a stream
+func crossDocumentError(o *Operation) error {
+	return fmt.Errorf("yamlpatch %s operation does not apply: paths naming a document, such as %s, can only be used when applying a stream", o.Op, o.From)
+}
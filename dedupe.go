@@ -0,0 +1,65 @@
+package yamlpatch
+
+import "fmt"
+
+// tryDedupe removes the elements of the slice at the operation's path that
+// are equal to an earlier element, keeping the first of each in order. With
+// by, elements of a slice of maps are compared by the value of that key
+// alone, and those without the key are kept.
+func tryDedupe(doc Container, op *Operation) error {
+	con, key, err := findContainer(doc, &op.Path)
+	if err != nil {
+		return pathError(err, "yamlpatch dedupe operation does not apply: doc is missing path: %s", op.Path)
+	}
+
+	val, err := con.Get(key)
+	if val == nil || err != nil {
+		return fmt.Errorf("yamlpatch dedupe operation does not apply: doc is missing key: %s", op.Path)
+	}
+
+	ary, ok := val.Container().(*nodeSlice)
+	if !ok {
+		return fmt.Errorf("yamlpatch dedupe operation does not apply: value is not a slice: %s", pathOrRoot(string(op.Path)))
+	}
+
+	var seen []*Node
+	kept := nodeSlice{}
+
+	for i, elem := range *ary {
+		id := elem
+		if op.By != "" {
+			m, ok := elem.Container().(*nodeMap)
+			if !ok {
+				return fmt.Errorf("yamlpatch dedupe operation does not apply: element %d is not a map: %s", i, pathOrRoot(string(op.Path)))
+			}
+
+			id, _ = m.Get(op.By)
+			if id == nil {
+				kept = append(kept, elem)
+				continue
+			}
+		}
+
+		if containsEqual(seen, id) {
+			continue
+		}
+
+		seen = append(seen, id)
+		kept = append(kept, elem)
+	}
+
+	*ary = kept
+
+	return nil
+}
+
+// containsEqual returns whether any of the nodes is equal to n
+func containsEqual(nodes []*Node, n *Node) bool {
+	for _, other := range nodes {
+		if other.Equal(n) {
+			return true
+		}
+	}
+
+	return false
+}
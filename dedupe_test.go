@@ -0,0 +1,61 @@
+package yamlpatch_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("dedupe", func() {
+	It("removes duplicate elements, keeping the first of each in order", func() {
+		actual, err := applyPatch(`[{op: dedupe, path: /hosts}]`, `hosts:
+- b.example.com
+- a.example.com
+- b.example.com
+- {name: c}
+- a.example.com
+- {name: c}
+`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actual).To(Equal(`hosts:
+- b.example.com
+- a.example.com
+- name: c
+`))
+	})
+
+	It("compares maps by the value of a key with by", func() {
+		actual, err := applyPatch(`[{op: dedupe, path: /ports, by: name}]`, `ports:
+- {name: http, port: 80}
+- {name: https, port: 443}
+- {name: http, port: 8080}
+- {port: 9090}
+- {port: 9090}
+`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actual).To(Equal(`ports:
+- name: http
+  port: 80
+- name: https
+  port: 443
+- port: 9090
+- port: 9090
+`))
+	})
+
+	It("dedupes the whole document with the empty path", func() {
+		actual, err := applyPatch(`[{op: dedupe, path: ""}]`, "[1, 2, 1, 3, 2]\n")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actual).To(Equal("- 1\n- 2\n- 3\n"))
+	})
+
+	DescribeTable("fails",
+		func(ops, doc, message string) {
+			_, err := applyPatch(ops, doc)
+			Expect(err).To(MatchError(message))
+		},
+		Entry("for a value that is not a slice", `[{op: dedupe, path: /a}]`, "a: {b: 1}\n", "yamlpatch dedupe operation does not apply: value is not a slice: /a"),
+		Entry("for a missing key", `[{op: dedupe, path: /b}]`, "a: []\n", "yamlpatch dedupe operation does not apply: doc is missing key: /b"),
+		Entry("for an element that is not a map with by", `[{op: dedupe, path: /a, by: name}]`, "a: [{name: x}, y]\n", "yamlpatch dedupe operation does not apply: element 1 is not a map: /a"),
+	)
+})
@@ -0,0 +1,163 @@
+package yamlpatch
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// DefaultMaxDepth is the maximum nesting depth of a document that is used when
+// ApplyOptions does not specify one
+const DefaultMaxDepth = 10000
+
+// depthChecker walks a decoded document, failing when it is nested more
+// deeply than allowed or when a map or slice contains itself
+type depthChecker struct {
+	subject string
+	max     int
+	depth   int
+
+	// active holds the maps and slices that are ancestors of the value being
+	// checked
+	active map[uintptr]bool
+}
+
+// depthError is returned by a depthChecker. The keys and indices leading to
+// the offending value are collected as the walk unwinds, so that no path is
+// built for documents that pass.
+type depthError struct {
+	format string
+	args   []interface{}
+	path   []string
+
+	// prefix is the pointer to the value that was checked within its
+	// document, or empty for the whole document
+	prefix string
+}
+
+func (e *depthError) Error() string {
+	segments := make([]string, len(e.path))
+	for i := range e.path {
+		segments[i] = e.path[len(e.path)-1-i]
+	}
+
+	path := e.prefix + "/" + strings.Join(segments, "/")
+	if len(segments) == 0 && e.prefix != "" {
+		path = e.prefix
+	}
+
+	return fmt.Sprintf(e.format, append(e.args, path)...)
+}
+
+// checkDepth returns an error when v is nested more deeply than maxDepth or
+// contains a cycle
+func checkDepth(v interface{}, maxDepth int) error {
+	c := &depthChecker{
+		subject: "document",
+		max:     maxDepth,
+		active:  map[uintptr]bool{},
+	}
+
+	err := c.check(v)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// checkValueDepth returns an error when v, placed at the path within a
+// document, would be nested more deeply than maxDepth. Each segment of the
+// path counts as one level of the document above the value.
+func checkValueDepth(v interface{}, path OpPath, maxDepth int) error {
+	c := &depthChecker{
+		subject: "value",
+		max:     maxDepth,
+		depth:   strings.Count(string(path), "/"),
+		active:  map[uintptr]bool{},
+	}
+
+	err := c.check(v)
+	if err != nil {
+		err.prefix = string(path)
+		return err
+	}
+
+	return nil
+}
+
+// checkDepth returns an error when the value the operation places in the
+// document, its value or the value at its from, would be nested more deeply
+// than maxDepth. Missing paths are left for the operation to report.
+func (o *Operation) checkDepth(c Container, maxDepth int) error {
+	switch {
+	case o.Op == opCopy || o.Op == opMove:
+		from := o.From
+
+		con, key, err := findContainer(c, &from)
+		if err != nil {
+			return nil
+		}
+
+		val, err := con.Get(key)
+		if err != nil || val == nil {
+			return nil
+		}
+
+		return checkValueDepth(val.plain(), o.Path, maxDepth)
+	case o.Value != nil:
+		return checkValueDepth(o.Value.plain(), o.Path, maxDepth)
+	}
+
+	return nil
+}
+
+func (c *depthChecker) check(v interface{}) *depthError {
+	var ptr uintptr
+	switch it := v.(type) {
+	case map[interface{}]interface{}:
+		ptr = reflect.ValueOf(it).Pointer()
+	case []interface{}:
+		if len(it) == 0 {
+			return nil
+		}
+		ptr = reflect.ValueOf(it).Pointer()
+	default:
+		return nil
+	}
+
+	if c.active[ptr] {
+		return &depthError{format: "%s contains a cycle at %s", args: []interface{}{c.subject}}
+	}
+
+	if c.depth >= c.max {
+		return &depthError{format: "%s exceeds the maximum depth of %d at %s", args: []interface{}{c.subject, c.max}}
+	}
+
+	c.active[ptr] = true
+	c.depth++
+
+	var err *depthError
+	switch it := v.(type) {
+	case map[interface{}]interface{}:
+		for k, v := range it {
+			if err = c.check(v); err != nil {
+				err.path = append(err.path, fmt.Sprint(k))
+				break
+			}
+		}
+	case []interface{}:
+		for i, v := range it {
+			if err = c.check(v); err != nil {
+				err.path = append(err.path, strconv.Itoa(i))
+				break
+			}
+		}
+	}
+
+	c.depth--
+	delete(c.active, ptr)
+
+	return err
+}
@@ -0,0 +1,54 @@
+package yamlpatch
+
+import "fmt"
+
+// unmarshalDisabled decodes the disabled field of an operation, which is
+// either a bool or a reference to a captured variable, {{var:name}}, whose
+// value disables the operation when the patch is applied
+func (o *Operation) unmarshalDisabled(unmarshal func(interface{}) error) error {
+	var fields struct {
+		Disabled interface{} `yaml:"disabled"`
+	}
+
+	err := unmarshal(&fields)
+	if err != nil {
+		return err
+	}
+
+	switch v := fields.Disabled.(type) {
+	case nil:
+	case bool:
+		o.Disabled = v
+	case string:
+		m := referenceRegex.FindStringSubmatch(v)
+		if m == nil || m[0] != v || m[1] != "var" {
+			return fmt.Errorf("yamlpatch %s operation disabled is not a bool or a reference to a variable: %s", o.Op, v)
+		}
+		o.DisabledBy = m[2]
+	default:
+		return fmt.Errorf("yamlpatch %s operation disabled is not a bool or a reference to a variable: %v", o.Op, v)
+	}
+
+	return nil
+}
+
+// disabledByVariable returns whether the variable named by the operation's
+// DisabledBy is true, as it is when the operation is performed. It is an error
+// for the variable to be undefined or not a bool.
+func (o *Operation) disabledByVariable(ctx *applyContext) (bool, error) {
+	if o.DisabledBy == "" {
+		return false, nil
+	}
+
+	val, err := lookupReference("var", o.DisabledBy, ctx)
+	if err != nil {
+		return false, fmt.Errorf("yamlpatch %s operation does not apply: %s", o.Op, err)
+	}
+
+	disabled, ok := val.(bool)
+	if !ok {
+		return false, fmt.Errorf("yamlpatch %s operation does not apply: variable %s is not a bool: %v", o.Op, o.DisabledBy, val)
+	}
+
+	return disabled, nil
+}
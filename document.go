@@ -0,0 +1,100 @@
+package yamlpatch
+
+import "fmt"
+
+// ParseDocument decodes a YAML document into a Node that any number of
+// patches can be applied to with ApplyToNode, without the document being
+// parsed and marshaled again for each patch
+func ParseDocument(doc []byte) (*Node, error) {
+	return ParseDocumentWithOptions(doc, ApplyOptions{})
+}
+
+// ParseDocumentWithOptions is ParseDocument for a document decoded as the
+// options decode it, as ApplyToNode with the same options expects
+func ParseDocumentWithOptions(doc []byte, opts ApplyOptions) (*Node, error) {
+	iface, err := opts.codec().unmarshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed unmarshaling doc: %s\n\n%s", string(doc), err)
+	}
+
+	return NewNode(&iface), nil
+}
+
+// ApplyToNode mutates the document held by the node per the patch. The node
+// is typically one returned by ParseDocument. Its depth is checked against
+// the options' MaxDepth by the first patch applied to it. The options'
+// output settings have no effect; pass them to MarshalNode instead.
+func (p Patch) ApplyToNode(n *Node, opts ApplyOptions) error {
+	ctx := newApplyContext(opts)
+
+	if n.container == nil {
+		err := checkDepth(*n.raw, opts.maxDepth())
+		if err != nil {
+			return err
+		}
+	}
+
+	return p.applyTo(n, ctx)
+}
+
+// MarshalNode returns the document held by the node as YAML, emitted using
+// the given options
+func MarshalNode(n *Node, opts ApplyOptions) ([]byte, error) {
+	return opts.marshal(n, nil)
+}
+
+// ApplyPatches applies each of the patches to the document in turn, as
+// applying them one after the other with ApplyWithOptions does, but to a
+// single decoded document that is marshaled only once at the end, which is
+// much cheaper for many small patches. Each patch orders its own operations
+// by priority and is checked for conflicts on its own. Unlike with separate
+// applications, the variables one patch captures can be referenced by the
+// patches after it. When the options are best-effort, the *ApplyError of each
+// failing operation gives its index counted across all of the patches. A
+// document that cannot be decoded fails with an *ApplyError whose Operation is
+// -1.
+func ApplyPatches(doc []byte, patches []Patch, opts ApplyOptions) ([]byte, error) {
+	ctx := newApplyContext(opts)
+
+	iface, err := opts.codec().unmarshal(doc)
+	if err != nil {
+		return nil, &ApplyError{Document: -1, Operation: -1, Err: fmt.Errorf("failed unmarshaling doc: %s\n\n%s", string(doc), err)}
+	}
+
+	err = checkDepth(iface, opts.maxDepth())
+	if err != nil {
+		return nil, err
+	}
+
+	root := NewNode(&iface)
+	errs := &MultiError{}
+
+	offset := 0
+	for _, p := range patches {
+		err := p.applyTo(root, ctx)
+		if m, ok := err.(*MultiError); ok {
+			for _, e := range m.errs {
+				e.(*ApplyError).Operation += offset
+				errs.append(e)
+			}
+		} else if err != nil {
+			return nil, err
+		}
+
+		offset += len(p)
+	}
+
+	bs, err := ctx.marshal(root, doc, findExplicitTags(doc, opts.codec()))
+	if err != nil {
+		return nil, err
+	}
+
+	bs = opts.frameLike(doc, bs)
+
+	err = opts.checkOutputSize(bs)
+	if err != nil {
+		return nil, err
+	}
+
+	return bs, errs.errorOrNil()
+}
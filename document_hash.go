@@ -0,0 +1,76 @@
+package yamlpatch
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// DocumentSHA256 returns the hex-encoded SHA-256 hash of the canonical form
+// of the document, in which the keys of every map are sorted. Documents that
+// differ only in formatting, comments or the order of their keys have the
+// same hash. It is the hash a test operation's document_sha256 is compared
+// with when its path is empty.
+func DocumentSHA256(doc []byte) (string, error) {
+	return DocumentSHA256WithOptions(doc, ApplyOptions{})
+}
+
+// DocumentSHA256WithOptions is DocumentSHA256 for a document decoded as the
+// options decode it, so that with YAML12 a document whose yes is a string
+// has the hash of the document it is patched into
+func DocumentSHA256WithOptions(doc []byte, opts ApplyOptions) (string, error) {
+	codec := opts.codec()
+
+	v, err := codec.unmarshal(doc)
+	if err != nil {
+		return "", fmt.Errorf("failed unmarshaling doc: %s\n\n%s", string(doc), err)
+	}
+
+	return codec.canonicalSHA256(v)
+}
+
+// ApplyWithHash is Apply, additionally returning the hex-encoded SHA-256 hash
+// of the canonical form of the patched document, as DocumentSHA256 returns
+// for it. The hash changes only when the patched document's content does,
+// not its formatting, so it can key a cache or detect whether the output of
+// a patch has changed.
+func (p Patch) ApplyWithHash(doc []byte) ([]byte, string, error) {
+	bs, err := p.Apply(doc)
+	if err != nil {
+		return nil, "", err
+	}
+
+	sum, err := DocumentSHA256(bs)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return bs, sum, nil
+}
+
+// canonicalSHA256 returns the hex-encoded SHA-256 hash of the decoded value
+// marshaled with its keys sorted
+func (c yamlCodec) canonicalSHA256(v interface{}) (string, error) {
+	bs, err := c.marshal(sortKeys(v))
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(bs)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// testDocumentSHA256 compares the canonical hash of the value at a test
+// operation's path with its document_sha256
+func testDocumentSHA256(op *Operation, val *Node) error {
+	sum, err := defaultCodec.canonicalSHA256(val.plain())
+	if err != nil {
+		return err
+	}
+
+	if sum != op.DocumentSHA256 {
+		return fmt.Errorf("test failed: sha256 of %s is %s, not %s", pathOrRoot(string(op.Path)), sum, op.DocumentSHA256)
+	}
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package yamlpatch_test
+
+import (
+	"fmt"
+
+	yamlpatch "github.com/krishicks/yaml-patch"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("document_sha256", func() {
+	doc := []byte("name: web\nspec:\n  replicas: 1\n")
+
+	guarded := func(path, sum string) yamlpatch.Patch {
+		patch, err := yamlpatch.DecodePatch([]byte(fmt.Sprintf(`---
+- op: test
+  path: %q
+  document_sha256: %s
+- op: replace
+  path: /spec/replicas
+  value: 3
+`, path, sum)))
+		Expect(err).NotTo(HaveOccurred())
+		return patch
+	}
+
+	It("applies the patch when the document has the hash", func() {
+		sum, err := yamlpatch.DocumentSHA256(doc)
+		Expect(err).NotTo(HaveOccurred())
+
+		actual, err := guarded("", sum).Apply(doc)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(actual)).To(Equal("name: web\nspec:\n  replicas: 3\n"))
+	})
+
+	It("hashes documents that differ only in formatting and key order the same", func() {
+		sum, err := yamlpatch.DocumentSHA256(doc)
+		Expect(err).NotTo(HaveOccurred())
+
+		other, err := yamlpatch.DocumentSHA256([]byte("---\n# the web app\nspec: {replicas: 1}\nname: 'web'\n"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(other).To(Equal(sum))
+	})
+
+	It("decodes the document as the options do", func() {
+		yes, err := yamlpatch.DocumentSHA256WithOptions([]byte("enabled: yes\n"), yamlpatch.ApplyOptions{YAMLVersion: yamlpatch.YAML12})
+		Expect(err).NotTo(HaveOccurred())
+
+		quoted, err := yamlpatch.DocumentSHA256([]byte("enabled: \"yes\"\n"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(yes).To(Equal(quoted))
+
+		boolean, err := yamlpatch.DocumentSHA256([]byte("enabled: yes\n"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(boolean).NotTo(Equal(quoted))
+	})
+
+	It("hashes keys of different types written the same the same every time", func() {
+		colliding := "{\"1\": a, 1: b, \"true\": c, true: d}"
+
+		sum, err := yamlpatch.DocumentSHA256([]byte(colliding))
+		Expect(err).NotTo(HaveOccurred())
+
+		test, err := yamlpatch.DecodePatch([]byte(fmt.Sprintf(`[{op: test, path: "", document_sha256: %s}]`, sum)))
+		Expect(err).NotTo(HaveOccurred())
+
+		checksum, err := yamlpatch.DecodePatch([]byte(`[{op: checksum, from: /data, path: /sum}, {op: remove, path: /data}]`))
+		Expect(err).NotTo(HaveOccurred())
+
+		for i := 0; i < 50; i++ {
+			Expect(yamlpatch.DocumentSHA256([]byte(colliding))).To(Equal(sum))
+
+			_, err := test.Apply([]byte(colliding))
+			Expect(err).NotTo(HaveOccurred())
+
+			actual, err := checksum.Apply([]byte("data: " + colliding + "\n"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(actual)).To(Equal("sum: " + sum + "\n"))
+		}
+	})
+
+	It("fails when the document has changed", func() {
+		sum, err := yamlpatch.DocumentSHA256(doc)
+		Expect(err).NotTo(HaveOccurred())
+
+		changed := []byte("name: web\nspec:\n  replicas: 2\n")
+		changedSum, err := yamlpatch.DocumentSHA256(changed)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = guarded("", sum).Apply(changed)
+		Expect(err).To(MatchError(fmt.Sprintf("test failed: sha256 of / is %s, not %s", changedSum, sum)))
+	})
+
+	It("hashes the value at the path", func() {
+		sum, err := yamlpatch.DocumentSHA256([]byte("replicas: 1\n"))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = guarded("/spec", sum).Apply([]byte("name: changed\nspec:\n  replicas: 1\n"))
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
+
+var _ = Describe("ApplyWithHash", func() {
+	patch := yamlpatch.Patch{{Op: "replace", Path: "/spec/replicas", Value: nodeOf(3)}}
+
+	It("returns the patched document and the hash of its canonical form", func() {
+		actual, sum, err := patch.ApplyWithHash([]byte("name: web\nspec:\n  replicas: 1\n"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(actual)).To(Equal("name: web\nspec:\n  replicas: 3\n"))
+
+		expected, err := yamlpatch.DocumentSHA256(actual)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sum).To(Equal(expected))
+	})
+
+	It("returns the same hash for sources that differ only in formatting", func() {
+		_, sum, err := patch.ApplyWithHash([]byte("name: web\nspec:\n  replicas: 1\n"))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, other, err := patch.ApplyWithHash([]byte("# the web app\nspec: {replicas: 0x1}\nname: \"web\"\n"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(other).To(Equal(sum))
+	})
+
+	It("returns the error of a patch that fails", func() {
+		_, sum, err := patch.ApplyWithHash([]byte("name: web\n"))
+		Expect(err).To(HaveOccurred())
+		Expect(sum).To(BeEmpty())
+	})
+})
@@ -0,0 +1,106 @@
+package yamlpatch_test
+
+import (
+	yamlpatch "github.com/krishicks/yaml-patch"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ParseDocument", func() {
+	It("applies successive patches to the decoded document", func() {
+		node, err := yamlpatch.ParseDocument([]byte(`{a: 1, b: [x]}`))
+		Expect(err).NotTo(HaveOccurred())
+
+		first, err := yamlpatch.DecodePatch([]byte(`[{op: add, path: /b/-, value: z}]`))
+		Expect(err).NotTo(HaveOccurred())
+
+		second, err := yamlpatch.DecodePatch([]byte(`[{op: replace, path: /a, value: 2}, {op: test, path: /b/1, value: z}]`))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(first.ApplyToNode(node, yamlpatch.ApplyOptions{})).To(Succeed())
+		Expect(second.ApplyToNode(node, yamlpatch.ApplyOptions{})).To(Succeed())
+
+		actual, err := yamlpatch.MarshalNode(node, yamlpatch.ApplyOptions{SortKeys: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actual).To(MatchYAML(`{a: 2, b: [x, z]}`))
+	})
+
+	It("decodes the document as the options decode it with ParseDocumentWithOptions", func() {
+		node, err := yamlpatch.ParseDocumentWithOptions([]byte(`{a: yes, b: 0644}`), yamlpatch.ApplyOptions{YAMLVersion: yamlpatch.YAML12})
+		Expect(err).NotTo(HaveOccurred())
+
+		patch, err := yamlpatch.DecodePatch([]byte(`[{op: test, path: /a, value: "yes"}, {op: test, path: /b, value: 644}]`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(patch.ApplyToNode(node, yamlpatch.ApplyOptions{YAMLVersion: yamlpatch.YAML12})).To(Succeed())
+	})
+
+	It("checks the depth of the document when the first patch is applied", func() {
+		node, err := yamlpatch.ParseDocument([]byte(`{a: {b: {c: {}}}}`))
+		Expect(err).NotTo(HaveOccurred())
+
+		err = yamlpatch.Patch{}.ApplyToNode(node, yamlpatch.ApplyOptions{MaxDepth: 2})
+		Expect(err).To(MatchError("document exceeds the maximum depth of 2 at /a/b"))
+	})
+
+	It("rejects a document that contains a cycle", func() {
+		inner := map[interface{}]interface{}{"b": 1}
+		inner["self"] = inner
+		var doc interface{} = map[interface{}]interface{}{"a": inner}
+
+		err := yamlpatch.Patch{}.ApplyToNode(yamlpatch.NewNode(&doc), yamlpatch.ApplyOptions{})
+		Expect(err).To(MatchError("document contains a cycle at /a/self"))
+	})
+
+	It("rejects an operation whose value contains a cycle", func() {
+		node, err := yamlpatch.ParseDocument([]byte(`{a: 1}`))
+		Expect(err).NotTo(HaveOccurred())
+
+		items := []interface{}{"x", nil}
+		items[1] = items
+		var value interface{} = items
+
+		patch := yamlpatch.Patch{{Op: "add", Path: "/b", Value: yamlpatch.NewNode(&value)}}
+		err = patch.ApplyToNode(node, yamlpatch.ApplyOptions{})
+		Expect(err).To(MatchError("yamlpatch add operation does not apply: value contains a cycle at /b/1"))
+	})
+
+	It("returns an error for a document that cannot be decoded", func() {
+		_, err := yamlpatch.ParseDocument([]byte(`{a: [`))
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Node", func() {
+	Describe("Clone", func() {
+		It("returns a copy that shares nothing with the original", func() {
+			node, err := yamlpatch.ParseDocument([]byte(`{a: {b: [1, {c: 2}]}}`))
+			Expect(err).NotTo(HaveOccurred())
+
+			clone := node.Clone()
+
+			patch, err := yamlpatch.DecodePatch([]byte(`[{op: add, path: /a/b/1/d, value: 3}, {op: add, path: /a/b/-, value: 4}]`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(patch.ApplyToNode(node, yamlpatch.ApplyOptions{})).To(Succeed())
+
+			original, err := yamlpatch.MarshalNode(clone, yamlpatch.ApplyOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(original).To(MatchYAML(`{a: {b: [1, {c: 2}]}}`))
+
+			Expect(clone.Equal(node)).To(BeFalse())
+		})
+
+		It("copies the changes made to the original before it was cloned", func() {
+			node, err := yamlpatch.ParseDocument([]byte(`{a: [1]}`))
+			Expect(err).NotTo(HaveOccurred())
+
+			patch, err := yamlpatch.DecodePatch([]byte(`[{op: add, path: /a/-, value: 2}]`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(patch.ApplyToNode(node, yamlpatch.ApplyOptions{})).To(Succeed())
+
+			actual, err := yamlpatch.MarshalNode(node.Clone(), yamlpatch.ApplyOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(actual).To(MatchYAML(`{a: [1, 2]}`))
+		})
+	})
+})
@@ -0,0 +1,190 @@
+package yamlpatch
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// scalarEdit returns the scalar to emit in place of a scalar of an emitted
+// document, given the scalar, its pointer and its text in the document, or
+// false to leave it as it is
+type scalarEdit func(n *yamlv3.Node, path, text string) (*yamlv3.Node, bool)
+
+// indentationIndicator matches the header of a block scalar that gives its
+// indentation explicitly, which is relative to where the scalar is emitted
+var indentationIndicator = regexp.MustCompile(`[|>][-+]?[1-9]`)
+
+// editScalars returns the emitted document with the text of its scalars, other
+// than keys, replaced as the edit returns, leaving the rest of the document as
+// go-yaml emitted it. A replacement is emitted by yaml.v3, which never wraps,
+// and the lines after the first of one that takes more than one line are
+// indented two spaces deeper than its parent, as go-yaml indents them.
+func editScalars(bs []byte, edit scalarEdit) []byte {
+	var doc yamlv3.Node
+	if yamlv3.Unmarshal(bs, &doc) != nil {
+		return bs
+	}
+
+	var nodes []emittedNode
+	collectNodes(&doc, "", 0, &nodes)
+
+	lines := bytes.SplitAfter(bs, []byte("\n"))
+	starts := make([]int, len(lines)+1)
+	for i, line := range lines {
+		starts[i+1] = starts[i] + len(line)
+	}
+
+	var out bytes.Buffer
+	last := 0
+	edited := false
+
+	for i, node := range nodes {
+		n := node.node
+		if n.Kind != yamlv3.ScalarNode || node.key || n.Line < 1 || n.Line > len(lines) {
+			continue
+		}
+
+		line := lines[n.Line-1]
+		start := starts[n.Line-1] + runeOffset(line, n.Column-1)
+
+		end := len(bytes.TrimRight(bs, "\n"))
+		for _, next := range nodes[i+1:] {
+			if next.node.Line > n.Line {
+				end = starts[next.node.Line-1] - 1
+				break
+			}
+		}
+
+		if start < last || start > end {
+			continue
+		}
+
+		replacement, ok := edit(n, node.path, string(bs[start:end]))
+		if !ok {
+			continue
+		}
+
+		text, ok := emitScalar(replacement, node.indent)
+		if !ok {
+			continue
+		}
+
+		out.Write(bs[last:start])
+		out.WriteString(text)
+		last = end
+		edited = true
+	}
+
+	if !edited {
+		return bs
+	}
+
+	out.Write(bs[last:])
+
+	return out.Bytes()
+}
+
+// emittedNode is a node of an emitted document with its pointer
+type emittedNode struct {
+	node *yamlv3.Node
+	path string
+
+	// key is whether the node is a key of a map, which has no pointer
+	key bool
+
+	// indent is the indentation of the lines of the node after its first
+	indent int
+}
+
+// collectNodes appends the nodes within n to nodes, in the order they appear
+// in the document
+func collectNodes(n *yamlv3.Node, path string, indent int, nodes *[]emittedNode) {
+	*nodes = append(*nodes, emittedNode{node: n, path: path, indent: indent})
+
+	switch n.Kind {
+	case yamlv3.DocumentNode:
+		for _, child := range n.Content {
+			collectNodes(child, path, 2, nodes)
+		}
+	case yamlv3.SequenceNode:
+		for i, child := range n.Content {
+			collectNodes(child, path+"/"+strconv.Itoa(i), child.Column-1, nodes)
+		}
+	case yamlv3.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key := n.Content[i]
+			*nodes = append(*nodes, emittedNode{node: key, key: true})
+			collectNodes(n.Content[i+1], path+"/"+encodePatchKey(key.Value), key.Column+1, nodes)
+		}
+	}
+}
+
+// runeOffset returns the offset in bytes of the given number of runes into
+// the line
+func runeOffset(line []byte, runes int) int {
+	offset := 0
+	for i := 0; i < runes && offset < len(line); i++ {
+		_, size := utf8.DecodeRune(line[offset:])
+		offset += size
+	}
+
+	return offset
+}
+
+// emitScalar returns the text of the scalar as yaml.v3 emits it, with any
+// lines after the first indented by the given number of spaces
+func emitScalar(n *yamlv3.Node, indent int) (string, bool) {
+	bs, err := yamlv3.Marshal(n)
+	if err != nil {
+		return "", false
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(bs), "\n"), "\n")
+	if len(lines) == 1 {
+		return lines[0], true
+	}
+
+	if indentationIndicator.MatchString(lines[0]) {
+		return "", false
+	}
+
+	common := -1
+	for _, line := range lines[1:] {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		n := len(line) - len(strings.TrimLeft(line, " "))
+		if common < 0 || n < common {
+			common = n
+		}
+	}
+
+	prefix := strings.Repeat(" ", indent)
+	for i, line := range lines[1:] {
+		if strings.TrimSpace(line) == "" {
+			lines[i+1] = ""
+			continue
+		}
+
+		lines[i+1] = prefix + line[common:]
+	}
+
+	return strings.Join(lines, "\n"), true
+}
+
+// unwrapScalar is the edit that puts each scalar go-yaml wrapped onto
+// continuation lines back on a single line. Literal and folded scalars are
+// left as they are, as are scalars with line breaks of their own.
+func unwrapScalar(n *yamlv3.Node, path, text string) (*yamlv3.Node, bool) {
+	if !strings.Contains(text, "\n") || n.Style&(yamlv3.LiteralStyle|yamlv3.FoldedStyle) != 0 || strings.Contains(n.Value, "\n") {
+		return nil, false
+	}
+
+	return &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: n.Tag, Value: n.Value, Style: n.Style}, true
+}
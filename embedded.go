@@ -0,0 +1,88 @@
+package yamlpatch
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	formatYAML = "yaml"
+	formatJSON = "json"
+)
+
+// tryEmbedded parses the string at the operation's path as a YAML or JSON
+// document, applies the operation's nested operations to it, and stores the
+// re-serialized document back into the string.
+//
+// The format is taken from the operation, or detected from the string when not
+// given: strings that begin with '{' or '[' are JSON, anything else is YAML.
+// Formatting of the embedded document is not preserved. YAML is re-emitted in
+// the same style as the rest of the output. JSON is re-emitted indented by two
+// spaces when the original spanned multiple lines, and compactly otherwise. A
+// trailing newline is kept when the original had one.
+func tryEmbedded(doc Container, op *Operation) error {
+	con, key, err := findContainer(doc, &op.Path)
+	if err != nil {
+		return pathError(err, "yamlpatch embedded operation does not apply: doc is missing path: %s", op.Path)
+	}
+
+	val, err := con.Get(key)
+	if val == nil || err != nil {
+		return fmt.Errorf("yamlpatch embedded operation does not apply: doc is missing key: %s", op.Path)
+	}
+
+	s, ok := val.plain().(string)
+	if !ok {
+		return fmt.Errorf("yamlpatch embedded operation does not apply: value is not a string: %s", op.Path)
+	}
+
+	format := op.Format
+	if format == "" {
+		format = detectFormat(s)
+	}
+
+	iface, err := defaultCodec.unmarshal([]byte(s))
+	if err != nil {
+		return fmt.Errorf("yamlpatch embedded operation does not apply: failed unmarshaling %s at %s: %s", format, op.Path, err)
+	}
+
+	root, err := op.Operations.apply(&iface, ApplyOptions{})
+	if err != nil {
+		return fmt.Errorf("yamlpatch embedded operation at %s failed: %s", op.Path, err)
+	}
+
+	var bs []byte
+	switch format {
+	case formatYAML:
+		bs, err = ApplyOptions{}.marshal(root, findExplicitTags([]byte(s), defaultCodec))
+	case formatJSON:
+		if strings.Contains(strings.TrimSpace(s), "\n") {
+			bs, err = json.MarshalIndent(JSONValue(root.plain()), "", "  ")
+		} else {
+			bs, err = json.Marshal(JSONValue(root.plain()))
+		}
+
+		if err == nil && strings.HasSuffix(s, "\n") {
+			bs = append(bs, '\n')
+		}
+	default:
+		return fmt.Errorf("yamlpatch embedded operation does not apply: unknown format: %s", format)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	var v interface{} = string(bs)
+	return con.Set(key, NewNode(&v))
+}
+
+func detectFormat(s string) string {
+	trimmed := strings.TrimSpace(s)
+	if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		return formatJSON
+	}
+
+	return formatYAML
+}
@@ -0,0 +1,44 @@
+package yamlpatch
+
+import "fmt"
+
+// DefaultEmbeddedPatchKey is the top-level key under which a document carries
+// its own patch when no other key is given
+const DefaultEmbeddedPatchKey = "__patches__"
+
+// ApplyEmbeddedPatch applies the patch a document carries under the given
+// top-level key, or DefaultEmbeddedPatchKey when the key is empty. The key is
+// removed from the document first, so the operations are applied, in order,
+// to the rest of the document and cannot refer to the patch itself. A
+// document without the key, or that is not a map, is returned unpatched, as
+// Normalize would return it.
+func ApplyEmbeddedPatch(doc []byte, key string, opts ApplyOptions) ([]byte, error) {
+	if key == "" {
+		key = DefaultEmbeddedPatchKey
+	}
+
+	iface, err := opts.codec().unmarshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed unmarshaling doc: %s\n\n%s", string(doc), err)
+	}
+
+	var patch Patch
+
+	if m, ok := iface.(map[interface{}]interface{}); ok {
+		if embedded, ok := m[key]; ok {
+			delete(m, key)
+
+			bs, err := opts.codec().marshal(embedded)
+			if err != nil {
+				return nil, err
+			}
+
+			patch, err = DecodePatch(bs)
+			if err != nil {
+				return nil, fmt.Errorf("failed decoding patch embedded under %s: %s", key, err)
+			}
+		}
+	}
+
+	return patch.applyDecoded(doc, &iface, newApplyContext(opts))
+}
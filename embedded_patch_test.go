@@ -0,0 +1,46 @@
+package yamlpatch_test
+
+import (
+	yamlpatch "github.com/krishicks/yaml-patch"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ApplyEmbeddedPatch", func() {
+	It("applies the patch the document carries and removes it", func() {
+		actual, err := yamlpatch.ApplyEmbeddedPatch([]byte(`---
+__patches__:
+- op: replace
+  path: /replicas
+  value: 3
+- op: copy
+  from: /replicas
+  path: /maxReplicas
+replicas: 1
+`), "", yamlpatch.ApplyOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(actual)).To(Equal("maxReplicas: 3\nreplicas: 3\n"))
+	})
+
+	It("does not let the operations refer to the patch", func() {
+		_, err := yamlpatch.ApplyEmbeddedPatch([]byte(`---
+ops:
+- op: remove
+  path: /ops
+a: 1
+`), "ops", yamlpatch.ApplyOptions{})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("returns a document without the key unpatched", func() {
+		actual, err := yamlpatch.ApplyEmbeddedPatch([]byte("b: 2\na: 1\n"), "", yamlpatch.ApplyOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(actual)).To(Equal("a: 1\nb: 2\n"))
+	})
+
+	It("fails when the key does not hold a patch", func() {
+		_, err := yamlpatch.ApplyEmbeddedPatch([]byte("__patches__: {op: add}\na: 1\n"), "", yamlpatch.ApplyOptions{})
+		Expect(err).To(MatchError(HavePrefix("failed decoding patch embedded under __patches__: ")))
+	})
+})
@@ -0,0 +1,132 @@
+package yamlpatch
+
+import (
+	"reflect"
+	"sort"
+	"unicode"
+)
+
+// emitOrder returns the keys of the map in the order go-yaml emits them
+func emitOrder(m nodeMap) []interface{} {
+	keys := make([]interface{}, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return emitLess(keys[i], keys[j])
+	})
+
+	return keys
+}
+
+// emitLess reports whether go-yaml emits the map key a before b: numbers and
+// bools by their value, before strings, and strings by their text, with runs
+// of digits compared as numbers and other characters before letters
+func emitLess(a, b interface{}) bool {
+	av, bv := reflect.ValueOf(a), reflect.ValueOf(b)
+	ak, bk := emitKind(av), emitKind(bv)
+
+	af, aok := emitFloat(av)
+	bf, bok := emitFloat(bv)
+	if aok && bok {
+		if af != bf {
+			return af < bf
+		}
+		if ak != bk {
+			return ak < bk
+		}
+		return emitNumLess(av, bv)
+	}
+
+	if ak != reflect.String || bk != reflect.String {
+		return ak < bk
+	}
+
+	ar, br := []rune(av.String()), []rune(bv.String())
+	for i := 0; i < len(ar) && i < len(br); i++ {
+		if ar[i] == br[i] {
+			continue
+		}
+
+		al, bl := unicode.IsLetter(ar[i]), unicode.IsLetter(br[i])
+		if al && bl {
+			return ar[i] < br[i]
+		}
+		if al || bl {
+			return bl
+		}
+
+		var ai, bi int
+		var an, bn int64
+		if ar[i] == '0' || br[i] == '0' {
+			for j := i - 1; j >= 0 && unicode.IsDigit(ar[j]); j-- {
+				if ar[j] != '0' {
+					an, bn = 1, 1
+					break
+				}
+			}
+		}
+		for ai = i; ai < len(ar) && unicode.IsDigit(ar[ai]); ai++ {
+			an = an*10 + int64(ar[ai]-'0')
+		}
+		for bi = i; bi < len(br) && unicode.IsDigit(br[bi]); bi++ {
+			bn = bn*10 + int64(br[bi]-'0')
+		}
+
+		if an != bn {
+			return an < bn
+		}
+		if ai != bi {
+			return ai < bi
+		}
+		return ar[i] < br[i]
+	}
+
+	return len(ar) < len(br)
+}
+
+// emitKind returns the kind of a map key as go-yaml sees it, for which a nil
+// key is an interface
+func emitKind(v reflect.Value) reflect.Kind {
+	if !v.IsValid() {
+		return reflect.Interface
+	}
+
+	return v.Kind()
+}
+
+// emitFloat returns the value of a map key that is a number or a bool as a
+// float, and whether it is one
+func emitFloat(v reflect.Value) (float64, bool) {
+	switch emitKind(v) {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), true
+	case reflect.Bool:
+		if v.Bool() {
+			return 1, true
+		}
+		return 0, true
+	}
+
+	return 0, false
+}
+
+// emitNumLess reports whether the number or bool a is less than b, which is of
+// the same kind
+func emitNumLess(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() < b.Float()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return a.Uint() < b.Uint()
+	}
+
+	return !a.Bool() && b.Bool()
+}
@@ -0,0 +1,52 @@
+package yamlpatch
+
+import "fmt"
+
+// Keys of a value given as per-environment overrides
+const (
+	environmentDefault   = "default"
+	environmentOverrides = "overrides"
+)
+
+// selectEnvironment returns the value for the environment of an operation
+// whose value is given as per-environment overrides by its value_by_env, a
+// map of an overrides map, keyed by environment, and optionally a default:
+// the environment's override, or the default when it has none. It is an error
+// for the environment to have no override when there is no default, and for
+// the operation to also have a value.
+func selectEnvironment(op *Operation, env string) (*Node, error) {
+	if !op.Value.Empty() || op.ValueFromFile != "" || op.ValueRaw != "" {
+		return nil, fmt.Errorf("value_by_env is mutually exclusive with value, value_from_file and value_raw")
+	}
+
+	m, ok := op.ValueByEnv.plain().(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("value_by_env is not a map of overrides and optionally a default")
+	}
+
+	overrides, ok := m[environmentOverrides].(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("value_by_env has no map of overrides")
+	}
+
+	for k := range m {
+		if k != environmentDefault && k != environmentOverrides {
+			return nil, fmt.Errorf("value_by_env has an unknown key: %v", k)
+		}
+	}
+
+	v, ok := overrides[env]
+	if !ok || env == "" {
+		v, ok = m[environmentDefault]
+	}
+
+	switch {
+	case ok:
+	case env == "":
+		return nil, fmt.Errorf("value_by_env has no default and no environment is set")
+	default:
+		return nil, fmt.Errorf("value_by_env has no default and no override for environment %s", env)
+	}
+
+	return NewNode(&v), nil
+}
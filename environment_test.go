@@ -0,0 +1,61 @@
+package yamlpatch_test
+
+import (
+	yamlpatch "github.com/krishicks/yaml-patch"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Environment", func() {
+	apply := func(ops, env string) (string, error) {
+		patch, err := yamlpatch.DecodePatch([]byte(ops))
+		Expect(err).NotTo(HaveOccurred())
+
+		actual, err := patch.ApplyWithOptions([]byte("replicas: 1\n"), yamlpatch.ApplyOptions{Environment: env})
+		return string(actual), err
+	}
+
+	DescribeTable("selects the value of per-environment overrides",
+		func(ops, env, expected string) {
+			actual, err := apply(ops, env)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(actual).To(Equal(expected))
+		},
+		Entry("for the environment's override",
+			`[{op: replace, path: /replicas, value_by_env: {default: 2, overrides: {prod: 5, dev: 1}}}]`, "prod", "replicas: 5\n"),
+		Entry("using the default for an environment without an override",
+			`[{op: replace, path: /replicas, value_by_env: {default: 2, overrides: {prod: 5}}}]`, "staging", "replicas: 2\n"),
+		Entry("using the default when no environment is set",
+			`[{op: replace, path: /replicas, value_by_env: {default: 2, overrides: {prod: 5}}}]`, "", "replicas: 2\n"),
+		Entry("for an override that is a map",
+			`[{op: replace, path: /replicas, value_by_env: {default: 2, overrides: {prod: {min: 3, max: 5}}}}]`, "prod", "replicas:\n  max: 5\n  min: 3\n"),
+		Entry("for the value of a test operation",
+			`[{op: test, path: /replicas, value_by_env: {default: 2, overrides: {dev: 1}}}]`, "dev", "replicas: 1\n"),
+		Entry("leaving a value of the same shape as it is",
+			`[{op: replace, path: /replicas, value: {default: 2, overrides: {prod: 5}}}]`, "prod", "replicas:\n  default: 2\n  overrides:\n    prod: 5\n"),
+	)
+
+	DescribeTable("fails when there is no default and no matching override",
+		func(env, message string) {
+			_, err := apply(`[{op: replace, path: /replicas, value_by_env: {overrides: {prod: 5}}}]`, env)
+			Expect(err).To(MatchError(message))
+		},
+		Entry("for another environment", "dev", "yamlpatch replace operation does not apply: value_by_env has no default and no override for environment dev"),
+		Entry("when no environment is set", "", "yamlpatch replace operation does not apply: value_by_env has no default and no environment is set"),
+	)
+
+	DescribeTable("fails for a value_by_env that is not overrides",
+		func(ops, message string) {
+			_, err := apply(ops, "prod")
+			Expect(err).To(MatchError(message))
+		},
+		Entry("with other keys",
+			`[{op: replace, path: /replicas, value_by_env: {default: 2, overrides: {prod: 5}, other: 1}}]`, "yamlpatch replace operation does not apply: value_by_env has an unknown key: other"),
+		Entry("without overrides",
+			`[{op: replace, path: /replicas, value_by_env: {default: 2}}]`, "yamlpatch replace operation does not apply: value_by_env has no map of overrides"),
+		Entry("with a value",
+			`[{op: replace, path: /replicas, value: 1, value_by_env: {overrides: {prod: 5}}}]`, "yamlpatch replace operation does not apply: value_by_env is mutually exclusive with value, value_from_file and value_raw"),
+	)
+})
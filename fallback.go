@@ -0,0 +1,43 @@
+package yamlpatch
+
+// fallbackPath returns the first of the operation's path and its fallback
+// paths that is found in the document, or the path when none are. The search
+// stops at a path that cannot be traversed because of a value of the wrong
+// kind, so that the operation fails with the type mismatch rather than being
+// performed elsewhere.
+func (o *Operation) fallbackPath(c Container, token string) OpPath {
+	candidates := append([]OpPath{o.Path}, o.Fallback...)
+
+	for _, path := range candidates {
+		found, mismatch := o.pathFound(c, path, token)
+		if found || mismatch {
+			return path
+		}
+	}
+
+	return o.Path
+}
+
+// pathFound returns whether the path is found in the document, and whether
+// the search for it ended at a type mismatch. For operations that create the
+// value at their path, which are add, copy, move and checksum, the path is
+// found when its parent is; for all others the value at the path must exist.
+func (o *Operation) pathFound(c Container, path OpPath, token string) (bool, bool) {
+	if path.ContainsExtendedSyntax() {
+		return len(newPathFinder(c, token).Find(string(path))) > 0, false
+	}
+
+	con, key, err := findContainer(c, &path)
+	if err != nil {
+		_, mismatch := err.(*typeMismatchError)
+		return false, mismatch
+	}
+
+	switch o.Op {
+	case opAdd, opCopy, opMove, opChecksum:
+		return true, false
+	}
+
+	val, err := con.Get(key)
+	return val != nil && err == nil, false
+}
@@ -0,0 +1,46 @@
+package yamlpatch
+
+import (
+	"fmt"
+	"math"
+)
+
+// checkFinite returns an error when the operation's value is, or contains, a
+// float that is NaN or infinite, naming the path within the document at which
+// the value would be placed
+func checkFinite(op *Operation) error {
+	if op.Value.Empty() {
+		return nil
+	}
+
+	if path, f, ok := findNonFinite(op.Value.plain(), string(op.Path)); ok {
+		return fmt.Errorf("yamlpatch %s operation does not apply: value is not a finite number: %v at %s", op.Op, f, path)
+	}
+
+	return nil
+}
+
+// findNonFinite returns the path and value of the first float within v that
+// is NaN or infinite
+func findNonFinite(v interface{}, path string) (string, float64, bool) {
+	switch it := v.(type) {
+	case float64:
+		if math.IsNaN(it) || math.IsInf(it, 0) {
+			return path, it, true
+		}
+	case map[interface{}]interface{}:
+		for _, k := range sortedKeys(it) {
+			if p, f, ok := findNonFinite(it[k], path+"/"+encodePatchKey(fmt.Sprint(k))); ok {
+				return p, f, true
+			}
+		}
+	case []interface{}:
+		for i, v := range it {
+			if p, f, ok := findNonFinite(v, fmt.Sprintf("%s/%d", path, i)); ok {
+				return p, f, true
+			}
+		}
+	}
+
+	return "", 0, false
+}
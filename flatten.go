@@ -0,0 +1,176 @@
+package yamlpatch
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// flattenSeparator joins the keys of nested maps, and the indices of
+// sequences, into the keys of a flattened map
+const flattenSeparator = "."
+
+// tryFlatten replaces the map at the operation's path with a single-level
+// map whose keys are the dot-joined keys and indices of each value nested
+// within it, as in a.b.0: value. Empty maps and sequences are kept as values.
+func tryFlatten(doc Container, op *Operation) error {
+	return replaceMap(doc, op, func(m map[interface{}]interface{}) (interface{}, error) {
+		flat := map[interface{}]interface{}{}
+		err := flattenInto(flat, "", m)
+		return flat, err
+	})
+}
+
+// tryUnflatten replaces the single-level map at the operation's path with the
+// nested maps its dot-joined keys describe. A map whose keys are the indices
+// 0 to n-1 becomes a sequence.
+func tryUnflatten(doc Container, op *Operation) error {
+	return replaceMap(doc, op, unflatten)
+}
+
+// replaceMap replaces the map at the operation's path with the result of
+// applying fn to it
+func replaceMap(doc Container, op *Operation, fn func(map[interface{}]interface{}) (interface{}, error)) error {
+	con, key, err := findContainer(doc, &op.Path)
+	if err != nil {
+		return pathError(err, "yamlpatch %s operation does not apply: doc is missing path: %s", op.Op, op.Path)
+	}
+
+	val, err := con.Get(key)
+	if val == nil || err != nil {
+		return fmt.Errorf("yamlpatch %s operation does not apply: doc is missing key: %s", op.Op, op.Path)
+	}
+
+	m, ok := val.plain().(map[interface{}]interface{})
+	if !ok {
+		return fmt.Errorf("yamlpatch %s operation does not apply: value is not a map: %s", op.Op, pathOrRoot(string(op.Path)))
+	}
+
+	v, err := fn(m)
+	if err != nil {
+		return fmt.Errorf("yamlpatch %s operation does not apply: %s at %s", op.Op, err, pathOrRoot(string(op.Path)))
+	}
+
+	return con.Set(key, NewNode(&v))
+}
+
+// flattenInto adds the leaves of the value to the flat map, with keys
+// prefixed by the given prefix
+func flattenInto(flat map[interface{}]interface{}, prefix string, v interface{}) error {
+	join := func(k string) string {
+		if prefix == "" {
+			return k
+		}
+		return prefix + flattenSeparator + k
+	}
+
+	switch it := v.(type) {
+	case map[interface{}]interface{}:
+		if len(it) > 0 || prefix == "" {
+			for _, k := range sortedKeys(it) {
+				err := flattenInto(flat, join(fmt.Sprint(k)), it[k])
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	case []interface{}:
+		if len(it) > 0 {
+			for i, elem := range it {
+				err := flattenInto(flat, join(strconv.Itoa(i)), elem)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+
+	if _, ok := flat[prefix]; ok {
+		return fmt.Errorf("more than one value flattens to key %s", prefix)
+	}
+
+	flat[prefix] = v
+	return nil
+}
+
+// unflatten returns the nested value the flat map's dot-joined keys describe
+func unflatten(flat map[interface{}]interface{}) (interface{}, error) {
+	root := map[interface{}]interface{}{}
+
+	// leaves holds the flat keys set so far, beneath which no other key can
+	// be set
+	leaves := map[string]bool{}
+
+	for _, k := range sortedKeys(flat) {
+		key := fmt.Sprint(k)
+		if leaves[key] {
+			return nil, fmt.Errorf("key %s is given more than once", key)
+		}
+
+		parts := strings.Split(key, flattenSeparator)
+
+		m := root
+		for i, part := range parts[:len(parts)-1] {
+			if prefix := strings.Join(parts[:i+1], flattenSeparator); leaves[prefix] {
+				return nil, fmt.Errorf("key %s conflicts with key %s", key, prefix)
+			}
+
+			next, ok := m[part].(map[interface{}]interface{})
+			if !ok {
+				next = map[interface{}]interface{}{}
+				m[part] = next
+			}
+			m = next
+		}
+
+		m[parts[len(parts)-1]] = flat[k]
+		leaves[key] = true
+	}
+
+	return sequencesFromIndices(root), nil
+}
+
+// sequencesFromIndices converts every map within the value whose keys are
+// exactly the indices 0 to n-1 into a sequence
+func sequencesFromIndices(v interface{}) interface{} {
+	m, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return v
+	}
+
+	indices := make([]int, 0, len(m))
+	for k := range m {
+		i, err := parseIndex(fmt.Sprint(k))
+		if err != nil {
+			indices = nil
+			break
+		}
+		indices = append(indices, i)
+	}
+	sort.Ints(indices)
+
+	isSequence := len(indices) > 0
+	for i, index := range indices {
+		if index != i {
+			isSequence = false
+		}
+	}
+
+	if isSequence {
+		s := make([]interface{}, len(m))
+		for k, v := range m {
+			i, _ := parseIndex(fmt.Sprint(k))
+			s[i] = sequencesFromIndices(v)
+		}
+		return s
+	}
+
+	for k, v := range m {
+		m[k] = sequencesFromIndices(v)
+	}
+
+	return m
+}
@@ -0,0 +1,59 @@
+package yamlpatch_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("flatten and unflatten", func() {
+	nested := `config:
+  server:
+    hosts:
+    - a.example.com
+    - b.example.com
+    port: 8080
+  tags: []
+name: web
+`
+
+	flat := `config:
+  server.hosts.0: a.example.com
+  server.hosts.1: b.example.com
+  server.port: 8080
+  tags: []
+name: web
+`
+
+	It("flattens the map at the path into dotted keys", func() {
+		actual, err := applyPatch(`[{op: flatten, path: /config}]`, nested)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actual).To(Equal(flat))
+	})
+
+	It("unflattens dotted keys into nested maps and sequences", func() {
+		actual, err := applyPatch(`[{op: unflatten, path: /config}]`, flat)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actual).To(Equal(nested))
+	})
+
+	It("flattens the whole document with the empty path", func() {
+		actual, err := applyPatch(`[{op: flatten, path: ""}]`, "a:\n  b: 1\nc: 2\n")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actual).To(Equal("a.b: 1\nc: 2\n"))
+	})
+
+	It("fails to flatten when two values flatten to the same key", func() {
+		_, err := applyPatch(`[{op: flatten, path: /config}]`, "config:\n  a.b: 1\n  a:\n    b: 2\n")
+		Expect(err).To(MatchError("yamlpatch flatten operation does not apply: more than one value flattens to key a.b at /config"))
+	})
+
+	It("fails to unflatten a key beneath another key's value", func() {
+		_, err := applyPatch(`[{op: unflatten, path: /config}]`, "config:\n  a: 1\n  a.b: 2\n")
+		Expect(err).To(MatchError("yamlpatch unflatten operation does not apply: key a.b conflicts with key a at /config"))
+	})
+
+	It("fails for a value that is not a map", func() {
+		_, err := applyPatch(`[{op: flatten, path: /name}]`, "name: web\n")
+		Expect(err).To(MatchError("yamlpatch flatten operation does not apply: value is not a map: /name"))
+	})
+})
@@ -0,0 +1,111 @@
+package yamlpatch
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// itemRegex matches the placeholders for the index or key of the current item
+// of a for_each operation, which are interchangeable
+var itemRegex = regexp.MustCompile(`\{\{(index|key)\}\}`)
+
+// expandForEach returns a copy of the operation for each element of the array,
+// or entry of the map, at its for_each path, with the element's index or the
+// entry's key substituted for {{index}} and {{key}} in its paths and value.
+// Map entries are expanded in the order of their keys. In a value, a string
+// that consists of only a placeholder is replaced by the index as an integer,
+// or the key as it is in the document; in a path, the key is escaped.
+func (o *Operation) expandForEach(c Container, token string) ([]Operation, error) {
+	con, key, err := findContainer(c, &o.ForEach)
+	if err != nil {
+		return nil, pathError(err, "yamlpatch %s operation does not apply: doc is missing for_each path: %s", o.Op, o.ForEach)
+	}
+
+	val, err := con.Get(key)
+	if val == nil || err != nil {
+		return nil, fmt.Errorf("yamlpatch %s operation does not apply: doc is missing for_each path: %s", o.Op, o.ForEach)
+	}
+
+	var items []interface{}
+	switch it := val.Container().(type) {
+	case *nodeSlice:
+		for i := range *it {
+			items = append(items, i)
+		}
+	case *nodeMap:
+		for k := range *it {
+			items = append(items, k)
+		}
+		sort.Slice(items, func(i, j int) bool {
+			return keyLess(items[i], items[j])
+		})
+	default:
+		return nil, fmt.Errorf("yamlpatch %s operation does not apply: for_each path is not a map or array: %s", o.Op, o.ForEach)
+	}
+
+	var ops []Operation
+	for _, item := range items {
+		op := *o
+		op.ForEach = ""
+		op.Path = substituteItemPath(o.Path, item)
+		op.From = substituteItemPath(o.From, item)
+
+		op.Paths = make([]OpPath, len(o.Paths))
+		for i, path := range o.Paths {
+			op.Paths[i] = substituteItemPath(path, item)
+		}
+
+		op.Fallback = make([]OpPath, len(o.Fallback))
+		for i, path := range o.Fallback {
+			op.Fallback[i] = substituteItemPath(path, item)
+		}
+
+		if !o.Value.Empty() {
+			v := substituteItem(o.Value.plain(), item)
+			op.Value = NewNode(&v)
+		}
+
+		if !o.ValueByEnv.Empty() {
+			v := substituteItem(o.ValueByEnv.plain(), item)
+			op.ValueByEnv = NewNode(&v)
+		}
+
+		expanded, err := op.expand(c, token)
+		if err != nil {
+			return nil, err
+		}
+
+		ops = append(ops, expanded...)
+	}
+
+	return ops, nil
+}
+
+func substituteItemPath(path OpPath, item interface{}) OpPath {
+	return OpPath(itemRegex.ReplaceAllLiteralString(string(path), encodePatchKey(fmt.Sprint(item))))
+}
+
+func substituteItem(v interface{}, item interface{}) interface{} {
+	switch it := v.(type) {
+	case string:
+		if loc := itemRegex.FindStringIndex(it); loc != nil && loc[0] == 0 && loc[1] == len(it) {
+			return item
+		}
+		return itemRegex.ReplaceAllLiteralString(it, fmt.Sprint(item))
+	case map[interface{}]interface{}:
+		m := make(map[interface{}]interface{}, len(it))
+		for k, v := range it {
+			m[k] = substituteItem(v, item)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(it))
+		for i, v := range it {
+			s[i] = substituteItem(v, item)
+		}
+		return s
+	}
+
+	return v
+}
@@ -0,0 +1,83 @@
+package yamlpatch_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("for_each", func() {
+	It("repeats the operation for each element of an array", func() {
+		actual, err := applyPatch(`---
+- op: add
+  for_each: /spec/containers
+  path: /spec/containers/{{index}}/imagePullPolicy
+  value: Always
+`, `---
+spec:
+  containers:
+  - name: web
+  - name: sidecar
+`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actual).To(Equal(`spec:
+  containers:
+  - imagePullPolicy: Always
+    name: web
+  - imagePullPolicy: Always
+    name: sidecar
+`))
+	})
+
+	It("substitutes the key of each map entry into the path and value", func() {
+		actual, err := applyPatch(`---
+- op: add
+  for_each: /services
+  path: /services/{{key}}/labels
+  value:
+    service: "{{key}}"
+    description: service {{key}}
+`, `---
+services:
+  api: {}
+  a/b: {}
+`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actual).To(Equal(`services:
+  a/b:
+    labels:
+      description: service a/b
+      service: a/b
+  api:
+    labels:
+      description: service api
+      service: api
+`))
+	})
+
+	It("keeps the index an integer in a value that consists of only the placeholder", func() {
+		actual, err := applyPatch(`---
+- op: add
+  for_each: /items
+  path: /items/{{index}}/position
+  value: "{{index}}"
+`, "items: [{}, {}]\n")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actual).To(Equal("items:\n- position: 0\n- position: 1\n"))
+	})
+
+	It("leaves empty strings in the value as they are", func() {
+		actual, err := applyPatch(`---
+- op: add
+  for_each: /items
+  path: /items/{{index}}/labels
+  value: {name: "", id: "{{index}}"}
+`, "items: [{}]\n")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actual).To(Equal("items:\n- labels:\n    id: 0\n    name: \"\"\n"))
+	})
+
+	It("fails when the for_each path is not a collection", func() {
+		_, err := applyPatch(`[{op: add, for_each: /a, path: "/b/{{index}}", value: 1}]`, "a: 1\n")
+		Expect(err).To(MatchError("yamlpatch add operation does not apply: for_each path is not a map or array: /a"))
+	})
+})
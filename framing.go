@@ -0,0 +1,122 @@
+package yamlpatch
+
+import (
+	"bytes"
+	"strings"
+)
+
+// byteOrderMark is the UTF-8 encoding of U+FEFF, which some editors write at
+// the start of a file
+const byteOrderMark = "\ufeff"
+
+// document is the text of a single document within a YAML stream, along with
+// the parts of its framing that are lost when it is decoded: the directives
+// that precede it and whether it ends with an explicit end marker
+type document struct {
+	text        []byte
+	directives  []string
+	explicitEnd bool
+}
+
+// frame returns the marshaled body of the document surrounded by its
+// directives and end marker. A start marker is included when the document has
+// directives, which require one, or when explicitStart is set.
+func (d document) frame(body []byte, explicitStart bool) []byte {
+	var buf bytes.Buffer
+
+	for _, directive := range d.directives {
+		buf.WriteString(directive)
+		buf.WriteString("\n")
+	}
+
+	if explicitStart || len(d.directives) > 0 {
+		buf.WriteString("---\n")
+	}
+
+	buf.Write(body)
+
+	if d.explicitEnd {
+		buf.WriteString("...\n")
+	}
+
+	return buf.Bytes()
+}
+
+// framing returns the document with the framing the options keep: without
+// its directives when they are stripped
+func (o ApplyOptions) framing(d document) document {
+	if o.StripDirectives {
+		d.directives = nil
+	}
+
+	return d
+}
+
+// splitDocuments splits a YAML stream into its documents by scanning for
+// directives, start markers (---) and end markers (...) at the start of a
+// line. The lines of a block scalar are indented beneath its key, so markers
+// within one are never at the start of a line, and a line that begins with a
+// marker ends a block scalar, as go-yaml reads it. Each document's text keeps
+// its directives and start marker so that it can be decoded on its own. Text
+// that contains only comments or blank lines is not a document. A UTF-8 byte
+// order mark, which editors on Windows write at the start of a file, is
+// dropped from the start of any line, so that the directives and markers of
+// documents concatenated from such files are found.
+func splitDocuments(stream []byte) []document {
+	var docs []document
+
+	var cur document
+	var lines []string
+	started, hasContent := false, false
+
+	finish := func() {
+		if started || hasContent || len(cur.directives) > 0 {
+			cur.text = []byte(strings.Join(lines, ""))
+			docs = append(docs, cur)
+		}
+
+		cur = document{}
+		lines = nil
+		started, hasContent = false, false
+	}
+
+	for _, line := range strings.SplitAfter(string(stream), "\n") {
+		line = strings.TrimPrefix(line, byteOrderMark)
+		trimmed := strings.TrimRight(line, "\r\n")
+
+		switch {
+		case strings.HasPrefix(trimmed, "%"):
+			if started || hasContent {
+				finish()
+			}
+			cur.directives = append(cur.directives, trimmed)
+		case isMarker(trimmed, "---"):
+			if started || hasContent {
+				finish()
+			}
+			started = true
+		case isMarker(trimmed, "..."):
+			cur.explicitEnd = true
+			finish()
+			continue
+		case trimmed != "" && !strings.HasPrefix(strings.TrimSpace(trimmed), "#"):
+			hasContent = true
+		}
+
+		lines = append(lines, line)
+	}
+
+	finish()
+
+	return docs
+}
+
+// isMarker returns whether the line begins with the given document marker
+func isMarker(line, marker string) bool {
+	if !strings.HasPrefix(line, marker) {
+		return false
+	}
+
+	rest := line[len(marker):]
+	return rest == "" || rest[0] == ' ' || rest[0] == '\t'
+}
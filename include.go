@@ -0,0 +1,168 @@
+package yamlpatch
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// FileDecoder decodes ops files from disk, resolving include directives.
+//
+// An ops file is either a sequence of operations or a mapping with an
+// `include` list of other ops files and an `operations` sequence:
+//
+//	include: [base.yml, prod.yml]
+//	operations:
+//	- op: add
+//	  path: /env
+//	  value: prod
+//
+// Included files are resolved relative to the including file and their
+// operations come first, in the order listed, followed by the file's own.
+type FileDecoder struct {
+	// Strict rejects operations and ops files with unrecognized fields, as
+	// DecodePatchStrict does
+	Strict bool
+
+	// ReadFile, when set, reads the contents of every file instead of
+	// ioutil.ReadFile, as to decompress them
+	ReadFile func(path string) ([]byte, error)
+
+	// Preprocess, when set, is applied to the contents of every file before it
+	// is decoded
+	Preprocess func([]byte) []byte
+}
+
+// DecodePatchFile decodes the ops file at the given path, resolving include
+// directives
+func DecodePatchFile(path string) (Patch, error) {
+	return FileDecoder{}.Decode(path)
+}
+
+// Decode decodes the ops file at the given path, resolving include directives
+func (d FileDecoder) Decode(path string) (Patch, error) {
+	return d.decode(path, nil)
+}
+
+type patchFile struct {
+	Include    []string    `yaml:"include"`
+	Operations interface{} `yaml:"operations"`
+}
+
+func (d FileDecoder) decode(path string, stack []string) (Patch, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range stack {
+		if p == abs {
+			return nil, fmt.Errorf("include cycle: %s", strings.Join(append(stack, abs), " -> "))
+		}
+	}
+	stack = append(stack, abs)
+
+	readFile := d.ReadFile
+	if readFile == nil {
+		readFile = ioutil.ReadFile
+	}
+
+	bs, err := readFile(abs)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.Preprocess != nil {
+		bs = d.Preprocess(bs)
+	}
+
+	// Ops files are decoded as YAML 1.1, as DecodePatch decodes them
+	iface, err := defaultCodec.unmarshal(bs)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+
+	m, ok := iface.(map[interface{}]interface{})
+	if !ok {
+		return d.decodeOperations(path, abs, bs)
+	}
+
+	_, hasInclude := m["include"]
+	_, hasOperations := m["operations"]
+	if !hasInclude && !hasOperations {
+		return nil, fmt.Errorf("%s: expected a sequence of operations or a mapping with include or operations", path)
+	}
+
+	var pf patchFile
+	if d.Strict {
+		err = yaml.UnmarshalStrict(bs, &pf)
+	} else {
+		err = yaml.Unmarshal(bs, &pf)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+
+	var patch Patch
+	for _, inc := range pf.Include {
+		if !filepath.IsAbs(inc) {
+			inc = filepath.Join(filepath.Dir(abs), inc)
+		}
+
+		included, err := d.decode(inc, stack)
+		if err != nil {
+			return nil, err
+		}
+
+		patch = append(patch, included...)
+	}
+
+	if pf.Operations != nil {
+		ops, err := defaultCodec.marshal(pf.Operations)
+		if err != nil {
+			return nil, err
+		}
+
+		own, err := d.decodeOperations(path, abs, ops)
+		if err != nil {
+			return nil, err
+		}
+
+		patch = append(patch, own...)
+	}
+
+	return patch, nil
+}
+
+// decodeOperations decodes a sequence of operations from the file at path,
+// resolving any relative value_from_file against the file's directory
+func (d FileDecoder) decodeOperations(path, abs string, bs []byte) (Patch, error) {
+	decode := DecodePatch
+	if d.Strict {
+		decode = DecodePatchStrict
+	}
+
+	patch, err := decode(bs)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+
+	resolveValueFiles(patch, filepath.Dir(abs))
+
+	return patch, nil
+}
+
+// resolveValueFiles resolves any relative value_from_file of the operations,
+// and of the operations nested within them, against the given directory
+func resolveValueFiles(patch Patch, dir string) {
+	for i := range patch {
+		if f := patch[i].ValueFromFile; f != "" && !filepath.IsAbs(f) {
+			patch[i].ValueFromFile = filepath.Join(dir, f)
+		}
+
+		resolveValueFiles(patch[i].Operations, dir)
+	}
+}
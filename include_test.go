@@ -0,0 +1,223 @@
+package yamlpatch_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	yamlpatch "github.com/krishicks/yaml-patch"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DecodePatchFile", func() {
+	var dir string
+
+	write := func(name, contents string) string {
+		path := filepath.Join(dir, name)
+		Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(path, []byte(contents), 0644)).To(Succeed())
+		return path
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "yamlpatch-include")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("decodes a file containing a sequence of operations", func() {
+		path := write("ops.yml", `---
+- op: add
+  path: /a
+  value: 1
+`)
+
+		patch, err := yamlpatch.DecodePatchFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(patch).To(HaveLen(1))
+	})
+
+	It("prepends the operations of included files, resolved relative to the including file", func() {
+		write("overlays/base.yml", `---
+- op: add
+  path: /env
+  value: base
+- op: add
+  path: /replicas
+  value: 1
+`)
+		write("overlays/prod.yml", `---
+include: [base.yml]
+operations:
+- op: replace
+  path: /env
+  value: prod
+`)
+		path := write("ops.yml", `---
+include: [overlays/prod.yml]
+operations:
+- op: replace
+  path: /replicas
+  value: 3
+`)
+
+		patch, err := yamlpatch.DecodePatchFile(path)
+		Expect(err).NotTo(HaveOccurred())
+
+		actual, err := patch.Apply([]byte(`{}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actual).To(MatchYAML(`{env: prod, replicas: 3}`))
+	})
+
+	It("allows a file with only includes", func() {
+		write("base.yml", `[{op: add, path: /a, value: 1}]`)
+		path := write("ops.yml", `include: [base.yml, base.yml]`)
+
+		patch, err := yamlpatch.DecodePatchFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(patch).To(HaveLen(2))
+	})
+
+	It("reads every file, including those it includes, with ReadFile", func() {
+		write("base.yml", `[{op: add, path: /a, value: 1}]`)
+		path := write("ops.yml", `include: [base.yml]`)
+
+		var read []string
+		decoder := yamlpatch.FileDecoder{
+			ReadFile: func(path string) ([]byte, error) {
+				read = append(read, filepath.Base(path))
+				return ioutil.ReadFile(path)
+			},
+		}
+
+		patch, err := decoder.Decode(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(patch).To(HaveLen(1))
+		Expect(read).To(Equal([]string{"ops.yml", "base.yml"}))
+	})
+
+	It("returns an error for an include cycle", func() {
+		write("a.yml", `include: [b.yml]`)
+		write("b.yml", `include: [a.yml]`)
+
+		_, err := yamlpatch.DecodePatchFile(filepath.Join(dir, "a.yml"))
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("include cycle"))
+	})
+
+	It("returns an error for a missing included file", func() {
+		path := write("ops.yml", `include: [missing.yml]`)
+
+		_, err := yamlpatch.DecodePatchFile(path)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("missing.yml"))
+	})
+
+	Context("with value_from_file", func() {
+		It("sets the contents of the file, resolved relative to the ops file, as a string", func() {
+			write("overlays/cert.pem", "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n")
+			path := write("overlays/ops.yml", `[{op: add, path: /tls/cert, value_from_file: cert.pem}]`)
+
+			patch, err := yamlpatch.DecodePatchFile(path)
+			Expect(err).NotTo(HaveOccurred())
+
+			actual, err := patch.Apply([]byte(`{tls: {}}`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(actual).To(MatchYAML(`---
+tls:
+  cert: |
+    -----BEGIN CERTIFICATE-----
+    MIIB
+    -----END CERTIFICATE-----
+`))
+		})
+
+		It("resolves the files of nested operations relative to the ops file", func() {
+			write("overlays/name.txt", "web")
+			path := write("overlays/ops.yml", `[{op: block, operations: [{op: add, path: /name, value_from_file: name.txt}]}]`)
+
+			patch, err := yamlpatch.DecodePatchFile(path)
+			Expect(err).NotTo(HaveOccurred())
+
+			actual, err := patch.Apply([]byte(`{}`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(actual).To(MatchYAML(`{name: web}`))
+		})
+
+		It("parses the contents of the file when a format is given", func() {
+			write("block.yml", "replicas: 3\nports: [80, 443]\n")
+			path := write("ops.yml", `[{op: replace, path: /spec, value_from_file: block.yml, format: yaml}]`)
+
+			patch, err := yamlpatch.DecodePatchFile(path)
+			Expect(err).NotTo(HaveOccurred())
+
+			actual, err := patch.Apply([]byte(`{spec: {}}`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(actual).To(MatchYAML(`{spec: {replicas: 3, ports: [80, 443]}}`))
+		})
+
+		It("returns an error naming the file when it is missing", func() {
+			path := write("ops.yml", `[{op: add, path: /a, value_from_file: missing.txt}]`)
+
+			patch, err := yamlpatch.DecodePatchFile(path)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = patch.Apply([]byte(`{}`))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("missing.txt"))
+		})
+
+		It("returns an error when a value is also given", func() {
+			write("a.txt", "a")
+			path := write("ops.yml", `[{op: add, path: /a, value: b, value_from_file: a.txt}]`)
+
+			patch, err := yamlpatch.DecodePatchFile(path)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = patch.Apply([]byte(`{}`))
+			Expect(err).To(MatchError(ContainSubstring("mutually exclusive")))
+		})
+	})
+
+	Context("with a FileDecoder", func() {
+		It("applies Preprocess to every file", func() {
+			write("base.yml", `[{op: add, path: /a, value: ENV}]`)
+			path := write("ops.yml", `include: [base.yml]`)
+
+			decoder := yamlpatch.FileDecoder{
+				Preprocess: func(bs []byte) []byte {
+					return bytes.Replace(bs, []byte("ENV"), []byte("prod"), -1)
+				},
+			}
+			patch, err := decoder.Decode(path)
+			Expect(err).NotTo(HaveOccurred())
+
+			actual, err := patch.Apply([]byte(`{}`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(actual).To(MatchYAML(`{a: prod}`))
+		})
+
+		It("rejects unknown fields in included files when Strict", func() {
+			write("base.yml", `[{op: add, path: /a, value: 1, bogus: true}]`)
+			path := write("ops.yml", `include: [base.yml]`)
+
+			_, err := yamlpatch.FileDecoder{Strict: true}.Decode(path)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("unknown field 'bogus'"))
+		})
+
+		It("rejects unknown top-level keys when Strict", func() {
+			path := write("ops.yml", `{include: [], operatons: []}`)
+
+			_, err := yamlpatch.FileDecoder{Strict: true}.Decode(path)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
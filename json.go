@@ -0,0 +1,24 @@
+package yamlpatch
+
+import "fmt"
+
+// JSONValue converts v, as decoded from YAML, into a value that can be
+// marshaled by encoding/json by converting map keys into strings
+func JSONValue(v interface{}) interface{} {
+	switch it := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(it))
+		for k, v := range it {
+			m[fmt.Sprint(k)] = JSONValue(v)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(it))
+		for i := range it {
+			s[i] = JSONValue(it[i])
+		}
+		return s
+	}
+
+	return v
+}
@@ -0,0 +1,106 @@
+package yamlpatch
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// jsonPatchOps are the ops RFC 6902 defines
+var jsonPatchOps = map[Op]bool{
+	opAdd:     true,
+	opRemove:  true,
+	opReplace: true,
+	opMove:    true,
+	opCopy:    true,
+	opTest:    true,
+}
+
+// jsonPatchFields are the fields of an Operation that are either part of RFC
+// 6902 or are accounted for when a patch is marshaled as JSON Patch
+var jsonPatchFields = map[string]bool{
+	"op":       true,
+	"path":     true,
+	"from":     true,
+	"value":    true,
+	"priority": true,
+	"disabled": true,
+}
+
+// jsonPatchOperation is an operation of an RFC 6902 JSON Patch
+type jsonPatchOperation struct {
+	Op    Op           `json:"op"`
+	Path  OpPath       `json:"path"`
+	From  OpPath       `json:"from,omitempty"`
+	Value *interface{} `json:"value,omitempty"`
+}
+
+// MarshalJSONPatch returns the patch as an RFC 6902 JSON Patch array. The
+// operations are emitted in the order they are applied in, so priorities
+// are accounted for, and disabled operations are left out. An operation
+// with a list of paths is emitted once for each path. It is an error for the
+// patch to use any other extension of this package, such as an op RFC 6902
+// does not define, a key=value or recursive search path, or a field such as
+// value_contains, since JSON Patch cannot represent it.
+func (p Patch) MarshalJSONPatch() ([]byte, error) {
+	ops := []jsonPatchOperation{}
+
+	for _, i := range p.order() {
+		op := p[i]
+		if op.Disabled {
+			continue
+		}
+
+		err := op.checkJSONPatch()
+		if err != nil {
+			return nil, fmt.Errorf("operation %d cannot be represented as JSON Patch: %s", i, err)
+		}
+
+		paths := op.Paths
+		if len(paths) == 0 {
+			paths = []OpPath{op.Path}
+		}
+
+		for _, path := range paths {
+			jsonOp := jsonPatchOperation{Op: op.Op, Path: path, From: op.From}
+
+			if op.Op == opAdd || op.Op == opReplace || op.Op == opTest {
+				v := JSONValue(op.Value.plain())
+				jsonOp.Value = &v
+			}
+
+			ops = append(ops, jsonOp)
+		}
+	}
+
+	return json.Marshal(ops)
+}
+
+// checkJSONPatch returns an error describing the first part of the operation
+// that RFC 6902 does not define
+func (o Operation) checkJSONPatch() error {
+	if !jsonPatchOps[o.Op] {
+		return fmt.Errorf("op %s is not defined by RFC 6902", o.Op)
+	}
+
+	v := reflect.ValueOf(o)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		if name == "-" || jsonPatchFields[name] || v.Field(i).IsZero() {
+			continue
+		}
+
+		return fmt.Errorf("field %s is not defined by RFC 6902", name)
+	}
+
+	paths := append([]OpPath{o.Path, o.From}, o.Paths...)
+	for _, path := range paths {
+		if path.ContainsExtendedSyntax() {
+			return fmt.Errorf("path %s uses extended syntax", path)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package yamlpatch_test
+
+import (
+	yamlpatch "github.com/krishicks/yaml-patch"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MarshalJSONPatch", func() {
+	marshal := func(ops string) (string, error) {
+		patch, err := yamlpatch.DecodePatch([]byte(ops))
+		Expect(err).NotTo(HaveOccurred())
+
+		bs, err := patch.MarshalJSONPatch()
+		return string(bs), err
+	}
+
+	It("emits the operations as RFC 6902 JSON Patch", func() {
+		actual, err := marshal(`---
+- op: add
+  path: /spec/template
+  value: {replicas: 3, ports: [80]}
+- op: replace
+  path: /image
+  value: null
+- op: remove
+  path: /old
+- op: move
+  from: /a
+  path: /b
+- op: copy
+  from: /b
+  path: /c
+- op: test
+  path: /name
+  value: web
+`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actual).To(MatchJSON(`[
+			{"op": "add", "path": "/spec/template", "value": {"replicas": 3, "ports": [80]}},
+			{"op": "replace", "path": "/image", "value": null},
+			{"op": "remove", "path": "/old"},
+			{"op": "move", "from": "/a", "path": "/b"},
+			{"op": "copy", "from": "/b", "path": "/c"},
+			{"op": "test", "path": "/name", "value": "web"}
+		]`))
+	})
+
+	It("emits operations in the order they are applied, leaving out disabled ones", func() {
+		actual, err := marshal(`---
+- {op: add, path: /a, value: 1, priority: 1}
+- {op: add, path: /b, value: 2, disabled: true}
+- {op: add, path: /c, value: 3}
+`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actual).To(MatchJSON(`[{"op": "add", "path": "/c", "value": 3}, {"op": "add", "path": "/a", "value": 1}]`))
+	})
+
+	It("emits an operation with a list of paths once for each path", func() {
+		actual, err := marshal(`[{op: remove, path: [/a, /b]}]`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actual).To(MatchJSON(`[{"op": "remove", "path": "/a"}, {"op": "remove", "path": "/b"}]`))
+	})
+
+	DescribeTable("fails for extensions that JSON Patch cannot represent",
+		func(ops, message string) {
+			_, err := marshal(ops)
+			Expect(err).To(MatchError(message))
+		},
+		Entry("an op", `[{op: rename, from: /a, path: /b}]`, "operation 0 cannot be represented as JSON Patch: op rename is not defined by RFC 6902"),
+		Entry("a field", `[{op: test, path: /a, value_contains: {b: 1}}]`, "operation 0 cannot be represented as JSON Patch: field value_contains is not defined by RFC 6902"),
+		Entry("a key=value path", `[{op: remove, path: /items/name=web}]`, "operation 0 cannot be represented as JSON Patch: path /items/name=web uses extended syntax"),
+	)
+})
@@ -0,0 +1,65 @@
+package yamlpatch_test
+
+import (
+	"fmt"
+	"strings"
+
+	yamlpatch "github.com/krishicks/yaml-patch"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type recordingLogger struct {
+	lines []string
+}
+
+func (l *recordingLogger) Debugf(format string, args ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintf(format, args...))
+}
+
+var _ = Describe("Logger", func() {
+	It("receives a trace of each operation", func() {
+		patch, err := yamlpatch.DecodePatch([]byte(`---
+- op: replace
+  path: /items/name=b/value
+  value: 2
+- op: add
+  path: /spec
+  value: {}
+  if_kind: Deployment
+- op: remove
+  path: /missing
+`))
+		Expect(err).NotTo(HaveOccurred())
+
+		logger := &recordingLogger{}
+		_, err = patch.ApplyWithOptions([]byte(`items: [{name: a, value: 0}, {name: b, value: 1}]`), yamlpatch.ApplyOptions{Logger: logger})
+		Expect(err).To(HaveOccurred())
+
+		Expect(logger.lines).To(Equal([]string{
+			"operation 0: replace /items/name=b/value",
+			"  expanded to /items/1/value",
+			"operation 1: skipping add /spec: ConditionFalse",
+			"operation 2: remove /missing",
+			"operation 2: failed: " + err.Error(),
+		}))
+	})
+
+	It("traces the paths of a scope in the order of their subtrees in the document", func() {
+		patch, err := yamlpatch.DecodePatch([]byte(`[{op: replace, path: /ready, value: true, scope: /items/kind=Pod}]`))
+		Expect(err).NotTo(HaveOccurred())
+
+		doc := "items:\n" + strings.Repeat("- {kind: Pod, ready: false}\n", 11)
+
+		logger := &recordingLogger{}
+		_, err = patch.ApplyWithOptions([]byte(doc), yamlpatch.ApplyOptions{Logger: logger})
+		Expect(err).NotTo(HaveOccurred())
+
+		expected := []string{"operation 0: replace /ready"}
+		for i := 0; i < 11; i++ {
+			expected = append(expected, fmt.Sprintf("  expanded to /items/%d/ready", i))
+		}
+		Expect(logger.lines).To(Equal(expected))
+	})
+})
@@ -0,0 +1,78 @@
+package yamlpatch
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// MergePatch applies the patch to the document and returns a JSON merge patch
+// (RFC 7386) that transforms the original document into the patched one,
+// such as can be given to kubectl patch --type merge.
+//
+// Merge patches cannot express every change: arrays are replaced as a whole
+// rather than patched element by element, and setting a value to null is
+// indistinguishable from removing it. Kubernetes strategic merge patches,
+// which merge some lists by key, are not produced.
+func (p Patch) MergePatch(doc []byte) ([]byte, error) {
+	patched, err := p.Apply(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return CreateMergePatch(doc, patched)
+}
+
+// CreateMergePatch returns a JSON merge patch (RFC 7386) that transforms the
+// original YAML document into the modified one
+func CreateMergePatch(original, modified []byte) ([]byte, error) {
+	orig, err := defaultCodec.unmarshal(original)
+	if err != nil {
+		return nil, fmt.Errorf("failed unmarshaling original doc: %s", err)
+	}
+
+	mod, err := defaultCodec.unmarshal(modified)
+	if err != nil {
+		return nil, fmt.Errorf("failed unmarshaling modified doc: %s", err)
+	}
+
+	patch, changed := mergePatch(orig, mod)
+	if !changed {
+		patch = map[interface{}]interface{}{}
+	}
+
+	return json.Marshal(JSONValue(patch))
+}
+
+// mergePatch returns the merge patch that transforms orig into mod, and
+// whether there is any difference between them
+func mergePatch(orig, mod interface{}) (interface{}, bool) {
+	origMap, origOK := orig.(map[interface{}]interface{})
+	modMap, modOK := mod.(map[interface{}]interface{})
+
+	if !origOK || !modOK {
+		return mod, !reflect.DeepEqual(orig, mod)
+	}
+
+	patch := map[interface{}]interface{}{}
+
+	for k := range origMap {
+		if _, ok := modMap[k]; !ok {
+			patch[k] = nil
+		}
+	}
+
+	for k, v := range modMap {
+		ov, ok := origMap[k]
+		if !ok {
+			patch[k] = v
+			continue
+		}
+
+		if sub, changed := mergePatch(ov, v); changed {
+			patch[k] = sub
+		}
+	}
+
+	return patch, len(patch) > 0
+}
@@ -0,0 +1,68 @@
+package yamlpatch_test
+
+import (
+	yamlpatch "github.com/krishicks/yaml-patch"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MergePatch", func() {
+	DescribeTable(
+		"produces a merge patch",
+		func(doc, ops, expectedJSON string) {
+			patch, err := yamlpatch.DecodePatch([]byte(ops))
+			Expect(err).NotTo(HaveOccurred())
+
+			actual, err := patch.MergePatch([]byte(doc))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(actual).To(MatchJSON(expectedJSON))
+		},
+		Entry("for added, replaced and removed object members",
+			`---
+metadata:
+  name: app
+  labels:
+    env: dev
+    team: web
+spec:
+  replicas: 1
+`,
+			`---
+- op: replace
+  path: /spec/replicas
+  value: 3
+- op: remove
+  path: /metadata/labels/team
+- op: add
+  path: /metadata/annotations
+  value:
+    owner: me
+`,
+			`{"metadata":{"labels":{"team":null},"annotations":{"owner":"me"}},"spec":{"replicas":3}}`,
+		),
+		Entry("that replaces arrays as a whole",
+			`---
+args: [a, b]
+`,
+			`---
+- op: add
+  path: /args/-
+  value: c
+`,
+			`{"args":["a","b","c"]}`,
+		),
+		Entry("that is empty when nothing changed",
+			`---
+foo: bar
+`,
+			`---
+- op: test
+  path: /foo
+  value: bar
+`,
+			`{}`,
+		),
+	)
+})
@@ -0,0 +1,78 @@
+package yamlpatch
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ApplyError is an error that occurred while applying a patch, along with
+// where it occurred. Document is the index of the document within a stream
+// and Operation the index of the operation within the patch; either is -1
+// when it does not apply.
+type ApplyError struct {
+	Document  int
+	Operation int
+	Err       error
+}
+
+func (e *ApplyError) Error() string {
+	var prefix string
+
+	if e.Document >= 0 {
+		prefix += fmt.Sprintf("document %d: ", e.Document)
+	}
+
+	if e.Operation >= 0 {
+		prefix += fmt.Sprintf("operation %d: ", e.Operation)
+	}
+
+	return prefix + e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *ApplyError) Unwrap() error {
+	return e.Err
+}
+
+// MultiError is the set of errors returned by a best-effort apply and by the
+// apply of a stream. Each of its errors is an *ApplyError. errors.Is and
+// errors.As match against each of them.
+type MultiError struct {
+	errs []error
+}
+
+// Errors returns the errors, in the order they occurred
+func (e *MultiError) Errors() []error {
+	return e.errs
+}
+
+func (e *MultiError) Error() string {
+	if len(e.errs) == 1 {
+		return e.errs[0].Error()
+	}
+
+	msgs := make([]string, len(e.errs))
+	for i, err := range e.errs {
+		msgs[i] = err.Error()
+	}
+
+	return fmt.Sprintf("%d errors occurred:\n\t%s", len(e.errs), strings.Join(msgs, "\n\t"))
+}
+
+// Unwrap returns the errors, for errors.Is and errors.As
+func (e *MultiError) Unwrap() []error {
+	return e.errs
+}
+
+func (e *MultiError) append(err error) {
+	e.errs = append(e.errs, err)
+}
+
+// errorOrNil returns the MultiError, or nil when it holds no errors
+func (e *MultiError) errorOrNil() error {
+	if len(e.errs) == 0 {
+		return nil
+	}
+
+	return e
+}
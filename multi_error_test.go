@@ -0,0 +1,114 @@
+package yamlpatch_test
+
+import (
+	"errors"
+
+	yamlpatch "github.com/krishicks/yaml-patch"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var errMultiSentinel = errors.New("sentinel failure")
+
+var _ = Describe("MultiError", func() {
+	BeforeEach(func() {
+		yamlpatch.RegisterOperation("fail_sentinel", func(doc *yamlpatch.Node, op yamlpatch.Operation) error {
+			return errMultiSentinel
+		})
+	})
+
+	AfterEach(func() {
+		yamlpatch.UnregisterOperation("fail_sentinel")
+	})
+
+	Describe("a best-effort apply", func() {
+		It("performs every operation that succeeds and returns the rest as errors", func() {
+			patch, err := yamlpatch.DecodePatch([]byte(`---
+- op: remove
+  path: /missing
+- op: add
+  path: /b
+  value: 2
+- op: fail_sentinel
+  path: /a
+`))
+			Expect(err).NotTo(HaveOccurred())
+
+			actual, err := patch.ApplyWithOptions([]byte("a: 1\n"), yamlpatch.ApplyOptions{BestEffort: true})
+			Expect(string(actual)).To(Equal("a: 1\nb: 2\n"))
+
+			var multi *yamlpatch.MultiError
+			Expect(errors.As(err, &multi)).To(BeTrue())
+			Expect(multi.Errors()).To(HaveLen(2))
+
+			first := multi.Errors()[0].(*yamlpatch.ApplyError)
+			Expect(first.Document).To(Equal(-1))
+			Expect(first.Operation).To(Equal(0))
+
+			second := multi.Errors()[1].(*yamlpatch.ApplyError)
+			Expect(second.Operation).To(Equal(2))
+			Expect(second.Error()).To(Equal("operation 2: sentinel failure"))
+
+			Expect(errors.Is(err, errMultiSentinel)).To(BeTrue())
+		})
+
+		It("returns no error when every operation succeeds", func() {
+			patch := yamlpatch.Patch{{Op: "add", Path: "/b", Value: nodeOf(2)}}
+
+			actual, err := patch.ApplyWithOptions([]byte("a: 1\n"), yamlpatch.ApplyOptions{BestEffort: true})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(actual)).To(Equal("a: 1\nb: 2\n"))
+		})
+	})
+
+	Describe("a stream apply", func() {
+		var stream = []byte(`---
+a: 1
+---
+b: 1
+---
+a: 2
+---
+b: 2
+`)
+
+		It("returns an error for every failing document with its indices", func() {
+			patch := yamlpatch.Patch{
+				{Op: "add", Path: "/c", Value: nodeOf(3)},
+				{Op: "replace", Path: "/a", Value: nodeOf(0)},
+			}
+
+			_, err := patch.ApplyStream(stream)
+
+			var multi *yamlpatch.MultiError
+			Expect(errors.As(err, &multi)).To(BeTrue())
+			Expect(multi.Errors()).To(HaveLen(2))
+
+			var applyErr *yamlpatch.ApplyError
+			Expect(errors.As(multi.Errors()[0], &applyErr)).To(BeTrue())
+			Expect(applyErr.Document).To(Equal(1))
+			Expect(applyErr.Operation).To(Equal(1))
+			Expect(err.Error()).To(ContainSubstring("\tdocument 3: operation 1: "))
+		})
+
+		It("returns the patched stream with failing operations skipped when best-effort", func() {
+			patch := yamlpatch.Patch{
+				{Op: "replace", Path: "/a", Value: nodeOf(0)},
+			}
+
+			actual, err := patch.ApplyStreamWithOptions(stream, yamlpatch.ApplyOptions{BestEffort: true})
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*yamlpatch.MultiError).Errors()).To(HaveLen(2))
+			Expect(string(actual)).To(Equal(`---
+a: 0
+---
+b: 1
+---
+a: 0
+---
+b: 2
+`))
+		})
+	})
+})
@@ -0,0 +1,120 @@
+package yamlpatch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// NDJSONOptions controls how a Patch is applied to line-delimited JSON
+type NDJSONOptions struct {
+	ApplyOptions
+
+	// SkipMalformed skips lines that are not valid JSON rather than stopping
+	// at the first of them. The skipped lines are reported once every line
+	// has been read.
+	SkipMalformed bool
+}
+
+// ErrMalformedJSON is the error wrapped by the error for a line that is not
+// valid JSON
+var ErrMalformedJSON = errors.New("invalid JSON")
+
+// LineError is an error that occurred on a line of line-delimited JSON. Line
+// is numbered from 1.
+type LineError struct {
+	Line int
+	Err  error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// ApplyNDJSON applies the patch to each line of r, which holds one JSON
+// document per line, writing each patched document to w as a line of JSON as
+// soon as it is patched. Blank lines are ignored. The keys of the output
+// objects are sorted.
+//
+// Application stops at the first line that fails, returning a *LineError.
+// When SkipMalformed is set, lines that are not valid JSON are left out of the
+// output instead, and when the options are best-effort, failing operations are
+// skipped; the errors of each are returned together as a *MultiError of
+// *LineErrors once every line has been read.
+func (p Patch) ApplyNDJSON(r io.Reader, w io.Writer, opts NDJSONOptions) error {
+	br := bufio.NewReader(r)
+	errs := &MultiError{}
+
+	for n := 1; ; n++ {
+		line, err := br.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 {
+			bs, lineErr := p.applyJSONLine(trimmed, opts)
+			if lineErr != nil {
+				malformed := errors.Is(lineErr, ErrMalformedJSON)
+				_, partial := lineErr.(*MultiError)
+
+				switch {
+				case malformed && opts.SkipMalformed:
+					errs.append(&LineError{Line: n, Err: lineErr})
+				case partial:
+					errs.append(&LineError{Line: n, Err: lineErr})
+				default:
+					return &LineError{Line: n, Err: lineErr}
+				}
+			}
+
+			if bs != nil {
+				_, werr := w.Write(append(bs, '\n'))
+				if werr != nil {
+					return werr
+				}
+			}
+		}
+
+		if err == io.EOF {
+			break
+		}
+	}
+
+	return errs.errorOrNil()
+}
+
+// applyJSONLine applies the patch to the JSON document on a line, returning
+// the patched document as JSON. The document is returned along with the
+// *MultiError of a best-effort apply.
+func (p Patch) applyJSONLine(line []byte, opts NDJSONOptions) ([]byte, error) {
+	var check interface{}
+	err := json.Unmarshal(line, &check)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrMalformedJSON, err)
+	}
+
+	iface, err := opts.codec().unmarshal(line)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrMalformedJSON, err)
+	}
+
+	root, applyErr := p.apply(&iface, opts.ApplyOptions)
+	if applyErr != nil && (!opts.BestEffort || root == nil) {
+		return nil, applyErr
+	}
+
+	bs, err := json.Marshal(JSONValue(root.plain()))
+	if err != nil {
+		return nil, err
+	}
+
+	return bs, applyErr
+}
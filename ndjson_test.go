@@ -0,0 +1,59 @@
+package yamlpatch_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+
+	yamlpatch "github.com/krishicks/yaml-patch"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ApplyNDJSON", func() {
+	var patch = yamlpatch.Patch{
+		{Op: "add", Path: "/tags/-", Value: nodeOf("seen")},
+	}
+
+	It("patches each line, ignoring blank lines", func() {
+		var out bytes.Buffer
+		err := patch.ApplyNDJSON(strings.NewReader(`{"id": 1, "tags": []}
+
+{"id": 2, "tags": ["x"]}`), &out, yamlpatch.NDJSONOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out.String()).To(Equal(`{"id":1,"tags":["seen"]}
+{"id":2,"tags":["x","seen"]}
+`))
+	})
+
+	It("stops at the first line that fails, naming it", func() {
+		var out bytes.Buffer
+		err := patch.ApplyNDJSON(strings.NewReader(`{"tags": []}
+{"id": 2}
+{"tags": []}
+`), &out, yamlpatch.NDJSONOptions{})
+
+		var lineErr *yamlpatch.LineError
+		Expect(errors.As(err, &lineErr)).To(BeTrue())
+		Expect(lineErr.Line).To(Equal(2))
+		Expect(out.String()).To(Equal("{\"tags\":[\"seen\"]}\n"))
+	})
+
+	It("skips malformed lines when SkipMalformed is set, reporting each", func() {
+		var out bytes.Buffer
+		err := patch.ApplyNDJSON(strings.NewReader(`{"tags": []}
+tags: []
+{"tags": [
+{"tags": []}
+`), &out, yamlpatch.NDJSONOptions{SkipMalformed: true})
+		Expect(out.String()).To(Equal("{\"tags\":[\"seen\"]}\n{\"tags\":[\"seen\"]}\n"))
+
+		var multi *yamlpatch.MultiError
+		Expect(errors.As(err, &multi)).To(BeTrue())
+		Expect(multi.Errors()).To(HaveLen(2))
+		Expect(multi.Errors()[0].(*yamlpatch.LineError).Line).To(Equal(2))
+		Expect(multi.Errors()[1].(*yamlpatch.LineError).Line).To(Equal(3))
+		Expect(errors.Is(err, yamlpatch.ErrMalformedJSON)).To(BeTrue())
+	})
+})
@@ -0,0 +1,74 @@
+package yamlpatch
+
+import "fmt"
+
+// placingOps are the ops that place a value at their path, which NoClobber
+// requires to be absent
+var placingOps = map[Op]bool{
+	opAdd:      true,
+	opCopy:     true,
+	opMove:     true,
+	opChecksum: true,
+}
+
+// checkNoClobber returns an error for the first operation of the patch, or
+// of a block within it, that always overwrites a value and so cannot be
+// applied with NoClobber: a replace operation. It is checked before any
+// operation is performed, so that such a patch leaves the document as it is.
+func (p Patch) checkNoClobber() error {
+	for i := range p {
+		op := &p[i]
+		if op.Disabled {
+			continue
+		}
+
+		var err error
+		switch op.Op {
+		case opReplace:
+			err = fmt.Errorf("yamlpatch replace operation cannot be applied with NoClobber: it always overwrites a value: %s", op.Path)
+		case opBlock, opWith:
+			err = op.Operations.checkNoClobber()
+		}
+
+		if err != nil {
+			return &ApplyError{Document: -1, Operation: i, Err: err}
+		}
+	}
+
+	return nil
+}
+
+// checkClobber returns an error when the operation would overwrite the value
+// of an existing key of a map at its path. Inserting into an array does not
+// overwrite a value, and neither does an add operation that skips existing
+// keys or a rename operation that does not overwrite them. Missing paths are
+// left for the operation to report.
+func (o *Operation) checkClobber(c Container) error {
+	switch {
+	case o.Op == opAdd && o.OnExisting == onExistingSkip:
+		return nil
+	case o.Op == opRename && !o.Overwrite:
+		return nil
+	case !placingOps[o.Op] && o.Op != opRename:
+		return nil
+	}
+
+	path := o.Path
+
+	con, key, err := findContainer(c, &path)
+	if err != nil {
+		return nil
+	}
+
+	return o.checkClobberAt(con, key)
+}
+
+// checkClobberAt returns an error when the key of the container, which the
+// operation places its value at, is an existing key of a map
+func (o *Operation) checkClobberAt(con Container, key string) error {
+	if m, ok := con.(*nodeMap); ok && m.has(key) {
+		return fmt.Errorf("yamlpatch %s operation does not apply: NoClobber does not allow overwriting existing key: %s", o.Op, o.Path)
+	}
+
+	return nil
+}
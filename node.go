@@ -39,9 +39,25 @@ func (n *Node) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	return nil
 }
 
+// Clone returns a deep copy of the node and everything beneath it, sharing no
+// nodes, maps or slices with it, so that changes to either leave the other
+// untouched
+func (n *Node) Clone() *Node {
+	if n == nil {
+		return nil
+	}
+
+	if n.raw == nil && n.container == nil {
+		return &Node{}
+	}
+
+	v := deepCopy(n.plain())
+	return NewNode(&v)
+}
+
 // Empty returns whether the raw value is nil
 func (n *Node) Empty() bool {
-	return *n.raw == nil
+	return n == nil || n.raw == nil || *n.raw == nil
 }
 
 // Container returns the node as a Container
@@ -50,21 +66,30 @@ func (n *Node) Container() Container {
 		return n.container
 	}
 
+	// The nodes of the children, and the values of map entries, are allocated
+	// together rather than one at a time
 	switch rt := (*n.raw).(type) {
 	case []interface{}:
 		c := make(nodeSlice, len(rt))
 		n.container = &c
 
+		nodes := make([]Node, len(rt))
 		for i := range rt {
-			c[i] = NewNode(&rt[i])
+			nodes[i].raw = &rt[i]
+			c[i] = &nodes[i]
 		}
 	case map[interface{}]interface{}:
 		c := make(nodeMap, len(rt))
 		n.container = &c
 
-		for k := range rt {
-			v := rt[k]
-			c[k] = NewNode(&v)
+		nodes := make([]Node, len(rt))
+		values := make([]interface{}, len(rt))
+		i := 0
+		for k, v := range rt {
+			values[i] = v
+			nodes[i].raw = &values[i]
+			c[k] = &nodes[i]
+			i++
 		}
 	}
 
@@ -72,12 +97,96 @@ func (n *Node) Container() Container {
 }
 
 // Equal compares the values of the raw interfaces that the YAML was
-// unmarshaled into
+// unmarshaled into, including any changes made through their Containers
 func (n *Node) Equal(other *Node) bool {
-	return reflect.DeepEqual(*n.raw, *other.raw)
+	return reflect.DeepEqual(n.plain(), other.plain())
+}
+
+// Contains returns whether the node's value contains the other's. A map
+// contains another when it has each of the other's keys with a value that
+// contains the other's value, ignoring any extra keys. A slice contains
+// another when, for each of the other's elements, it has an element that
+// contains it. Any other values must be equal.
+func (n *Node) Contains(other *Node) bool {
+	return contains(n.plain(), other.plain())
+}
+
+func contains(v, sub interface{}) bool {
+	switch st := sub.(type) {
+	case map[interface{}]interface{}:
+		m, ok := v.(map[interface{}]interface{})
+		if !ok {
+			return false
+		}
+
+		for k, sv := range st {
+			mv, ok := m[k]
+			if !ok || !contains(mv, sv) {
+				return false
+			}
+		}
+
+		return true
+	case []interface{}:
+		s, ok := v.([]interface{})
+		if !ok {
+			return false
+		}
+
+		for _, sv := range st {
+			found := false
+			for _, e := range s {
+				if contains(e, sv) {
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return reflect.DeepEqual(v, sub)
 }
 
 // Value returns the raw value of the node
 func (n *Node) Value() interface{} {
 	return *n.raw
 }
+
+// plain returns the value of the node as plain maps, slices and scalars,
+// reflecting any changes made through its Container
+func (n *Node) plain() interface{} {
+	if n == nil || n.raw == nil {
+		return nil
+	}
+
+	if n.container == nil {
+		return *n.raw
+	}
+
+	return plainContainer(n.container)
+}
+
+func plainContainer(c Container) interface{} {
+	switch it := c.(type) {
+	case *nodeMap:
+		m := make(map[interface{}]interface{}, len(*it))
+		for k, v := range *it {
+			m[k] = v.plain()
+		}
+		return m
+	case *nodeSlice:
+		s := make([]interface{}, len(*it))
+		for i, v := range *it {
+			s[i] = v.plain()
+		}
+		return s
+	}
+
+	return nil
+}
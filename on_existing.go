@@ -0,0 +1,29 @@
+package yamlpatch
+
+import "fmt"
+
+// Behaviors of an add operation whose path is an existing key of a map
+const (
+	onExistingReplace = "replace"
+	onExistingError   = "error"
+	onExistingSkip    = "skip"
+)
+
+// validateOnExisting returns an error when the operation's on_existing is
+// not one of replace, error or skip, or is given for an operation other than
+// add
+func (o *Operation) validateOnExisting() error {
+	switch o.OnExisting {
+	case "":
+		return nil
+	case onExistingReplace, onExistingError, onExistingSkip:
+	default:
+		return fmt.Errorf("yamlpatch %s operation has an invalid on_existing: %s", o.Op, o.OnExisting)
+	}
+
+	if o.Op != opAdd {
+		return fmt.Errorf("yamlpatch %s operation cannot have on_existing: only add operations can", o.Op)
+	}
+
+	return nil
+}
@@ -3,6 +3,7 @@ package yamlpatch
 import (
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -11,14 +12,43 @@ type Op string
 
 // Ops
 const (
-	opAdd     Op = "add"
-	opRemove  Op = "remove"
-	opReplace Op = "replace"
-	opMove    Op = "move"
-	opCopy    Op = "copy"
-	opTest    Op = "test"
+	opAdd           Op = "add"
+	opRemove        Op = "remove"
+	opReplace       Op = "replace"
+	opMove          Op = "move"
+	opCopy          Op = "copy"
+	opTest          Op = "test"
+	opRename        Op = "rename"
+	opEmbedded      Op = "embedded"
+	opCapture       Op = "capture"
+	opTransform     Op = "transform"
+	opBlock         Op = "block"
+	opTransformKeys Op = "transform_keys"
+	opFlatten       Op = "flatten"
+	opUnflatten     Op = "unflatten"
+	opComment       Op = "comment"
+	opDedupe        Op = "dedupe"
+	opWith          Op = "with"
+	opWrap          Op = "wrap"
+	opUnwrap        Op = "unwrap"
+	opSortKeys      Op = "sort_keys"
+	opChecksum      Op = "checksum"
 )
 
+// wholeDocumentOps are the ops that can be performed on the whole document,
+// with the empty path
+var wholeDocumentOps = map[Op]bool{
+	opReplace:       true,
+	opTest:          true,
+	opTransformKeys: true,
+	opFlatten:       true,
+	opUnflatten:     true,
+	opDedupe:        true,
+	opWrap:          true,
+	opUnwrap:        true,
+	opSortKeys:      true,
+}
+
 // OpPath is an RFC6902 'pointer'
 type OpPath string
 
@@ -40,9 +70,11 @@ func (p *OpPath) Decompose() ([]string, string, error) {
 
 // ContainsExtendedSyntax returns whether the OpPath uses the "key=value"
 // format, as in "/foo/name=bar", where /foo points at an array that contains
-// an object with a key "name" that has a value "bar"
+// an object with a key "name" that has a value "bar", or is a recursive
+// search, as in "//image[1]", which points at the second member with the key
+// "image" anywhere in the document
 func (p *OpPath) ContainsExtendedSyntax() bool {
-	return strings.Contains(string(*p), "=")
+	return strings.Contains(string(*p), "=") || strings.HasPrefix(string(*p), "//")
 }
 
 // String returns the OpPath as a string
@@ -50,6 +82,24 @@ func (p *OpPath) String() string {
 	return string(*p)
 }
 
+// UnmarshalYAML implements yaml.Unmarshaler. A list of paths is accepted and
+// left for the Operation to decode into its Paths, leaving the OpPath empty.
+func (p *OpPath) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	err := unmarshal(&s)
+	if err == nil {
+		*p = OpPath(s)
+		return nil
+	}
+
+	var list []interface{}
+	if unmarshal(&list) == nil {
+		return nil
+	}
+
+	return err
+}
+
 // Operation is an RFC6902 'Operation'
 // https://tools.ietf.org/html/rfc6902#section-4
 type Operation struct {
@@ -57,11 +107,540 @@ type Operation struct {
 	Path  OpPath `yaml:"path,omitempty"`
 	From  OpPath `yaml:"from,omitempty"`
 	Value *Node  `yaml:"value,omitempty"`
+
+	// Paths holds the paths of an operation whose path is given as a list, as
+	// in "path: [/a, /b/c]". The operation is performed at each in turn, and
+	// Path is ignored.
+	Paths []OpPath `yaml:"-"`
+
+	// ValueContains is the value a test operation checks the value at its
+	// path contains, ignoring extra keys of maps and extra elements of
+	// sequences, rather than equals
+	ValueContains *Node `yaml:"value_contains,omitempty"`
+
+	// DocumentSHA256 is the hash a test operation checks the canonical form
+	// of the value at its path has, as returned by DocumentSHA256 for the
+	// whole document when the path is empty
+	DocumentSHA256 string `yaml:"document_sha256,omitempty"`
+
+	// ValueSemver is a comparison a test operation checks the semantic
+	// version at its path satisfies: an operator, one of >=, <=, >, <, == or
+	// !=, followed by a version, as in ">= v1.2"
+	ValueSemver string `yaml:"value_semver,omitempty"`
+
+	// ValueType is the type a test operation checks the value at its path
+	// has as decoded: int, float, string, bool, null, map or slice
+	ValueType string `yaml:"value_type,omitempty"`
+
+	// Spread causes an add operation whose path ends in "/-" and whose value
+	// is a list to append each element of the list individually, rather than
+	// appending the list as a single nested element
+	Spread bool `yaml:"spread,omitempty"`
+
+	// Overwrite allows a rename operation to replace an existing key
+	Overwrite bool `yaml:"overwrite,omitempty"`
+
+	// OnExisting is what an add operation whose path is an existing key of a
+	// map does: replace its value, as RFC 6902 specifies and by default,
+	// fail with error, or leave it as it is with skip
+	OnExisting string `yaml:"on_existing,omitempty"`
+
+	// IfKind restricts the operation to documents whose top-level kind field
+	// has the given value
+	IfKind string `yaml:"if_kind,omitempty"`
+
+	// Encode names an encoding, such as base64, that the string value is
+	// encoded with before the operation is performed
+	Encode string `yaml:"encode,omitempty"`
+
+	// Decode names an encoding, such as base64, that the value in the
+	// document is decoded from before a test operation compares it
+	Decode string `yaml:"decode,omitempty"`
+
+	// Operations are the nested operations an embedded operation applies to
+	// the document held in the string at its path, that a block operation
+	// applies to the document in order, or that a with operation applies in
+	// order with paths relative to the value its path selects
+	Operations Patch `yaml:"operations,omitempty"`
+
+	// OnError is the policy of a block or with operation for when one of its
+	// operations fails: rollback restores the document to what it was at the
+	// start of the block and continues with the next operation. By default
+	// the block fails.
+	OnError string `yaml:"on_error,omitempty"`
+
+	// Format is the format, yaml or json, of the document held in the string
+	// at the path of an embedded operation, or that the contents of
+	// ValueFromFile are parsed as
+	Format string `yaml:"format,omitempty"`
+
+	// ValueFromFile names a file whose contents are used as the operation's
+	// value, as a string unless Format is given. Relative paths are resolved
+	// against the ops file when the patch is decoded with a FileDecoder, and
+	// against the working directory otherwise.
+	ValueFromFile string `yaml:"value_from_file,omitempty"`
+
+	// ValueRaw is a YAML fragment used as the operation's value and emitted
+	// in the output exactly as written, comments and styles included, rather
+	// than being marshaled. Its indentation is removed and replaced with that
+	// of the path it is placed at. Later operations see its parsed value; if
+	// one modifies it, it is marshaled like any other value.
+	ValueRaw string `yaml:"value_raw,omitempty"`
+
+	// ValueByEnv gives the operation's value as per-environment overrides,
+	// a map of an overrides map, keyed by environment, and optionally a
+	// default, as in {default: 2, overrides: {prod: 5}}. The value is the
+	// override for ApplyOptions.Environment, or the default when it has none.
+	ValueByEnv *Node `yaml:"value_by_env,omitempty"`
+
+	// Position is where a comment operation's comment is emitted: above the
+	// value's key, the default, or inline on the same line
+	Position string `yaml:"position,omitempty"`
+
+	// Priority orders operations within a patch. Operations with lower
+	// priorities are applied first; operations with equal priorities keep
+	// their relative order. The default priority is 0.
+	Priority int `yaml:"priority,omitempty"`
+
+	// As names the variable a capture operation binds the value at its path
+	// to. Later operations in the same patch can refer to the variable in
+	// their values as {{var:name}}.
+	As string `yaml:"as,omitempty"`
+
+	// Transform names the transform a transform operation applies to the
+	// string at its path, or that a copy or move operation applies to the
+	// string it places: trim, lower, upper, title or regex. For a
+	// transform_keys operation, it names the transform applied to every key
+	// of the maps at its path: camelToSnake, snakeToCamel, lower or upper.
+	Transform string `yaml:"transform,omitempty"`
+
+	// Pattern is the regular expression the regex transform replaces matches
+	// of with Replacement, which may refer to submatches as $1
+	Pattern     string `yaml:"pattern,omitempty"`
+	Replacement string `yaml:"replacement,omitempty"`
+
+	// Scope restricts the operation to the subtrees its pointer expands to,
+	// using the same key=value syntax as paths, e.g. /kind=Pod. The
+	// operation's path and from are treated as relative to each subtree.
+	Scope OpPath `yaml:"scope,omitempty"`
+
+	// Range, for a replace operation on an array, is the range of elements
+	// to replace with the elements of the value, in the form START:END, where
+	// START is included and END is not. Either may be omitted, as in :2 or
+	// 1:, to leave the range open at that end.
+	Range string `yaml:"range,omitempty"`
+
+	// Fallback are paths tried in order, after the path, when the path is not
+	// found in the document. The operation is performed at the first that is
+	// found. A path along which a value is of the wrong kind to descend into
+	// is not a missing path, and is not fallen back from.
+	Fallback []OpPath `yaml:"fallback,omitempty"`
+
+	// Disabled skips the operation when the patch is applied, leaving it in
+	// the patch, e.g. to temporarily turn it off in an ops file. In a patch,
+	// disabled is either a bool or a reference to a captured variable,
+	// {{var:name}}, which is decoded into DisabledBy.
+	Disabled bool `yaml:"-"`
+
+	// DisabledBy is the name of a captured variable that skips the operation
+	// when it is true at the time the operation is performed, e.g. to toggle
+	// a section of an ops file by a value of the document
+	DisabledBy string `yaml:"-"`
+
+	// Key, for an add operation whose path is an array of maps, upserts the
+	// value by the named key instead of adding it at the path: the element
+	// whose key has the same value as the value's is replaced, and when there
+	// is none the value is appended
+	Key string `yaml:"key,omitempty"`
+
+	// By, for a dedupe operation on an array of maps, compares the maps by
+	// the value of the named key rather than as a whole
+	By string `yaml:"by,omitempty"`
+
+	// TakeFirst allows an unwrap operation to replace an array of more than
+	// one element with its first element
+	TakeFirst bool `yaml:"take_first,omitempty"`
+
+	// Target restricts the operation to one document of a stream: first,
+	// last, or the document at an index counted from 0, empty documents
+	// included. By default an operation applies to every document. A single
+	// document is both the first and the last, at index 0.
+	Target string `yaml:"target,omitempty"`
+
+	// ForEach is the path of an array or map over which the operation is
+	// repeated, once for each element or entry, with its index or key
+	// substituted for {{index}} or {{key}} in the operation's paths and value
+	ForEach OpPath `yaml:"for_each,omitempty"`
+}
+
+type plainOperation Operation
+
+// UnmarshalYAML implements yaml.Unmarshaler, decoding a path given as a list
+// into Paths and rejecting paths that ValidatePath finds malformed. The from
+// of an operation cannot be a list, and neither can the path of a move
+// operation, whose value would be gone after the first path.
+func (o *Operation) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	err := unmarshal((*plainOperation)(o))
+	if err != nil {
+		return err
+	}
+
+	err = o.unmarshalNullValueType(unmarshal)
+	if err != nil {
+		return err
+	}
+
+	err = o.unmarshalDisabled(unmarshal)
+	if err != nil {
+		return err
+	}
+
+	var lists struct {
+		Path interface{} `yaml:"path"`
+		From interface{} `yaml:"from"`
+	}
+
+	err = unmarshal(&lists)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := lists.From.([]interface{}); ok {
+		return fmt.Errorf("yamlpatch %s operation from cannot be a list of paths", o.Op)
+	}
+
+	list, ok := lists.Path.([]interface{})
+	if !ok {
+		return o.validate()
+	}
+
+	if len(o.Fallback) > 0 {
+		return fmt.Errorf("yamlpatch %s operation with a list of paths cannot have fallback paths", o.Op)
+	}
+
+	if o.Op == opMove {
+		return fmt.Errorf("yamlpatch move operation path cannot be a list of paths")
+	}
+
+	if len(list) == 0 {
+		return fmt.Errorf("yamlpatch %s operation path is an empty list", o.Op)
+	}
+
+	o.Paths = make([]OpPath, len(list))
+	for i, v := range list {
+		path, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("yamlpatch %s operation path list contains a non-string: %v", o.Op, v)
+		}
+
+		o.Paths[i] = OpPath(path)
+	}
+
+	return o.validate()
+}
+
+// appliesTo returns whether the operation should be performed against the
+// given document
+func (o *Operation) appliesTo(doc Container) bool {
+	return o.skipReason(doc) == ""
+}
+
+// skipReason returns why the operation should be skipped for the given
+// document, or "" when it should be performed
+func (o *Operation) skipReason(doc Container) SkipReason {
+	if o.Disabled {
+		return SkipDisabled
+	}
+
+	if o.IfKind == "" {
+		return ""
+	}
+
+	m, ok := doc.(*nodeMap)
+	if !ok {
+		return SkipConditionFalse
+	}
+
+	kind, _ := m.Get("kind")
+	if kind.Empty() || kind.Value() != o.IfKind {
+		return SkipConditionFalse
+	}
+
+	return ""
 }
 
 // Perform executes the operation on the given container
 func (o *Operation) Perform(c Container) error {
-	var err error
+	err := o.perform(c, newApplyContext(ApplyOptions{}))
+	if _, ok := err.(*skippedError); ok {
+		return nil
+	}
+
+	return err
+}
+
+// performExpanded executes the operation on the given container once for
+// each path it expands to, returning why it was skipped when it expanded to
+// none, or was skipped at each path it expanded to
+func (o *Operation) performExpanded(c Container, ctx *applyContext) (SkipReason, error) {
+	if o.isCrossDocument() {
+		return "", crossDocumentError(o)
+	}
+
+	disabled, err := o.disabledByVariable(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if disabled {
+		return SkipDisabled, nil
+	}
+
+	// The value is checked before references within it are resolved, which
+	// would not terminate for a value that contains a cycle
+	if o.Value != nil {
+		err := checkValueDepth(o.Value.plain(), o.Path, ctx.opts.maxDepth())
+		if err != nil {
+			return "", fmt.Errorf("yamlpatch %s operation does not apply: %s", o.Op, err)
+		}
+	}
+
+	token := ctx.opts.AppendToken
+	err = checkAppendToken(token)
+	if err != nil {
+		return "", fmt.Errorf("yamlpatch %s operation does not apply: %s", o.Op, err)
+	}
+
+	ops, err := o.expand(c, token)
+	if err != nil {
+		return "", err
+	}
+
+	if len(ops) == 0 {
+		return SkipPathMissing, nil
+	}
+
+	var reason SkipReason
+	skipped := 0
+
+	for _, op := range ops {
+		if op.Path != o.Path {
+			ctx.debugf("  expanded to %s", op.Path)
+		}
+
+		op := op.withAppendToken(c, token)
+
+		if ctx.opts.CaseInsensitiveKeys {
+			err := op.foldKeys(c)
+			if err != nil {
+				return "", fmt.Errorf("yamlpatch %s operation does not apply: %s", op.Op, err)
+			}
+		}
+
+		err := op.perform(c, ctx)
+		if s, ok := err.(*skippedError); ok {
+			ctx.debugf("  skipped at %s: %s", op.Path, s.reason)
+			reason = s.reason
+			skipped++
+			continue
+		}
+
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if skipped < len(ops) {
+		return "", nil
+	}
+
+	return reason, nil
+}
+
+// expand returns a copy of the operation for each concrete path that its
+// for_each, paths, scope and pointer expand to within the given container. A
+// list of paths is expanded in the order given. Scopes are expanded in the
+// order of their subtrees in the document, and a scope that matches nothing,
+// like a for_each over an empty collection, expands to no operations. Pointers
+// are expanded with the given append token referring to the end of arrays, as
+// "-" does.
+func (o *Operation) expand(c Container, token string) ([]Operation, error) {
+	if o.ForEach != "" {
+		return o.expandForEach(c, token)
+	}
+
+	if len(o.Paths) > 0 {
+		var ops []Operation
+		for _, path := range o.Paths {
+			op := *o
+			op.Paths = nil
+			op.Path = path
+
+			expanded, err := op.expand(c, token)
+			if err != nil {
+				return nil, err
+			}
+
+			ops = append(ops, expanded...)
+		}
+
+		return ops, nil
+	}
+
+	if o.Scope != "" {
+		scopes := newPathFinder(c, token).Find(string(o.Scope))
+		sortByPosition(c, scopes)
+
+		var ops []Operation
+		for _, scope := range scopes {
+			scope = strings.TrimSuffix(scope, "/")
+
+			op := *o
+			op.Scope = ""
+			op.Path = OpPath(scope + string(o.Path))
+			if o.From != "" {
+				op.From = OpPath(scope + string(o.From))
+			}
+
+			op.Fallback = make([]OpPath, len(o.Fallback))
+			for i, path := range o.Fallback {
+				op.Fallback[i] = OpPath(scope + string(path))
+			}
+
+			expanded, err := op.expand(c, token)
+			if err != nil {
+				return nil, err
+			}
+
+			ops = append(ops, expanded...)
+		}
+
+		return ops, nil
+	}
+
+	if len(o.Fallback) > 0 {
+		op := *o
+		op.Fallback = nil
+		op.Path = o.fallbackPath(c, token)
+
+		return op.expand(c, token)
+	}
+
+	if !o.Path.ContainsExtendedSyntax() {
+		return []Operation{*o}, nil
+	}
+
+	paths := newPathFinder(c, token).Find(string(o.Path))
+	if paths == nil {
+		return nil, fmt.Errorf("could not expand pointer: %s", o.Path)
+	}
+
+	ops := make([]Operation, len(paths))
+	for i, path := range paths {
+		ops[i] = *o
+		ops[i].Path = OpPath(path)
+	}
+
+	return ops, nil
+}
+
+func (o *Operation) perform(c Container, ctx *applyContext) error {
+	if o.ValueFromFile != "" {
+		val, err := readValueFile(o, ctx.opts.codec())
+		if err != nil {
+			return fmt.Errorf("yamlpatch %s operation does not apply: %s", o.Op, err)
+		}
+
+		op := *o
+		op.Value = val
+		op.ValueFromFile = ""
+		o = &op
+	}
+
+	if o.ValueByEnv != nil {
+		val, err := selectEnvironment(o, ctx.opts.Environment)
+		if err != nil {
+			return fmt.Errorf("yamlpatch %s operation does not apply: %s", o.Op, err)
+		}
+
+		op := *o
+		op.Value = val
+		op.ValueByEnv = nil
+		o = &op
+	}
+
+	if o.Value != nil {
+		val, err := resolveReferences(o.Value, ctx)
+		if err != nil {
+			return fmt.Errorf("yamlpatch %s operation does not apply: %s", o.Op, err)
+		}
+
+		if val != o.Value {
+			op := *o
+			op.Value = val
+			o = &op
+		}
+	}
+
+	// The value an add or replace places in the document must not share nodes
+	// with the patch, or with the value placed at another of its paths, or
+	// later operations on one would also change the others
+	if o.Value != nil && (o.Op == opAdd || o.Op == opReplace) {
+		op := *o
+		op.Value = o.Value.Clone()
+		o = &op
+	}
+
+	if o.ValueRaw != "" {
+		val, err := readRawValue(o, ctx)
+		if err != nil {
+			return fmt.Errorf("yamlpatch %s operation does not apply: %s", o.Op, err)
+		}
+
+		op := *o
+		op.Value = val
+		op.ValueRaw = ""
+		o = &op
+	}
+
+	if o.Encode != "" {
+		val, err := encodeValue(o.Encode, o.Value)
+		if err != nil {
+			return fmt.Errorf("yamlpatch %s operation does not apply: %s", o.Op, err)
+		}
+
+		op := *o
+		op.Value = val
+		op.Encode = ""
+
+		return op.perform(c, ctx)
+	}
+
+	if ctx.opts.RejectNonFinite && o.Op != opTest {
+		err := checkFinite(o)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := o.checkDepth(c, ctx.opts.maxDepth())
+	if err != nil {
+		return fmt.Errorf("yamlpatch %s operation does not apply: %s", o.Op, err)
+	}
+
+	if ctx.opts.NoClobber {
+		err = o.checkClobber(c)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The empty path refers to the whole document, which can be replaced,
+	// tested, flattened, deduplicated, wrapped or have its keys transformed
+	// whatever its kind
+	if o.Path == "" && ctx.root != nil && wholeDocumentOps[o.Op] {
+		c = &rootHolder{root: ctx.root}
+	}
 
 	switch o.Op {
 	case opAdd:
@@ -76,26 +655,107 @@ func (o *Operation) Perform(c Container) error {
 		err = tryCopy(c, o)
 	case opTest:
 		err = tryTest(c, o)
+	case opRename:
+		err = tryRename(c, o)
+	case opEmbedded:
+		err = tryEmbedded(c, o)
+	case opCapture:
+		err = tryCapture(c, o, ctx)
+	case opTransform:
+		err = tryTransform(c, o)
+	case opTransformKeys:
+		err = tryTransformKeys(c, o)
+	case opFlatten:
+		err = tryFlatten(c, o)
+	case opUnflatten:
+		err = tryUnflatten(c, o)
+	case opComment:
+		err = tryComment(c, o, ctx)
+	case opDedupe:
+		err = tryDedupe(c, o)
+	case opWrap:
+		err = tryWrap(c, o)
+	case opUnwrap:
+		err = tryUnwrap(c, o)
+	case opChecksum:
+		err = tryChecksum(c, o)
+	case opSortKeys:
+		err = trySortKeys(c, o, ctx)
+	case opBlock:
+		err = tryBlock(o, ctx)
+	case opWith:
+		err = tryWith(c, o, ctx)
 	default:
-		err = fmt.Errorf("Unexpected op: %s", o.Op)
+		fn, ok := registeredOperation(o.Op)
+		if !ok {
+			return fmt.Errorf("Unexpected op: %s", o.Op)
+		}
+
+		err = fn(&Node{raw: new(interface{}), container: c}, *o)
 	}
 
 	return err
 }
 
 func tryAdd(doc Container, op *Operation) error {
+	if op.Key != "" {
+		return tryUpsert(doc, op)
+	}
+
+	if appended, err := tryAddAppending(doc, op); appended {
+		return err
+	}
+
 	con, key, err := findContainer(doc, &op.Path)
 	if err != nil {
-		return fmt.Errorf("yamlpatch add operation does not apply: doc is missing path: %s", op.Path)
+		return pathError(err, "yamlpatch add operation does not apply: doc is missing path: %s", op.Path)
+	}
+
+	if op.Spread {
+		return trySpread(con, key, op)
+	}
+
+	// The index of an array always refers to a new element, which is inserted
+	if m, ok := con.(*nodeMap); ok && m.has(key) {
+		switch op.OnExisting {
+		case onExistingError:
+			return fmt.Errorf("yamlpatch add operation does not apply: doc already has key: %s", op.Path)
+		case onExistingSkip:
+			return &skippedError{reason: SkipAlreadyPresent}
+		}
 	}
 
 	return con.Add(key, op.Value)
 }
 
+func trySpread(con Container, key string, op *Operation) error {
+	if _, ok := con.(*nodeSlice); !ok || key != "-" {
+		return fmt.Errorf("yamlpatch add operation with spread must append to an array: %s", op.Path)
+	}
+
+	if op.Value.Empty() {
+		return fmt.Errorf("yamlpatch add operation with spread requires an array value: %s", op.Path)
+	}
+
+	vals, ok := op.Value.Container().(*nodeSlice)
+	if !ok {
+		return fmt.Errorf("yamlpatch add operation with spread requires an array value: %s", op.Path)
+	}
+
+	for _, val := range *vals {
+		err := con.Add(key, val)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func tryRemove(doc Container, op *Operation) error {
 	con, key, err := findContainer(doc, &op.Path)
 	if err != nil {
-		return fmt.Errorf("yamlpatch remove operation does not apply: doc is missing path: %s", op.Path)
+		return pathError(err, "yamlpatch remove operation does not apply: doc is missing path: %s", op.Path)
 	}
 
 	return con.Remove(key)
@@ -104,7 +764,7 @@ func tryRemove(doc Container, op *Operation) error {
 func tryReplace(doc Container, op *Operation) error {
 	con, key, err := findContainer(doc, &op.Path)
 	if err != nil {
-		return fmt.Errorf("yamlpatch replace operation does not apply: doc is missing path: %s", op.Path)
+		return pathError(err, "yamlpatch replace operation does not apply: doc is missing path: %s", op.Path)
 	}
 
 	val, err := con.Get(key)
@@ -112,13 +772,93 @@ func tryReplace(doc Container, op *Operation) error {
 		return fmt.Errorf("yamlpatch replace operation does not apply: doc is missing key: %s", op.Path)
 	}
 
+	if op.Range != "" {
+		return replaceRange(val, op)
+	}
+
 	return con.Set(key, op.Value)
 }
 
+// replaceRange replaces the elements of the array within the operation's range
+// with the elements of its value
+func replaceRange(val *Node, op *Operation) error {
+	ary, ok := val.Container().(*nodeSlice)
+	if !ok {
+		return fmt.Errorf("yamlpatch replace operation does not apply: range given for a path that is not an array: %s", op.Path)
+	}
+
+	var elements []*Node
+	if !op.Value.Empty() {
+		vals, ok := op.Value.Container().(*nodeSlice)
+		if !ok {
+			return fmt.Errorf("yamlpatch replace operation does not apply: value for a range is not an array: %s", op.Path)
+		}
+		elements = *vals
+	}
+
+	start, end, err := parseRange(op.Range, len(*ary))
+	if err != nil {
+		return fmt.Errorf("yamlpatch replace operation does not apply: %s: %s", err, op.Path)
+	}
+
+	ary.splice(start, end, elements)
+	return nil
+}
+
+// ParseRange parses a range in the form START:END, where START is included
+// and END is not. Either may be omitted, as in :2 or 1:, to leave the range
+// open at that end, for which START is 0 and END is -1.
+func ParseRange(r string) (int, int, error) {
+	bounds := strings.SplitN(r, ":", 2)
+	if len(bounds) != 2 {
+		return 0, 0, fmt.Errorf("range '%s' is not in the form START:END", r)
+	}
+
+	start, end := 0, -1
+
+	var err error
+	if bounds[0] != "" {
+		start, err = strconv.Atoi(bounds[0])
+		if err != nil || start < 0 {
+			return 0, 0, fmt.Errorf("range '%s' has an invalid start", r)
+		}
+	}
+
+	if bounds[1] != "" {
+		end, err = strconv.Atoi(bounds[1])
+		if err != nil || end < start {
+			return 0, 0, fmt.Errorf("range '%s' has an invalid end", r)
+		}
+	}
+
+	return start, end, nil
+}
+
+// parseRange parses a range in the form START:END within an array of the given
+// length
+func parseRange(r string, length int) (int, int, error) {
+	start, end, err := ParseRange(r)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if start > length {
+		return 0, 0, fmt.Errorf("range '%s' has an invalid start for an array of length %d", r, length)
+	}
+
+	if end == -1 {
+		end = length
+	} else if end > length {
+		return 0, 0, fmt.Errorf("range '%s' has an invalid end for an array of length %d", r, length)
+	}
+
+	return start, end, nil
+}
+
 func tryMove(doc Container, op *Operation) error {
 	con, key, err := findContainer(doc, &op.From)
 	if err != nil {
-		return fmt.Errorf("yamlpatch move operation does not apply: doc is missing from path: %s", op.From)
+		return pathError(err, "yamlpatch move operation does not apply: doc is missing from path: %s", op.From)
 	}
 
 	val, err := con.Get(key)
@@ -126,6 +866,13 @@ func tryMove(doc Container, op *Operation) error {
 		return err
 	}
 
+	if op.Transform != "" {
+		val, err = transformNode(op, val)
+		if err != nil {
+			return fmt.Errorf("yamlpatch move operation does not apply: %s: %s", err, op.From)
+		}
+	}
+
 	err = con.Remove(key)
 	if err != nil {
 		return err
@@ -133,7 +880,7 @@ func tryMove(doc Container, op *Operation) error {
 
 	con, key, err = findContainer(doc, &op.Path)
 	if err != nil {
-		return fmt.Errorf("yamlpatch move operation does not apply: doc is missing destination path: %s", op.Path)
+		return pathError(err, "yamlpatch move operation does not apply: doc is missing destination path: %s", op.Path)
 	}
 
 	return con.Set(key, val)
@@ -142,7 +889,7 @@ func tryMove(doc Container, op *Operation) error {
 func tryCopy(doc Container, op *Operation) error {
 	con, key, err := findContainer(doc, &op.From)
 	if err != nil {
-		return fmt.Errorf("copy operation does not apply: doc is missing from path: %s", op.From)
+		return pathError(err, "copy operation does not apply: doc is missing from path: %s", op.From)
 	}
 
 	val, err := con.Get(key)
@@ -150,9 +897,24 @@ func tryCopy(doc Container, op *Operation) error {
 		return err
 	}
 
+	if val == nil {
+		return fmt.Errorf("copy operation does not apply: doc is missing from path: %s", op.From)
+	}
+
+	// The copy must not share nodes with the original, or later operations
+	// on one would also change the other
+	val = val.Clone()
+
+	if op.Transform != "" {
+		val, err = transformNode(op, val)
+		if err != nil {
+			return fmt.Errorf("copy operation does not apply: %s: %s", err, op.From)
+		}
+	}
+
 	con, key, err = findContainer(doc, &op.Path)
 	if err != nil {
-		return fmt.Errorf("copy operation does not apply: doc is missing destination path: %s", op.Path)
+		return pathError(err, "copy operation does not apply: doc is missing destination path: %s", op.Path)
 	}
 
 	return con.Set(key, val)
@@ -161,7 +923,7 @@ func tryCopy(doc Container, op *Operation) error {
 func tryTest(doc Container, op *Operation) error {
 	con, key, err := findContainer(doc, &op.Path)
 	if err != nil {
-		return fmt.Errorf("test operation does not apply: doc is missing from path: %s", op.From)
+		return pathError(err, "test operation does not apply: doc is missing from path: %s", op.From)
 	}
 
 	val, err := con.Get(key)
@@ -169,7 +931,51 @@ func tryTest(doc Container, op *Operation) error {
 		return err
 	}
 
-	if op.Value.Empty() && val == nil {
+	if op.Decode != "" && !val.Empty() {
+		val, err = decodeValue(op.Decode, val)
+		if err != nil {
+			return fmt.Errorf("test operation does not apply: %s", err)
+		}
+	}
+
+	if op.DocumentSHA256 != "" || op.ValueSemver != "" || op.ValueType != "" {
+		if op.DocumentSHA256 != "" {
+			err = testDocumentSHA256(op, val)
+			if err != nil {
+				return err
+			}
+		}
+
+		if op.ValueSemver != "" {
+			err = testSemver(op, val)
+			if err != nil {
+				return err
+			}
+		}
+
+		if op.ValueType != "" {
+			err = testValueType(op, val)
+			if err != nil {
+				return err
+			}
+		}
+
+		if op.Value.Empty() && op.ValueContains == nil {
+			return nil
+		}
+	}
+
+	if op.ValueContains != nil {
+		if val.Empty() || !val.Contains(op.ValueContains) {
+			return errors.New("test failed")
+		}
+
+		if op.Value.Empty() {
+			return nil
+		}
+	}
+
+	if op.Value.Empty() && val.Empty() {
 		return nil
 	}
 
@@ -179,3 +985,58 @@ func tryTest(doc Container, op *Operation) error {
 
 	return errors.New("test failed")
 }
+
+// tryRename changes the name of the key at the operation's from to the last
+// segment of its path, keeping the key's value. Maps are unordered and are
+// emitted with their keys sorted, so the key cannot keep its position: it is
+// emitted where its new name sorts, as it would be after a move.
+func tryRename(doc Container, op *Operation) error {
+	fromParts, _, err := op.From.Decompose()
+	if err != nil {
+		return err
+	}
+
+	pathParts, newKey, err := op.Path.Decompose()
+	if err != nil {
+		return err
+	}
+
+	if strings.Join(fromParts, "/") != strings.Join(pathParts, "/") {
+		return fmt.Errorf("yamlpatch rename operation does not apply: from and path must share a parent: %s, %s", op.From, op.Path)
+	}
+
+	con, oldKey, err := findContainer(doc, &op.From)
+	if err != nil {
+		return pathError(err, "yamlpatch rename operation does not apply: doc is missing from path: %s", op.From)
+	}
+
+	m, ok := con.(*nodeMap)
+	if !ok {
+		return fmt.Errorf("yamlpatch rename operation does not apply: from path does not point into an object: %s", op.From)
+	}
+
+	if !m.has(oldKey) {
+		return fmt.Errorf("yamlpatch rename operation does not apply: doc is missing key: %s", op.From)
+	}
+
+	newKey = decodePatchKey(newKey)
+	if oldKey == newKey {
+		return nil
+	}
+
+	if m.has(newKey) && !op.Overwrite {
+		return fmt.Errorf("yamlpatch rename operation does not apply: key already exists: %s", op.Path)
+	}
+
+	val, err := m.Get(oldKey)
+	if err != nil {
+		return err
+	}
+
+	err = m.Remove(oldKey)
+	if err != nil {
+		return err
+	}
+
+	return m.Set(newKey, val)
+}
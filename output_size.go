@@ -0,0 +1,24 @@
+package yamlpatch
+
+import "fmt"
+
+// OutputSizeError is returned when the marshaled output exceeds the
+// options' MaxOutputBytes
+type OutputSizeError struct {
+	Size  int
+	Limit int
+}
+
+func (e *OutputSizeError) Error() string {
+	return fmt.Sprintf("output of %d bytes exceeds the maximum of %d bytes", e.Size, e.Limit)
+}
+
+// checkOutputSize returns an *OutputSizeError when the output is larger than
+// the options allow
+func (o ApplyOptions) checkOutputSize(bs []byte) error {
+	if o.MaxOutputBytes > 0 && len(bs) > o.MaxOutputBytes {
+		return &OutputSizeError{Size: len(bs), Limit: o.MaxOutputBytes}
+	}
+
+	return nil
+}
@@ -2,6 +2,9 @@ package yamlpatch
 
 import (
 	"fmt"
+	"reflect"
+	"sort"
+	"strings"
 
 	yaml "gopkg.in/yaml.v2"
 )
@@ -21,40 +24,367 @@ func DecodePatch(bs []byte) (Patch, error) {
 	return p, nil
 }
 
+// DecodePatchStrict decodes the passed YAML document as if it were an RFC 6902
+// patch, returning an error naming the operation index and field when any
+// operation, including the nested operations of another, contains a field that
+// is not recognized. The index of a nested operation follows the index of the
+// operation it is nested in.
+func DecodePatchStrict(bs []byte) (Patch, error) {
+	var raw []interface{}
+
+	err := yaml.Unmarshal(bs, &raw)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkFields(raw, operationFields())
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodePatch(bs)
+}
+
+// checkFields returns an error for the first of the operations, or of their
+// nested operations, that contains a field that is not among the known fields
+func checkFields(ops []interface{}, known map[string]bool) error {
+	for i, op := range ops {
+		fields, ok := op.(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+
+		var unknown []string
+		for field := range fields {
+			if name := fmt.Sprint(field); !known[name] {
+				unknown = append(unknown, name)
+			}
+		}
+
+		if len(unknown) > 0 {
+			sort.Strings(unknown)
+			return fmt.Errorf("operation %d: unknown field '%s'", i, unknown[0])
+		}
+
+		nested, _ := fields["operations"].([]interface{})
+
+		err := checkFields(nested, known)
+		if err != nil {
+			return fmt.Errorf("operation %d: %s", i, err)
+		}
+	}
+
+	return nil
+}
+
+// operationFields returns the set of field names that an Operation can be
+// decoded from
+func operationFields() map[string]bool {
+	fields := map[string]bool{}
+
+	t := reflect.TypeOf(Operation{})
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		if name != "" && name != "-" {
+			fields[name] = true
+		}
+	}
+
+	// disabled is decoded by Operation.UnmarshalYAML, as a bool or a reference
+	fields["disabled"] = true
+
+	return fields
+}
+
+// Concat returns a new patch whose operations are those of the patch followed
+// by those of the other. Neither patch is modified. Priorities order the
+// operations of the whole resulting patch, so an operation of the other patch
+// with a lower priority is applied before the operations of this one.
+func (p Patch) Concat(other Patch) Patch {
+	return MergePatches(p, other)
+}
+
+// MergePatches returns a new patch whose operations are those of each of the
+// given patches, in order, as if they were concatenated with Concat
+func MergePatches(patches ...Patch) Patch {
+	n := 0
+	for _, p := range patches {
+		n += len(p)
+	}
+
+	merged := make(Patch, 0, n)
+	for _, p := range patches {
+		merged = append(merged, p...)
+	}
+
+	return merged
+}
+
+// Slice returns a new patch of the operations with indices from start up to,
+// but not including, end. The bounds are clamped to the patch, so that a slice
+// beyond its end is empty rather than a panic.
+func (p Patch) Slice(start, end int) Patch {
+	if start < 0 {
+		start = 0
+	}
+
+	if end > len(p) {
+		end = len(p)
+	}
+
+	if start >= end {
+		return Patch{}
+	}
+
+	return MergePatches(p[start:end])
+}
+
+// Reversed returns a new patch of the operations in reverse order, so that
+// they are applied last to first. The operations themselves are unchanged;
+// this is not the inverse of the patch. The result of operations that depend
+// on their order, such as inserts into the same array, changes accordingly.
+// Priorities still order the operations, so only operations of the same
+// priority are reversed relative to one another.
+func (p Patch) Reversed() Patch {
+	reversed := make(Patch, len(p))
+	for i, op := range p {
+		reversed[len(p)-1-i] = op
+	}
+
+	return reversed
+}
+
 // Apply returns a YAML document that has been mutated per the patch
 func (p Patch) Apply(doc []byte) ([]byte, error) {
-	var iface interface{}
-	err := yaml.Unmarshal(doc, &iface)
+	return p.ApplyWithOptions(doc, ApplyOptions{})
+}
+
+// ApplyWithOptions returns a YAML document that has been mutated per the
+// patch, using the given options
+func (p Patch) ApplyWithOptions(doc []byte, opts ApplyOptions) ([]byte, error) {
+	return p.applyDocument(doc, newApplyContext(opts))
+}
+
+// applyDocument decodes the document, applies the patch to it within the
+// given context and marshals the result
+func (p Patch) applyDocument(doc []byte, ctx *applyContext) ([]byte, error) {
+	opts := ctx.opts
+
+	iface, err := opts.codec().unmarshal(doc)
 	if err != nil {
 		return nil, fmt.Errorf("failed unmarshaling doc: %s\n\n%s", string(doc), err)
 	}
 
-	var c Container
-	c = NewNode(&iface).Container()
+	return p.applyDecoded(doc, &iface, ctx)
+}
 
-	for _, op := range p {
-		pathfinder := NewPathFinder(c)
-		if op.Path.ContainsExtendedSyntax() {
-			paths := pathfinder.Find(string(op.Path))
-			if paths == nil {
-				return nil, fmt.Errorf("could not expand pointer: %s", op.Path)
-			}
+// applyDecoded applies the patch to the decoded value of the document within
+// the given context and marshals the result, framed like the document
+func (p Patch) applyDecoded(doc []byte, iface *interface{}, ctx *applyContext) ([]byte, error) {
+	tags := findExplicitTags(doc, ctx.opts.codec())
 
-			for _, path := range paths {
-				newOp := op
-				newOp.Path = OpPath(path)
-				err = newOp.Perform(c)
-				if err != nil {
-					return nil, err
-				}
-			}
-		} else {
-			err = op.Perform(c)
+	root, applyErr := p.applyContext(iface, ctx)
+	if applyErr != nil && (!ctx.opts.BestEffort || root == nil) {
+		return nil, applyErr
+	}
+
+	bs, err := ctx.marshal(root, doc, tags)
+	if err != nil {
+		return nil, err
+	}
+
+	bs = ctx.opts.frameLike(doc, bs)
+
+	err = ctx.opts.checkOutputSize(bs)
+	if err != nil {
+		return nil, err
+	}
+
+	return bs, applyErr
+}
+
+// ApplyPartial is a debugging variant of ApplyWithOptions. When an operation
+// fails, it returns the document as it was just before that operation was
+// performed, with the effects of every operation performed before it, along
+// with the index of the failing operation within the patch and its error.
+// When the patch succeeds, or fails before any operation is performed, the
+// index is -1. The document is marshaled before every operation, so this is
+// considerably slower than ApplyWithOptions.
+func (p Patch) ApplyPartial(doc []byte, opts ApplyOptions) ([]byte, int, error) {
+	iface, err := opts.codec().unmarshal(doc)
+	if err != nil {
+		return nil, -1, fmt.Errorf("failed unmarshaling doc: %s\n\n%s", string(doc), err)
+	}
+
+	tags := findExplicitTags(doc, opts.codec())
+
+	var partial []byte
+	failed := -1
+
+	ctx := newApplyContext(opts)
+	ctx.before = func(i int, root *Node) error {
+		bs, err := ctx.marshal(root, doc, tags)
+		if err != nil {
+			return err
+		}
+
+		partial = bs
+		failed = i
+		return nil
+	}
+
+	root, err := p.applyContext(&iface, ctx)
+	if err != nil {
+		if failed == -1 {
+			return nil, -1, err
+		}
+
+		return opts.frameLike(doc, partial), failed, err
+	}
+
+	bs, err := ctx.marshal(root, doc, tags)
+	if err != nil {
+		return nil, -1, err
+	}
+
+	bs = opts.frameLike(doc, bs)
+
+	err = opts.checkOutputSize(bs)
+	if err != nil {
+		return nil, -1, err
+	}
+
+	return bs, -1, nil
+}
+
+// frameLike frames the marshaled document the way the first document in doc
+// is framed, leaving out its directives when the options strip them
+func (o ApplyOptions) frameLike(doc, bs []byte) []byte {
+	if docs := splitDocuments(doc); len(docs) > 0 {
+		return o.framing(docs[0]).frame(bs, false)
+	}
+
+	return bs
+}
+
+// Normalize returns the document re-emitted using the given options without
+// applying any operations. Only formatting changes; the content of the
+// document is unaffected.
+func Normalize(doc []byte, opts ApplyOptions) ([]byte, error) {
+	return Patch(nil).ApplyWithOptions(doc, opts)
+}
+
+// apply applies the patch to the decoded document, returning the root of the
+// patched document
+func (p Patch) apply(iface *interface{}, opts ApplyOptions) (*Node, error) {
+	return p.applyContext(iface, newApplyContext(opts))
+}
+
+func (p Patch) applyContext(iface *interface{}, ctx *applyContext) (*Node, error) {
+	err := checkDepth(*iface, ctx.opts.maxDepth())
+	if err != nil {
+		return nil, err
+	}
+
+	root := NewNode(iface)
+
+	err = p.applyTo(root, ctx)
+	if err != nil {
+		return root, err
+	}
+
+	return root, nil
+}
+
+// applyTo applies the patch to the document with the given root. Operations
+// with the empty path replace the root in place. When the options are
+// best-effort, the errors of failing operations are collected into a
+// *MultiError instead.
+func (p Patch) applyTo(root *Node, ctx *applyContext) error {
+	ctx.root = root
+
+	if ctx.opts.RejectConflicts {
+		if conflicts := p.Conflicts(); len(conflicts) > 0 {
+			return conflicts[0]
+		}
+	}
+
+	if ctx.opts.NoClobber {
+		if err := p.checkNoClobber(); err != nil {
+			return err
+		}
+	}
+
+	errs := &MultiError{}
+
+	for _, i := range p.order() {
+		op := p[i]
+		c := root.Container()
+
+		if ctx.stream && op.isCrossDocument() {
+			continue
+		}
+
+		if !op.targets(ctx.document, ctx.last) {
+			ctx.skip(i, op, SkipNotTargeted)
+			continue
+		}
+
+		if reason := op.skipReason(c); reason != "" {
+			ctx.skip(i, op, reason)
+			continue
+		}
+
+		ctx.debugf("operation %d: %s %s", i, op.Op, op.Path)
+
+		if ctx.before != nil {
+			err := ctx.before(i, root)
 			if err != nil {
-				return nil, err
+				return err
+			}
+		}
+
+		if ctx.opts.BeforeOperation != nil {
+			ctx.opts.BeforeOperation(i, op)
+		}
+
+		ctx.current = i
+		reason, err := op.performExpanded(c, ctx)
+		if reason != "" && err == nil {
+			ctx.skip(i, op, reason)
+			continue
+		}
+
+		if ctx.opts.OnOperation != nil {
+			ctx.opts.OnOperation(i, op, err)
+		}
+
+		if err != nil {
+			ctx.debugf("operation %d: failed: %s", i, err)
+
+			if !ctx.opts.BestEffort {
+				ctx.failed = i
+				return err
 			}
+
+			errs.append(&ApplyError{Document: -1, Operation: i, Err: err})
 		}
 	}
 
-	return yaml.Marshal(c)
+	return errs.errorOrNil()
+}
+
+// order returns the indices of the operations of the patch stably sorted by
+// priority
+func (p Patch) order() []int {
+	order := make([]int, len(p))
+	for i := range order {
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		return p[order[i]].Priority < p[order[j]].Priority
+	})
+
+	return order
 }
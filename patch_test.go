@@ -1,6 +1,9 @@
 package yamlpatch_test
 
 import (
+	"fmt"
+	"strings"
+
 	yamlpatch "github.com/krishicks/yaml-patch"
 	yaml "gopkg.in/yaml.v2"
 
@@ -158,6 +161,20 @@ foo: [bar]
 `,
 				`---
 foo: [bar, [abc, def]]
+`,
+			),
+			Entry("appending each element of an array to an array with spread",
+				`---
+foo: [bar]
+`,
+				`---
+- op: add
+  path: /foo/-
+  value: [abc,def]
+  spread: true
+`,
+				`---
+foo: [bar, abc, def]
 `,
 			),
 			Entry("removing a nil element from an object",
@@ -316,278 +333,2457 @@ foo:
     qux: corge
 `,
 			),
-			XEntry("copying an element in an array within a root array to a destination without an index",
-				// this is in jsonpatch, but I'd like confirmation from the spec that this is intended
+			Entry("replacing an element in an object with an integer key",
 				`---
-- foo: [bar, qux, baz]
-  bar: [qux, baz]
+foo:
+  1: one
+  2: two
 `,
 				`---
-- op: copy
-  from: /0/foo/0
-  path: /0/bar
+- op: replace
+  path: /foo/1
+  value: uno
 `,
 				`---
-- foo: [bar, qux, baz]
-  bar: [bar, qux, baz]
+foo:
+  1: uno
+  2: two
 `,
 			),
-			XEntry("copying an element in an array within a root array to a destination without an index",
+			Entry("removing an element from an object with an integer key",
 				`---
-- foo:
-  bar: [qux, baz]
-  baz:
-    qux: bum
+foo:
+  1: one
+  2: two
 `,
 				`---
-- op: copy
-  from: /0/foo/bar
-  path: /0/baz/bar
+- op: remove
+  path: /foo/2
 `,
 				`---
-- foo: [bar, qux, baz]
-  bar: [bar, qux, baz]
+foo:
+  1: one
 `,
 			),
-		)
-
-		DescribeTable(
-			"with extended syntax",
-			func(doc, ops, expectedYAML string) {
-				patch, err := yamlpatch.DecodePatch([]byte(ops))
-				Expect(err).NotTo(HaveOccurred())
-
-				actualBytes, err := patch.Apply([]byte(doc))
-				Expect(err).NotTo(HaveOccurred())
-
-				var actualIface interface{}
-				err = yaml.Unmarshal(actualBytes, &actualIface)
-				Expect(err).NotTo(HaveOccurred())
-
-				var expectedIface interface{}
-				err = yaml.Unmarshal([]byte(expectedYAML), &expectedIface)
-				Expect(err).NotTo(HaveOccurred())
-
-				Expect(actualIface).To(Equal(expectedIface))
-			},
-			Entry("a path that begins with a composite key",
+			Entry("preferring a string key over an integer key",
 				`---
-- foo: bar
+foo:
+  1: one
+  "1": string-one
 `,
 				`---
 - op: replace
-  path: /foo=bar
-  value:
-    baz: quux
+  path: /foo/1
+  value: uno
 `,
 				`---
-- baz: quux
+foo:
+  1: one
+  "1": uno
 `,
 			),
-			Entry("a path that begins with an array index and ends with a composite key",
+			Entry("renaming a key in an object",
 				`---
-- waldo:
-    - thud: boo
-    - foo: bar
-- corge: grault
+foo:
+  bar: baz
+  qux: ~
 `,
 				`---
-- op: replace
-  path: /0/foo=bar
-  value:
-    baz: quux
+- op: rename
+  from: /foo/bar
+  path: /foo/waldo
 `,
 				`---
-- waldo:
-    - thud: boo
-    - baz: quux
-- corge: grault
+foo:
+  waldo: baz
+  qux: ~
 `,
 			),
-			Entry("a path that begins with an object key and ends with a composite key",
+			Entry("renaming a key onto an existing key with overwrite",
 				`---
-waldo:
-  - thud: boo
-  - foo: bar
-corge: grault
+foo:
+  bar: baz
+  qux: quux
 `,
 				`---
-- op: replace
-  path: /waldo/foo=bar
-  value:
-    baz: quux
+- op: rename
+  from: /foo/bar
+  path: /foo/qux
+  overwrite: true
 `,
 				`---
-waldo:
-  - thud: boo
-  - baz: quux
-corge: grault
+foo:
+  qux: baz
 `,
 			),
-			Entry("a path that doesn't end with a composite key",
+			Entry("adding a base64 encoded value to an object",
 				`---
-jobs:
-- name: upgrade-opsmgr
-  serial: true
-  plan:
-  - get: pivnet-opsmgr
-  - put: something-else
+data: {}
 `,
 				`---
-- op: replace
-  path: /jobs/name=upgrade-opsmgr/plan/1
-  value:
-    get: something-else
+- op: add
+  path: /data/password
+  value: hunter2
+  encode: base64
 `,
 				`---
-jobs:
-- name: upgrade-opsmgr
-  serial: true
-  plan:
-  - get: pivnet-opsmgr
-  - get: something-else
+data:
+  password: aHVudGVyMg==
 `,
 			),
-			Entry("removes multiple entries in a single op",
+			Entry("testing a base64 encoded value in an object",
 				`---
-foo:
-  - bar: baz
-  - waldo: fred
-qux:
-  corge: grault
-  thud:
-    - waldo: fred
-    - bar: baz
+data:
+  password: aHVudGVyMg==
 `,
 				`---
-- op: remove
-  path: /waldo=fred
+- op: test
+  path: /data/password
+  value: hunter2
+  decode: base64
 `,
 				`---
-foo:
-  - bar: baz
-qux:
-  corge: grault
-  thud:
-    - bar: baz
+data:
+  password: aHVudGVyMg==
 `,
 			),
-		)
-
-		DescribeTable(
-			"failure cases",
-			func(doc, ops string) {
-				patch, err := yamlpatch.DecodePatch([]byte(ops))
-				Expect(err).NotTo(HaveOccurred())
-
-				_, err = patch.Apply([]byte(doc))
-				Expect(err).To(HaveOccurred())
-			},
-			Entry("adding an element to an object with a bad pointer",
+			Entry("patching a YAML document embedded in a string",
 				`---
-foo: bar
+data:
+  config.yml: |
+    server:
+      port: 80
 `,
 				`---
-- op: add
-  path: /baz/bat
-  value: qux
+- op: embedded
+  path: /data/config.yml
+  operations:
+  - op: replace
+    path: /server/port
+    value: 8080
+`,
+				`---
+data:
+  config.yml: |
+    server:
+      port: 8080
 `,
 			),
-			Entry("removing an element from an object with a bad pointer",
+			Entry("patching a JSON document embedded in a string",
 				`---
-a:
-  b:
-    d: 1
+data:
+  config.json: '{"server":{"port":80}}'
 `,
 				`---
-- op: remove
-  path: /a/b/c
+- op: embedded
+  path: /data/config.json
+  operations:
+  - op: add
+    path: /server/host
+    value: localhost
 `,
-			),
-			Entry("moving an element in an object with a bad pointer",
 				`---
-a:
-  b:
-    d: 1
+data:
+  config.json: '{"server":{"host":"localhost","port":80}}'
 `,
+			),
+			Entry("applying operations with CRLF line endings to a document with CRLF line endings",
+				"---\r\nfoo:\r\n  bar: baz\r\n",
+				"---\r\n- op: add\r\n  path: /foo/qux\r\n  value: |\r\n    multi\r\n    line\r\n",
 				`---
-- op: move
-  from: /a/b/c
-  path: /a/b/e
+foo:
+  bar: baz
+  qux: |
+    multi
+    line
 `,
 			),
-			Entry("removing an element from an array with a bad pointer",
+			Entry("applying operations in order of priority",
 				`---
-a:
-  b: [1]
+foo: [bar]
 `,
 				`---
-- op: remove
-  path: /a/b/1
+- op: add
+  path: /foo/-
+  value: last
+  priority: 10
+- op: add
+  path: /foo/-
+  value: second
+- op: add
+  path: /foo/0
+  value: first
+  priority: -1
+- op: add
+  path: /foo/-
+  value: third
+`,
+				`---
+foo: [first, bar, second, third, last]
 `,
 			),
-			Entry("moving an element from an array with a bad pointer",
+			Entry("capturing a value and reusing it in later operations",
 				`---
-a:
-  b: [1]
+spec:
+  image: app
+  tag: v1.2.3
+metadata: {}
 `,
 				`---
-- op: move
-  from: /a/b/1
-  path: /a/b/2
+- op: capture
+  path: /spec/tag
+  as: tag
+- op: add
+  path: /metadata/annotations
+  value:
+    version: "{{var:tag}}"
+    image: "{{var:tag}}-{{ var:tag }}"
+- op: replace
+  path: /spec/image
+  value: "app:{{var:tag}}"
+`,
+				`---
+spec:
+  image: app:v1.2.3
+  tag: v1.2.3
+metadata:
+  annotations:
+    version: v1.2.3
+    image: v1.2.3-v1.2.3
 `,
 			),
-			Entry("an operation with an invalid pathz field",
+			Entry("capturing a structured value and reusing it",
 				`---
-foo: bar
+a: {b: [1, 2]}
 `,
 				`---
+- op: capture
+  path: /a/b
+  as: list
 - op: add
-  pathz: /baz
-  value: qux
+  path: /c
+  value: "{{var:list}}"
+`,
+				`---
+a: {b: [1, 2]}
+c: [1, 2]
 `,
 			),
-			Entry("an add operation with an empty path",
+			Entry("referencing other paths of the document in a value",
 				`---
-foo: bar
+spec:
+  image: app:v1
+  ports: [80, 443]
+metadata: {}
 `,
 				`---
 - op: add
-  path: ''
-  value: qux
+  path: /metadata/annotations
+  value:
+    built-from: "{{path:/spec/image}}"
+    summary: "{{path:/spec/image}} on {{ path:/spec/ports/0 }}"
+- op: add
+  path: /metadata/ports
+  value: "{{path:/spec/ports}}"
+- op: replace
+  path: /spec/image
+  value: app:v2
+- op: add
+  path: /metadata/current
+  value: "{{path:/spec/image}}"
+`,
+				`---
+spec:
+  image: app:v2
+  ports: [80, 443]
+metadata:
+  annotations:
+    built-from: app:v1
+    summary: app:v1 on 80
+  ports: [80, 443]
+  current: app:v2
 `,
 			),
-			Entry("a replace operation on an array with an invalid path",
+			Entry("transforming strings in an object",
+				`---
+metadata:
+  name: "  My-App  "
+  env: Prod
+  team: PLATFORM
+  title: the quick-brown fox
+`,
+				`---
+- op: transform
+  path: /metadata/name
+  transform: trim
+- op: transform
+  path: /metadata/name
+  transform: lower
+- op: transform
+  path: /metadata/env
+  transform: upper
+- op: transform
+  path: /metadata/title
+  transform: title
+`,
+				`---
+metadata:
+  name: my-app
+  env: PROD
+  team: PLATFORM
+  title: The Quick-Brown Fox
+`,
+			),
+			Entry("copying a string with a regex transform applied",
 				`---
-name:
-  foo:
-    bat
-  qux:
-    bum
+spec:
+  image: registry/app:v1.2.3
+metadata:
+  annotations: {}
 `,
 				`---
-- op: replace
-  path: /foo/2
-  value: bum
+- op: copy
+  from: /spec/image
+  path: /metadata/annotations/image
+  transform: regex
+  pattern: ":[^:/]*$"
+  replacement: ""
+- op: copy
+  from: /spec/image
+  path: /metadata/annotations/version
+  transform: regex
+  pattern: "^.*:(v[0-9.]+)$"
+  replacement: "$1"
+`,
+				`---
+spec:
+  image: registry/app:v1.2.3
+metadata:
+  annotations:
+    image: registry/app
+    version: v1.2.3
 `,
 			),
-		)
-	})
-
-	Describe("DecodePatch", func() {
-		It("returns an empty patch when given nil", func() {
-			patch, err := yamlpatch.DecodePatch(nil)
-			Expect(err).NotTo(HaveOccurred())
-
-			Expect(patch).To(HaveLen(0))
-		})
-
-		It("returns a patch with a single op when given a single op", func() {
-			ops := []byte(
+			Entry("moving a string with a transform applied",
+				`---
+name: "  App  "
+`,
+				`---
+- op: move
+  from: /name
+  path: /title
+  transform: trim
+`,
 				`---
+title: App
+`,
+			),
+			Entry("testing that an object contains a subset of keys",
+				`---
+metadata:
+  name: app
+  labels:
+    tier: web
+    team: core
+  annotations: {}
+`,
+				`---
+- op: test
+  path: /metadata
+  value_contains:
+    labels:
+      tier: web
 - op: add
-  path: /baz
-  value: qux`)
+  path: /metadata/labels/checked
+  value: true
+`,
+				`---
+metadata:
+  name: app
+  labels:
+    tier: web
+    team: core
+    checked: true
+  annotations: {}
+`,
+			),
+			Entry("testing that an array contains an element matching a subset",
+				`---
+containers:
+- name: app
+  image: app:1
+- name: sidecar
+  image: proxy:2
+`,
+				`---
+- op: test
+  path: /containers
+  value_contains:
+  - name: sidecar
+`,
+				`---
+containers:
+- name: app
+  image: app:1
+- name: sidecar
+  image: proxy:2
+`,
+			),
+			Entry("adding a value at each of a list of paths",
+				`---
+web:
+  env: {}
+worker:
+  env: {}
+`,
+				`---
+- op: add
+  path: [/web/env/LOG_LEVEL, /worker/env/LOG_LEVEL]
+  value: info
+`,
+				`---
+web:
+  env:
+    LOG_LEVEL: info
+worker:
+  env:
+    LOG_LEVEL: info
+`,
+			),
+			Entry("appending to one of the values added at a list of paths",
+				`---
+{}
+`,
+				`---
+- op: add
+  path: [/a, /b]
+  value: [x]
+- op: add
+  path: /a/-
+  value: z
+`,
+				`---
+a: [x, z]
+b: [x]
+`,
+			),
+			Entry("copying a value to each of a list of paths, including extended syntax",
+				`---
+default: 3
+services:
+- name: web
+- name: worker
+`,
+				`---
+- op: copy
+  from: /default
+  path: [/services/name=web/replicas, /services/name=worker/replicas]
+`,
+				`---
+default: 3
+services:
+- name: web
+  replicas: 3
+- name: worker
+  replicas: 3
+`,
+			),
+			Entry("appending to and replacing within a document whose root is a sequence",
+				`---
+- a
+- b
+`,
+				`---
+- op: add
+  path: /-
+  value: c
+- op: replace
+  path: /0
+  value: z
+- op: test
+  path: /1
+  value: b
+`,
+				`---
+- z
+- b
+- c
+`,
+			),
+			Entry("replacing a document whose root is a scalar",
+				`---
+hello
+`,
+				`---
+- op: test
+  path: ""
+  value: hello
+- op: replace
+  path: ""
+  value: world
+`,
+				`---
+world
+`,
+			),
+			Entry("replacing the root of a document with a collection of another kind",
+				`---
+foo: bar
+`,
+				`---
+- op: replace
+  path: ""
+  value: [a]
+- op: add
+  path: /-
+  value: b
+`,
+				`---
+[a, b]
+`,
+			),
+			Entry("replacing the whole of an array with a list",
+				`---
+list: [a, b, c]
+`,
+				`---
+- op: replace
+  path: /list
+  value: [d]
+`,
+				`---
+list: [d]
+`,
+			),
+			Entry("replacing a range of elements of an array",
+				`---
+list: [a, b, c, d]
+`,
+				`---
+- op: replace
+  path: /list
+  range: "1:3"
+  value: [x, z, w]
+`,
+				`---
+list: [a, x, z, w, d]
+`,
+			),
+			Entry("replacing open-ended ranges of elements of an array",
+				`---
+list: [a, b, c, d]
+`,
+				`---
+- op: replace
+  path: /list
+  range: "3:"
+  value: []
+- op: replace
+  path: /list
+  range: ":1"
+  value: [x]
+`,
+				`---
+list: [x, b, c]
+`,
+			),
+			Entry("falling back to other paths when the path is not found",
+				`---
+a:
+  c: 1
+  d: 2
+`,
+				`---
+- op: replace
+  path: /a/b
+  fallback: [/a/missing, /a/c, /a/d]
+  value: 10
+- op: replace
+  path: /a/d
+  fallback: [/a/c]
+  value: 20
+- op: add
+  path: /x/e
+  fallback: [/a/e]
+  value: 30
+`,
+				`---
+a:
+  c: 10
+  d: 20
+  e: 30
+`,
+			),
+			Entry("skipping disabled operations",
+				`---
+a: 1
+`,
+				`---
+- op: remove
+  path: /a
+  disabled: true
+- op: remove
+  path: /missing
+  disabled: true
+- op: add
+  path: /b
+  value: 2
+  disabled: false
+`,
+				`---
+a: 1
+b: 2
+`,
+			),
+			Entry("skipping operations disabled by a variable",
+				`---
+legacy: true
+modern: false
+a: 1
+`,
+				`---
+- op: capture
+  path: /legacy
+  as: legacy
+- op: capture
+  path: /modern
+  as: modern
+- op: remove
+  path: /a
+  disabled: "{{var:legacy}}"
+- op: add
+  path: /b
+  value: 2
+  disabled: "{{var:modern}}"
+`,
+				`---
+a: 1
+b: 2
+legacy: true
+modern: false
+`,
+			),
+			Entry("adding with chained appends that create the structure",
+				`---
+spec:
+  containers:
+  - name: web
+`,
+				`---
+- op: add
+  path: /spec/containers/-/env/-
+  value: {name: DEBUG, value: "1"}
+- op: add
+  path: /spec/containers/-/-/-
+  value: x
+`,
+				`---
+spec:
+  containers:
+  - name: web
+  - env:
+    - {name: DEBUG, value: "1"}
+  - [[x]]
+`,
+			),
+			Entry("adding with chained appends that create missing keys",
+				`---
+spec: {}
+`,
+				`---
+- op: add
+  path: /spec/containers/-/env/-
+  value: {name: DEBUG, value: "1"}
+`,
+				`---
+spec:
+  containers:
+  - env:
+    - {name: DEBUG, value: "1"}
+`,
+			),
+			Entry("adding with a '-' that is a key of a map",
+				`---
+a: {"-": {b: c}}
+list: [{"-": {}}]
+`,
+				`---
+- op: add
+  path: /a/-/d
+  value: 1
+- op: add
+  path: /list/-/-/e
+  value: 2
+- op: add
+  path: /list/0/-/g
+  value: 3
+`,
+				`---
+a: {"-": {b: c, d: 1}}
+list:
+- {"-": {g: 3}}
+- [{e: 2}]
+`,
+			),
+			Entry("adding with chained appends to a root array",
+				`---
+- a
+`,
+				`---
+- op: add
+  path: /-/b/-
+  value: 1
+`,
+				`---
+- a
+- {b: [1]}
+`,
+			),
+			Entry("upserting maps into an array by key",
+				`---
+env:
+- name: LOG_LEVEL
+  value: info
+- name: PORT
+  value: "80"
+`,
+				`---
+- op: add
+  path: /env
+  key: name
+  value: {name: LOG_LEVEL, value: debug}
+- op: add
+  path: /env
+  key: name
+  value:
+  - {name: DEBUG, value: "1"}
+  - {name: PORT, value: "8080"}
+`,
+				`---
+env:
+- name: LOG_LEVEL
+  value: debug
+- name: PORT
+  value: "8080"
+- name: DEBUG
+  value: "1"
+`,
+			),
+			Entry("modifying a copy without modifying the original",
+				`---
+foo:
+  bar: baz
+`,
+				`---
+- op: copy
+  from: /foo
+  path: /qux
+- op: add
+  path: /qux/waldo
+  value: fred
+`,
+				`---
+foo:
+  bar: baz
+qux:
+  bar: baz
+  waldo: fred
+`,
+			),
+			XEntry("copying an element in an array within a root array to a destination without an index",
+				// this is in jsonpatch, but I'd like confirmation from the spec that this is intended
+				`---
+- foo: [bar, qux, baz]
+  bar: [qux, baz]
+`,
+				`---
+- op: copy
+  from: /0/foo/0
+  path: /0/bar
+`,
+				`---
+- foo: [bar, qux, baz]
+  bar: [bar, qux, baz]
+`,
+			),
+			XEntry("copying an element in an array within a root array to a destination without an index",
+				`---
+- foo:
+  bar: [qux, baz]
+  baz:
+    qux: bum
+`,
+				`---
+- op: copy
+  from: /0/foo/bar
+  path: /0/baz/bar
+`,
+				`---
+- foo: [bar, qux, baz]
+  bar: [bar, qux, baz]
+`,
+			),
+		)
+
+		DescribeTable(
+			"with extended syntax",
+			func(doc, ops, expectedYAML string) {
+				patch, err := yamlpatch.DecodePatch([]byte(ops))
+				Expect(err).NotTo(HaveOccurred())
+
+				actualBytes, err := patch.Apply([]byte(doc))
+				Expect(err).NotTo(HaveOccurred())
+
+				var actualIface interface{}
+				err = yaml.Unmarshal(actualBytes, &actualIface)
+				Expect(err).NotTo(HaveOccurred())
+
+				var expectedIface interface{}
+				err = yaml.Unmarshal([]byte(expectedYAML), &expectedIface)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(actualIface).To(Equal(expectedIface))
+			},
+			Entry("a path that begins with a composite key",
+				`---
+- foo: bar
+`,
+				`---
+- op: replace
+  path: /foo=bar
+  value:
+    baz: quux
+`,
+				`---
+- baz: quux
+`,
+			),
+			Entry("a path that begins with an array index and ends with a composite key",
+				`---
+- waldo:
+    - thud: boo
+    - foo: bar
+- corge: grault
+`,
+				`---
+- op: replace
+  path: /0/foo=bar
+  value:
+    baz: quux
+`,
+				`---
+- waldo:
+    - thud: boo
+    - baz: quux
+- corge: grault
+`,
+			),
+			Entry("a path that begins with an object key and ends with a composite key",
+				`---
+waldo:
+  - thud: boo
+  - foo: bar
+corge: grault
+`,
+				`---
+- op: replace
+  path: /waldo/foo=bar
+  value:
+    baz: quux
+`,
+				`---
+waldo:
+  - thud: boo
+  - baz: quux
+corge: grault
+`,
+			),
+			Entry("a path that doesn't end with a composite key",
+				`---
+jobs:
+- name: upgrade-opsmgr
+  serial: true
+  plan:
+  - get: pivnet-opsmgr
+  - put: something-else
+`,
+				`---
+- op: replace
+  path: /jobs/name=upgrade-opsmgr/plan/1
+  value:
+    get: something-else
+`,
+				`---
+jobs:
+- name: upgrade-opsmgr
+  serial: true
+  plan:
+  - get: pivnet-opsmgr
+  - get: something-else
+`,
+			),
+			Entry("removes multiple entries in a single op",
+				`---
+foo:
+  - bar: baz
+  - waldo: fred
+qux:
+  corge: grault
+  thud:
+    - waldo: fred
+    - bar: baz
+`,
+				`---
+- op: remove
+  path: /waldo=fred
+`,
+				`---
+foo:
+  - bar: baz
+qux:
+  corge: grault
+  thud:
+    - bar: baz
+`,
+			),
+			Entry("a path using a recursive search for the n-th occurrence of a key",
+				`---
+spec:
+  containers:
+  - name: app
+    image: app:v1
+  - name: sidecar
+    image: proxy:v1
+`,
+				`---
+- op: replace
+  path: //image[1]
+  value: proxy:v2
+`,
+				`---
+spec:
+  containers:
+  - name: app
+    image: app:v1
+  - name: sidecar
+    image: proxy:v2
+`,
+			),
+			Entry("an operation restricted to matching subtrees by scope",
+				`---
+items:
+- kind: Pod
+  metadata:
+    name: a
+- kind: Service
+  metadata:
+    name: b
+- list:
+  - kind: Pod
+    metadata:
+      name: c
+`,
+				`---
+- op: add
+  path: /metadata/labels
+  value:
+    app: web
+  scope: /kind=Pod
+`,
+				`---
+items:
+- kind: Pod
+  metadata:
+    name: a
+    labels:
+      app: web
+- kind: Service
+  metadata:
+    name: b
+- list:
+  - kind: Pod
+    metadata:
+      name: c
+      labels:
+        app: web
+`,
+			),
+			Entry("an operation with a scope that matches nothing",
+				`---
+kind: Service
+`,
+				`---
+- op: add
+  path: /metadata
+  value: {}
+  scope: /kind=Pod
+`,
+				`---
+kind: Service
+`,
+			),
+		)
+
+		DescribeTable(
+			"failure cases",
+			func(doc, ops string) {
+				patch, err := yamlpatch.DecodePatch([]byte(ops))
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = patch.Apply([]byte(doc))
+				Expect(err).To(HaveOccurred())
+			},
+			Entry("adding an element to an object with a bad pointer",
+				`---
+foo: bar
+`,
+				`---
+- op: add
+  path: /baz/bat
+  value: qux
+`,
+			),
+			Entry("removing an element from an object with a bad pointer",
+				`---
+a:
+  b:
+    d: 1
+`,
+				`---
+- op: remove
+  path: /a/b/c
+`,
+			),
+			Entry("moving an element in an object with a bad pointer",
+				`---
+a:
+  b:
+    d: 1
+`,
+				`---
+- op: move
+  from: /a/b/c
+  path: /a/b/e
+`,
+			),
+			Entry("removing an element from an array with a bad pointer",
+				`---
+a:
+  b: [1]
+`,
+				`---
+- op: remove
+  path: /a/b/1
+`,
+			),
+			Entry("moving an element from an array with a bad pointer",
+				`---
+a:
+  b: [1]
+`,
+				`---
+- op: move
+  from: /a/b/1
+  path: /a/b/2
+`,
+			),
+			Entry("an operation with an invalid pathz field",
+				`---
+foo: bar
+`,
+				`---
+- op: add
+  pathz: /baz
+  value: qux
+`,
+			),
+			Entry("an add operation with an empty path",
+				`---
+foo: bar
+`,
+				`---
+- op: add
+  path: ''
+  value: qux
+`,
+			),
+			Entry("a spread add operation with a value that is not an array",
+				`---
+foo: [bar]
+`,
+				`---
+- op: add
+  path: /foo/-
+  value: baz
+  spread: true
+`,
+			),
+			Entry("a spread add operation that does not append to an array",
+				`---
+foo: [bar]
+`,
+				`---
+- op: add
+  path: /foo/0
+  value: [abc,def]
+  spread: true
+`,
+			),
+			Entry("a rename operation with a missing key",
+				`---
+foo:
+  bar: baz
+`,
+				`---
+- op: rename
+  from: /foo/qux
+  path: /foo/waldo
+`,
+			),
+			Entry("a rename operation of a missing key to itself",
+				`---
+foo:
+  bar: baz
+`,
+				`---
+- op: rename
+  from: /foo/qux
+  path: /foo/qux
+`,
+			),
+			Entry("a rename operation onto an existing key",
+				`---
+foo:
+  bar: baz
+  qux: quux
+`,
+				`---
+- op: rename
+  from: /foo/bar
+  path: /foo/qux
+`,
+			),
+			Entry("a rename operation to a different parent",
+				`---
+foo:
+  bar: baz
+qux: {}
+`,
+				`---
+- op: rename
+  from: /foo/bar
+  path: /qux/bar
+`,
+			),
+			Entry("an encoded add operation with a value that is not a string",
+				`---
+data: {}
+`,
+				`---
+- op: add
+  path: /data/password
+  value: [hunter2]
+  encode: base64
+`,
+			),
+			Entry("an embedded operation on a value that is not a string",
+				`---
+data:
+  config: {}
+`,
+				`---
+- op: embedded
+  path: /data/config
+  operations:
+  - op: add
+    path: /foo
+    value: bar
+`,
+			),
+			Entry("an operation referring to an undefined variable",
+				`---
+a: b
+`,
+				`---
+- op: capture
+  path: /a
+  as: a
+- op: add
+  path: /c
+  value: "{{var:missing}}"
+`,
+			),
+			Entry("an operation disabled by a variable that is not a bool",
+				`---
+a: b
+`,
+				`---
+- op: capture
+  path: /a
+  as: a
+- op: remove
+  path: /a
+  disabled: "{{var:a}}"
+`,
+			),
+			Entry("a value referencing a path that does not exist",
+				`---
+a: b
+`,
+				`---
+- op: add
+  path: /c
+  value: "{{path:/missing}}"
+`,
+			),
+			Entry("a capture operation with a missing path",
+				`---
+a: b
+`,
+				`---
+- op: capture
+  path: /missing
+  as: a
+`,
+			),
+			Entry("a transform operation on a value that is not a string",
+				`---
+replicas: 3
+`,
+				`---
+- op: transform
+  path: /replicas
+  transform: lower
+`,
+			),
+			Entry("a transform operation with an unknown transform",
+				`---
+name: foo
+`,
+				`---
+- op: transform
+  path: /name
+  transform: reverse
+`,
+			),
+			Entry("a copy operation with a transform of a value that is not a string",
+				`---
+spec:
+  replicas: 3
+`,
+				`---
+- op: copy
+  from: /spec/replicas
+  path: /spec/count
+  transform: upper
+`,
+			),
+			Entry("moving an element in an array to an index beyond its end",
+				`---
+foo: [all, grass, cows, eat]
+`,
+				`---
+- op: move
+  from: /foo/1
+  path: /foo/5
+`,
+			),
+			Entry("copying an element in an array to a negative index",
+				`---
+foo: [all, grass, cows, eat]
+`,
+				`---
+- op: copy
+  from: /foo/1
+  path: /foo/-1
+`,
+			),
+			Entry("removing an element in an array at a negative index",
+				`---
+foo: [all, grass, cows, eat]
+`,
+				`---
+- op: remove
+  path: /foo/-1
+`,
+			),
+			Entry("testing that an object contains a key it lacks",
+				`---
+metadata:
+  labels:
+    tier: web
+`,
+				`---
+- op: test
+  path: /metadata
+  value_contains:
+    labels:
+      team: core
+`,
+			),
+			Entry("testing that an object contains a key with a different value",
+				`---
+metadata:
+  labels:
+    tier: web
+`,
+				`---
+- op: test
+  path: /metadata/labels
+  value_contains:
+    tier: db
+`,
+			),
+			Entry("testing that a missing value contains a subset",
+				`---
+metadata: {}
+`,
+				`---
+- op: test
+  path: /spec
+  value_contains:
+    replicas: 1
+`,
+			),
+			Entry("a replace operation with a list of paths, one of which is missing",
+				`---
+a: 1
+`,
+				`---
+- op: replace
+  path: [/a, /b]
+  value: 2
+`,
+			),
+			Entry("an add operation beneath a document whose root is a scalar",
+				`---
+hello
+`,
+				`---
+- op: add
+  path: /foo
+  value: bar
+`,
+			),
+			Entry("an add operation beneath a scalar",
+				`---
+foo: bar
+`,
+				`---
+- op: add
+  path: /foo/baz/qux
+  value: bar
+`,
+			),
+			Entry("a test operation on a document whose root is a scalar with a different value",
+				`---
+hello
+`,
+				`---
+- op: test
+  path: ""
+  value: world
+`,
+			),
+			Entry("a replace operation with a range beyond the end of the array",
+				`---
+list: [a, b]
+`,
+				`---
+- op: replace
+  path: /list
+  range: "1:3"
+  value: [x]
+`,
+			),
+			Entry("a replace operation with a range whose end precedes its start",
+				`---
+list: [a, b, c]
+`,
+				`---
+- op: replace
+  path: /list
+  range: "2:1"
+  value: [x]
+`,
+			),
+			Entry("a replace operation with a range for a path that is not an array",
+				`---
+a: {b: c}
+`,
+				`---
+- op: replace
+  path: /a
+  range: "0:1"
+  value: [x]
+`,
+			),
+			Entry("a replace operation with a range and a value that is not an array",
+				`---
+list: [a, b]
+`,
+				`---
+- op: replace
+  path: /list
+  range: "0:1"
+  value: x
+`,
+			),
+			Entry("an operation none of whose fallback paths are found",
+				`---
+a: 1
+`,
+				`---
+- op: remove
+  path: /b
+  fallback: [/c, /d]
+`,
+			),
+			Entry("an operation whose path has a type mismatch before its fallback",
+				`---
+a: 1
+b: {c: 2}
+`,
+				`---
+- op: replace
+  path: /a/c
+  fallback: [/b/c]
+  value: 3
+`,
+			),
+			Entry("an add operation appending within a path beneath a scalar",
+				`---
+a: {b: c}
+`,
+				`---
+- op: add
+  path: /a/b/-/d
+  value: 1
+`,
+			),
+			Entry("an add operation with a key for a path that is not an array",
+				`---
+env: {name: a}
+`,
+				`---
+- op: add
+  path: /env
+  key: name
+  value: {name: a}
+`,
+			),
+			Entry("an add operation with a key and a value missing the key",
+				`---
+env: [{name: a}]
+`,
+				`---
+- op: add
+  path: /env
+  key: name
+  value: {value: a}
+`,
+			),
+			Entry("a replace operation on an array with an invalid path",
+				`---
+name:
+  foo:
+    bat
+  qux:
+    bum
+`,
+				`---
+- op: replace
+  path: /foo/2
+  value: bum
+`,
+			),
+		)
+	})
+
+	Describe("ApplyWithOptions", func() {
+		var patch yamlpatch.Patch
+
+		BeforeEach(func() {
+			var err error
+			patch, err = yamlpatch.DecodePatch([]byte(`---
+- op: add
+  path: /b/a10
+  value: waldo
+`))
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("calls OnOperation after each operation, including a failing one", func() {
+			patch, err := yamlpatch.DecodePatch([]byte(`---
+- op: add
+  path: /a
+  value: 1
+- op: add
+  path: /b
+  value: 2
+  priority: -1
+- op: remove
+  path: /missing
+- op: add
+  path: /c
+  value: 3
+`))
+			Expect(err).NotTo(HaveOccurred())
+
+			var indices []int
+			var errs []error
+			_, err = patch.ApplyWithOptions([]byte(`{}`), yamlpatch.ApplyOptions{
+				OnOperation: func(index int, op yamlpatch.Operation, err error) {
+					Expect(op).To(Equal(patch[index]))
+					indices = append(indices, index)
+					errs = append(errs, err)
+				},
+			})
+			Expect(err).To(HaveOccurred())
+
+			Expect(indices).To(Equal([]int{1, 0, 2}))
+			Expect(errs[0]).NotTo(HaveOccurred())
+			Expect(errs[1]).NotTo(HaveOccurred())
+			Expect(errs[2]).To(Equal(err))
+		})
+
+		It("calls BeforeOperation before each operation and OnOperation after it", func() {
+			patch, err := yamlpatch.DecodePatch([]byte(`---
+- op: add
+  path: /a
+  value: 1
+- op: remove
+  path: /missing
+- op: add
+  path: /c
+  value: 3
+`))
+			Expect(err).NotTo(HaveOccurred())
+
+			var steps []string
+			_, err = patch.ApplyWithOptions([]byte(`{}`), yamlpatch.ApplyOptions{
+				BeforeOperation: func(index int, op yamlpatch.Operation) {
+					Expect(op).To(Equal(patch[index]))
+					steps = append(steps, fmt.Sprintf("before %d", index))
+				},
+				OnOperation: func(index int, op yamlpatch.Operation, err error) {
+					steps = append(steps, fmt.Sprintf("after %d", index))
+				},
+			})
+			Expect(err).To(HaveOccurred())
+
+			Expect(steps).To(Equal([]string{"before 0", "after 0", "before 1", "after 1"}))
+		})
+
+		It("rejects documents nested more deeply than MaxDepth", func() {
+			_, err := patch.ApplyWithOptions([]byte(`{a: {b: {c: [d]}}}`), yamlpatch.ApplyOptions{MaxDepth: 3})
+			Expect(err).To(MatchError("document exceeds the maximum depth of 3 at /a/b/c"))
+
+			_, err = patch.ApplyWithOptions([]byte(`{b: {c: [d]}}`), yamlpatch.ApplyOptions{MaxDepth: 3})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("fails cleanly for documents nested thousands of levels deep", func() {
+			deep := strings.Repeat("[", 5000) + strings.Repeat("]", 5000)
+
+			_, err := patch.ApplyWithOptions([]byte(deep), yamlpatch.ApplyOptions{MaxDepth: 1000})
+			Expect(err).To(MatchError(HavePrefix("document exceeds the maximum depth of 1000 at /0/0/0/")))
+
+			deeper := strings.Repeat("[", 20000) + strings.Repeat("]", 20000)
+
+			_, err = patch.Apply([]byte(deeper))
+			Expect(err).To(MatchError(ContainSubstring("exceeded max depth of 10000")))
+		})
+
+		It("rejects operations that would nest the document more deeply than MaxDepth", func() {
+			opts := yamlpatch.ApplyOptions{MaxDepth: 3}
+
+			_, err := yamlpatch.Patch{{Op: "add", Path: "/a/b", Value: nodeOf(map[interface{}]interface{}{"c": []interface{}{"d"}})}}.ApplyWithOptions([]byte(`{a: {}}`), opts)
+			Expect(err).To(MatchError("yamlpatch add operation does not apply: value exceeds the maximum depth of 3 at /a/b/c"))
+
+			_, err = yamlpatch.Patch{{Op: "copy", From: "/a", Path: "/a/b/c"}}.ApplyWithOptions([]byte(`{a: {b: {}}}`), opts)
+			Expect(err).To(MatchError("yamlpatch copy operation does not apply: value exceeds the maximum depth of 3 at /a/b/c"))
+
+			_, err = yamlpatch.Patch{{Op: "unflatten", Path: ""}}.ApplyWithOptions([]byte(`{a.b.c.d: 1}`), opts)
+			Expect(err).To(MatchError("document exceeds the maximum depth of 3 at /a/b/c"))
+		})
+
+		It("preserves directives and an explicit end marker", func() {
+			actual, err := patch.ApplyWithOptions([]byte(`%YAML 1.1
+---
+b: {}
+...
+`), yamlpatch.ApplyOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(actual)).To(Equal(`%YAML 1.1
+---
+b:
+  a10: waldo
+...
+`))
+		})
+
+		It("strips directives when StripDirectives is set", func() {
+			actual, err := patch.ApplyWithOptions([]byte("%YAML 1.1\n%TAG !e! tag:example.com,2000:\n---\nb: {}\n"), yamlpatch.ApplyOptions{StripDirectives: true})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(actual)).To(Equal("b:\n  a10: waldo\n"))
+		})
+
+		It("decodes a document beginning with a byte order mark", func() {
+			actual, err := patch.ApplyWithOptions([]byte("\ufeffb: {}\n"), yamlpatch.ApplyOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(actual)).To(Equal("b:\n  a10: waldo\n"))
+		})
+
+		It("preserves the directives of a document beginning with a byte order mark", func() {
+			actual, err := patch.ApplyWithOptions([]byte("\ufeff%YAML 1.1\n---\nb: {}\n"), yamlpatch.ApplyOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(actual)).To(Equal("%YAML 1.1\n---\nb:\n  a10: waldo\n"))
+		})
+
+		It("double-quotes every string value when QuoteStrings is set", func() {
+			actual, err := patch.ApplyWithOptions([]byte(`---
+b:
+  enabled: true
+  count: 3
+  ratio: 0.5
+  empty: ~
+  text: |
+    two
+    lines
+list: [a, "1"]
+nested: [[b], {key: [c, "two\nlines"]}]
+`), yamlpatch.ApplyOptions{QuoteStrings: true, SortKeys: true})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(actual)).To(Equal(`b:
+  a10: "waldo"
+  count: 3
+  empty: null
+  enabled: true
+  ratio: 0.5
+  text: "two\nlines\n"
+list:
+- "a"
+- "1"
+nested:
+- - "b"
+- key:
+  - "c"
+  - "two\nlines"
+`))
+		})
+
+		Describe("QuoteKeys", func() {
+			doc := []byte("app.kubernetes.io/name: web\nreplicas: 3\n1: one\ntrue: yes\nlist: [a]\n")
+
+			It("double-quotes every string key with QuoteAllKeys", func() {
+				actual, err := yamlpatch.Patch{}.ApplyWithOptions(doc, yamlpatch.ApplyOptions{QuoteKeys: yamlpatch.QuoteAllKeys})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(actual)).To(Equal(`true: true
+1: one
+"app.kubernetes.io/name": web
+"list":
+- a
+"replicas": 3
+`))
+			})
+
+			It("double-quotes only the keys that are not identifiers with QuoteNonIdentifierKeys", func() {
+				actual, err := yamlpatch.Patch{}.ApplyWithOptions(doc, yamlpatch.ApplyOptions{QuoteKeys: yamlpatch.QuoteNonIdentifierKeys})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(actual)).To(Equal(`true: true
+1: one
+"app.kubernetes.io/name": web
+list:
+- a
+replicas: 3
+`))
+			})
+
+			It("leaves keys unquoted by default", func() {
+				actual, err := yamlpatch.Patch{}.ApplyWithOptions(doc, yamlpatch.ApplyOptions{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(actual)).To(Equal("true: true\n1: one\napp.kubernetes.io/name: web\nlist:\n- a\nreplicas: 3\n"))
+			})
+
+			It("rejects unknown quoting", func() {
+				_, err := yamlpatch.Patch{}.ApplyWithOptions(doc, yamlpatch.ApplyOptions{QuoteKeys: "some"})
+				Expect(err).To(MatchError("unsupported key quoting some: only all or non-identifier is supported"))
+			})
+		})
+
+		Describe("NoClobber", func() {
+			apply := func(ops string) (string, error) {
+				patch, err := yamlpatch.DecodePatch([]byte(ops))
+				Expect(err).NotTo(HaveOccurred())
+
+				actual, err := patch.ApplyWithOptions([]byte("a: 1\nlist: [1]\n"), yamlpatch.ApplyOptions{NoClobber: true})
+				return string(actual), err
+			}
+
+			DescribeTable("performs operations that only create values",
+				func(ops, expected string) {
+					actual, err := apply(ops)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(actual).To(Equal(expected))
+				},
+				Entry("adding an absent key", `[{op: add, path: /b, value: 2}]`, "a: 1\nb: 2\nlist:\n- 1\n"),
+				Entry("inserting into an array", `[{op: add, path: /list/0, value: 0}, {op: add, path: /list/-, value: 2}]`, "a: 1\nlist:\n- 0\n- 1\n- 2\n"),
+				Entry("copying to an absent key", `[{op: copy, from: /a, path: /b}]`, "a: 1\nb: 1\nlist:\n- 1\n"),
+				Entry("skipping an existing key", `[{op: add, path: /a, value: 2, on_existing: skip}]`, "a: 1\nlist:\n- 1\n"),
+			)
+
+			DescribeTable("fails operations that would overwrite an existing key",
+				func(ops, message string) {
+					_, err := apply(ops)
+					Expect(err).To(MatchError(message))
+				},
+				Entry("for add", `[{op: add, path: /a, value: 2}]`, "yamlpatch add operation does not apply: NoClobber does not allow overwriting existing key: /a"),
+				Entry("for copy", `[{op: copy, from: /list, path: /a}]`, "yamlpatch copy operation does not apply: NoClobber does not allow overwriting existing key: /a"),
+				Entry("for move", `[{op: move, from: /list, path: /a}]`, "yamlpatch move operation does not apply: NoClobber does not allow overwriting existing key: /a"),
+				Entry("for a rename that overwrites", `[{op: rename, from: /list, path: /a, overwrite: true}]`, "yamlpatch rename operation does not apply: NoClobber does not allow overwriting existing key: /a"),
+			)
+
+			It("fails a patch with a replace operation before performing any operation", func() {
+				_, err := apply(`[{op: add, path: /b, value: 2}, {op: block, operations: [{op: replace, path: /b, value: 3}]}]`)
+				Expect(err).To(MatchError("operation 1: operation 0: yamlpatch replace operation cannot be applied with NoClobber: it always overwrites a value: /b"))
+			})
+		})
+
+		Describe("NoLineWrap", func() {
+			long := strings.Repeat("word ", 20) + "end"
+
+			It("wraps long strings at go-yaml's default width without it", func() {
+				actual, err := yamlpatch.Patch{}.ApplyWithOptions([]byte("token: "+long+"\n"), yamlpatch.ApplyOptions{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(strings.Count(string(actual), "\n")).To(BeNumerically(">", 1))
+			})
+
+			It("leaves long strings on a single line", func() {
+				actual, err := yamlpatch.Patch{}.ApplyWithOptions([]byte("token: "+long+"\nlist: [a]\n"), yamlpatch.ApplyOptions{NoLineWrap: true})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(actual)).To(Equal("list:\n- a\ntoken: " + long + "\n"))
+			})
+
+			It("leaves long quoted strings within sequences on a single line", func() {
+				doc := "list:\n- name: x\n  value: '" + long + " #'\n- \"" + long + "\\ttab\"\n- literal: |\n    " + long + "\n    line\n"
+				actual, err := yamlpatch.Patch{}.ApplyWithOptions([]byte(doc), yamlpatch.ApplyOptions{NoLineWrap: true})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(actual)).To(Equal("list:\n- name: x\n  value: '" + long + " #'\n- \"" + long + "\\ttab\"\n- literal: |\n    " + long + "\n    line\n"))
+			})
+		})
+
+		It("omits empty maps and sequences when OmitEmpty is set", func() {
+			actual, err := patch.ApplyWithOptions([]byte(`---
+b: {}
+labels: {}
+list: [[], {}, x]
+nested:
+  inner:
+    none: []
+`), yamlpatch.ApplyOptions{OmitEmpty: true})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(actual)).To(Equal(`b:
+  a10: waldo
+list:
+- x
+`))
+		})
+
+		It("emits the root when OmitEmpty is set and the document is empty", func() {
+			actual, err := yamlpatch.Patch{}.ApplyWithOptions([]byte("a: {}\n"), yamlpatch.ApplyOptions{OmitEmpty: true})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(actual)).To(Equal("{}\n"))
+		})
+
+		It("sorts map keys lexically and recursively when SortKeys is set", func() {
+			doc := []byte(`---
+b:
+  a2: fred
+list:
+- z: 1
+  x: 2
+a: [c, b]
+`)
+
+			actual, err := patch.ApplyWithOptions(doc, yamlpatch.ApplyOptions{SortKeys: true})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(actual)).To(Equal(`a:
+- c
+- b
+b:
+  a10: waldo
+  a2: fred
+list:
+- x: 2
+  z: 1
+`))
+		})
+	})
+
+	Describe("ApplyWithOptions with CaseInsensitiveKeys", func() {
+		opts := yamlpatch.ApplyOptions{CaseInsensitiveKeys: true}
+
+		It("matches keys regardless of case", func() {
+			patch, err := yamlpatch.DecodePatch([]byte(`---
+- op: replace
+  path: /metadata/name
+  value: web
+- op: add
+  path: /METADATA/labels/tier
+  value: frontend
+- op: copy
+  from: /Metadata/NAME
+  path: /metadata/labels/app
+- op: rename
+  from: /metadata/labels/Owner
+  path: /metadata/labels/owner
+`))
+			Expect(err).NotTo(HaveOccurred())
+
+			actual, err := patch.ApplyWithOptions([]byte(`{Metadata: {Name: app, Labels: {Owner: core}}}`), opts)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(actual).To(MatchYAML(`{Metadata: {Name: web, Labels: {tier: frontend, app: web, owner: core}}}`))
+		})
+
+		It("fails for a path that matches keys differing only in case", func() {
+			patch, err := yamlpatch.DecodePatch([]byte(`[{op: replace, path: /name, value: web}]`))
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = patch.ApplyWithOptions([]byte(`{Name: a, name: b}`), opts)
+			Expect(err).To(MatchError(ContainSubstring("path /name matches more than one key: Name, name")))
+		})
+
+		It("matches keys exactly by default", func() {
+			patch, err := yamlpatch.DecodePatch([]byte(`[{op: replace, path: /name, value: web}]`))
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = patch.ApplyWithOptions([]byte(`{Name: app}`), yamlpatch.ApplyOptions{})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("ApplyPartial", func() {
+		It("returns the document as of just before the failing operation", func() {
+			patch, err := yamlpatch.DecodePatch([]byte(`---
+- op: add
+  path: /a
+  value: 1
+- op: move
+  from: /b
+  path: /missing/b
+- op: add
+  path: /c
+  value: 3
+`))
+			Expect(err).NotTo(HaveOccurred())
+
+			partial, index, err := patch.ApplyPartial([]byte(`{b: 2}`), yamlpatch.ApplyOptions{})
+			Expect(err).To(HaveOccurred())
+			Expect(index).To(Equal(1))
+			Expect(partial).To(MatchYAML(`{a: 1, b: 2}`))
+		})
+
+		It("returns the patched document and an index of -1 on success", func() {
+			patch, err := yamlpatch.DecodePatch([]byte(`[{op: add, path: /a, value: 1}]`))
+			Expect(err).NotTo(HaveOccurred())
+
+			actual, index, err := patch.ApplyPartial([]byte(`{b: 2}`), yamlpatch.ApplyOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(index).To(Equal(-1))
+			Expect(actual).To(MatchYAML(`{a: 1, b: 2}`))
+		})
+
+		It("returns no document and an index of -1 when the document cannot be decoded", func() {
+			patch, err := yamlpatch.DecodePatch([]byte(`[{op: add, path: /a, value: 1}]`))
+			Expect(err).NotTo(HaveOccurred())
+
+			actual, index, err := patch.ApplyPartial([]byte(`{b: [`), yamlpatch.ApplyOptions{})
+			Expect(err).To(HaveOccurred())
+			Expect(index).To(Equal(-1))
+			Expect(actual).To(BeNil())
+		})
+	})
+
+	Describe("Apply with explicit tags", func() {
+		doc := []byte(`---
+created: !!timestamp 2001-12-14T21:59:43.10-05:00
+version: !!str 123
+ratio: !!float 1
+logo: !!binary aGVsbG8=
+owner: !!null ~
+name: app
+`)
+
+		It("keeps the tags of untouched scalars", func() {
+			patch, err := yamlpatch.DecodePatch([]byte(`[{op: replace, path: /name, value: web}]`))
+			Expect(err).NotTo(HaveOccurred())
+
+			actual, err := patch.Apply(doc)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(actual)).To(Equal(`created: !!timestamp 2001-12-14T21:59:43.10-05:00
+logo: !!binary aGVsbG8=
+name: web
+owner: !!null ~
+ratio: !!float 1
+version: !!str 123
+`))
+		})
+
+		It("leaves the rest of the document as go-yaml emits it", func() {
+			long := strings.Repeat("word ", 20) + "end"
+			patch, err := yamlpatch.DecodePatch([]byte(`[{op: add, path: /list/-, value: "yes"}]`))
+			Expect(err).NotTo(HaveOccurred())
+
+			actual, err := patch.Apply([]byte("avatar: !!binary |\n  aGVsbG8gd29ybGQg\n  aGVsbG8=\nlist: [a]\ntext: " + long + "\n"))
+			Expect(err).NotTo(HaveOccurred())
+
+			expected, err := yamlpatch.Patch{}.Apply([]byte("list: [a, \"yes\"]\ntext: " + long + "\n"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(actual)).To(Equal("avatar: !!binary |\n  aGVsbG8gd29ybGQg\n  aGVsbG8=\n" + string(expected)))
+		})
+
+		It("replaces a tagged scalar", func() {
+			patch, err := yamlpatch.DecodePatch([]byte(`[{op: replace, path: /created, value: now}, {op: replace, path: /version, value: 124}]`))
+			Expect(err).NotTo(HaveOccurred())
+
+			actual, err := patch.Apply(doc)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(actual)).To(ContainSubstring("created: now\n"))
+			Expect(string(actual)).To(ContainSubstring("version: 124\n"))
+		})
+
+		It("tests a tagged scalar by its value", func() {
+			patch, err := yamlpatch.DecodePatch([]byte(`[{op: test, path: /version, value: "123"}, {op: test, path: /ratio, value: 1.0}]`))
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = patch.Apply(doc)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Describe("type mismatches", func() {
+		DescribeTable("naming the path that cannot be descended into",
+			func(ops, message string) {
+				patch, err := yamlpatch.DecodePatch([]byte(ops))
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = patch.Apply([]byte(`---
+spec:
+  containers: web
+  ports: [80]
+`))
+				Expect(err).To(MatchError(message))
+			},
+			Entry("a scalar along the path",
+				`[{op: replace, path: /spec/containers/0/image, value: app}]`,
+				"yamlpatch replace operation does not apply: doc is missing path: /spec/containers/0/image: cannot descend into /spec/containers: expected map or array but found string",
+			),
+			Entry("a scalar holding the key",
+				`[{op: add, path: /spec/containers/image, value: app}]`,
+				"yamlpatch add operation does not apply: doc is missing path: /spec/containers/image: cannot descend into /spec/containers: expected map or array but found string",
+			),
+			Entry("a key within an array",
+				`[{op: remove, path: /spec/ports/name/value}]`,
+				"yamlpatch remove operation does not apply: doc is missing path: /spec/ports/name/value: cannot descend into /spec/ports: expected map but found array",
+			),
+		)
+	})
+
+	Describe("AppendToken", func() {
+		It("appends to arrays with the given token instead of -", func() {
+			patch, err := yamlpatch.DecodePatch([]byte(`---
+- op: add
+  path: /items/+
+  value: c
+- op: copy
+  from: /items/0
+  path: /items/+
+- op: add
+  path: /lists/+/+
+  value: x
+`))
+			Expect(err).NotTo(HaveOccurred())
+
+			actual, err := patch.ApplyWithOptions([]byte("items: [a, b]\nlists: []\n"), yamlpatch.ApplyOptions{AppendToken: "+"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(actual)).To(Equal("items:\n- a\n- b\n- c\n- a\nlists:\n- - x\n"))
+		})
+
+		It("treats the token as a key of maps", func() {
+			patch, err := yamlpatch.DecodePatch([]byte(`---
+- op: replace
+  path: /range/end
+  value: 9
+- op: add
+  path: /items/end
+  value: c
+`))
+			Expect(err).NotTo(HaveOccurred())
+
+			actual, err := patch.ApplyWithOptions([]byte("range: {start: 1, end: 5}\nitems: [a]\n"), yamlpatch.ApplyOptions{AppendToken: "end"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(actual)).To(Equal("items:\n- a\n- c\nrange:\n  end: 9\n  start: 1\n"))
+		})
+
+		It("appends with the token in paths relative to a scope or for_each", func() {
+			patch, err := yamlpatch.DecodePatch([]byte(`---
+- op: add
+  scope: /kind=List
+  path: /items/+
+  value: c
+- op: add
+  for_each: /lists
+  path: /lists/{{index}}/+
+  value: x
+`))
+			Expect(err).NotTo(HaveOccurred())
+
+			actual, err := patch.ApplyWithOptions([]byte("kind: List\nitems: [a]\nlists: [[], [w]]\n"), yamlpatch.ApplyOptions{AppendToken: "+"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(actual)).To(Equal("items:\n- a\n- c\nkind: List\nlists:\n- - x\n- - w\n  - x\n"))
+		})
+
+		It("appends with the token in pointers using key=value syntax", func() {
+			patch := yamlpatch.Patch{{Op: "add", Path: "/lists/name=a/items/+", Value: nodeOf("c")}}
+
+			actual, err := patch.ApplyWithOptions([]byte("lists: [{name: a, items: [b]}]\n"), yamlpatch.ApplyOptions{AppendToken: "+"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(actual)).To(Equal("lists:\n- items:\n  - b\n  - c\n  name: a\n"))
+		})
+
+		It("rejects tokens containing a slash", func() {
+			patch := yamlpatch.Patch{{Op: "add", Path: "/items/-", Value: nodeOf(1)}}
+
+			_, err := patch.ApplyWithOptions([]byte("items: []\n"), yamlpatch.ApplyOptions{AppendToken: "a/b"})
+			Expect(err).To(MatchError("yamlpatch add operation does not apply: append token cannot contain '/': a/b"))
+		})
+	})
+
+	Describe("RejectNonFinite", func() {
+		It("fails operations whose value contains NaN or an infinity", func() {
+			patch, err := yamlpatch.DecodePatch([]byte(`[{op: add, path: /limits, value: {cpu: 1, ratio: [0.5, -.inf]}}]`))
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = patch.ApplyWithOptions([]byte("a: 1\n"), yamlpatch.ApplyOptions{RejectNonFinite: true})
+			Expect(err).To(MatchError("yamlpatch add operation does not apply: value is not a finite number: -Inf at /limits/ratio/1"))
+		})
+
+		It("checks values referring to the document", func() {
+			patch, err := yamlpatch.DecodePatch([]byte(`[{op: replace, path: /b, value: "{{path:/a}}"}]`))
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = patch.ApplyWithOptions([]byte("a: .nan\nb: 1\n"), yamlpatch.ApplyOptions{RejectNonFinite: true})
+			Expect(err).To(MatchError("yamlpatch replace operation does not apply: value is not a finite number: NaN at /b"))
+		})
+
+		It("allows non-finite values without the option", func() {
+			patch, err := yamlpatch.DecodePatch([]byte(`[{op: add, path: /b, value: .inf}]`))
+			Expect(err).NotTo(HaveOccurred())
+
+			actual, err := patch.Apply([]byte("a: 1\n"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(actual)).To(Equal("a: 1\nb: .inf\n"))
+		})
+	})
+
+	Describe("MaxOutputBytes", func() {
+		patch := yamlpatch.Patch{{Op: "add", Path: "/b", Value: nodeOf("hello")}}
+
+		It("fails when the output is larger than the limit", func() {
+			_, err := patch.ApplyWithOptions([]byte("a: 1\n"), yamlpatch.ApplyOptions{MaxOutputBytes: 10})
+			Expect(err).To(MatchError("output of 14 bytes exceeds the maximum of 10 bytes"))
+
+			sizeErr, ok := err.(*yamlpatch.OutputSizeError)
+			Expect(ok).To(BeTrue())
+			Expect(sizeErr.Size).To(Equal(14))
+			Expect(sizeErr.Limit).To(Equal(10))
+		})
+
+		It("allows an output of exactly the limit", func() {
+			actual, err := patch.ApplyWithOptions([]byte("a: 1\n"), yamlpatch.ApplyOptions{MaxOutputBytes: 14})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(actual)).To(Equal("a: 1\nb: hello\n"))
+		})
+
+		It("limits the whole of a stream", func() {
+			stream := []byte("a: 1\n---\na: 2\n")
+
+			_, err := patch.ApplyStreamWithOptions(stream, yamlpatch.ApplyOptions{MaxOutputBytes: 20})
+			Expect(err).To(BeAssignableToTypeOf(&yamlpatch.OutputSizeError{}))
+		})
+	})
+
+	Describe("PreserveBlankLines", func() {
+		opts := yamlpatch.ApplyOptions{PreserveBlankLines: true}
+
+		It("keeps blank lines between top-level keys whose order is unchanged", func() {
+			patch := yamlpatch.Patch{{Op: "replace", Path: "/e", Value: nodeOf(5)}}
+
+			actual, err := patch.ApplyWithOptions([]byte(`---
+a: 1
+
+b:
+  c: 2
+
+# section
+d: 3
+e: 4
+`), opts)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(actual)).To(Equal("a: 1\n\nb:\n  c: 2\n\nd: 3\ne: 5\n"))
+		})
+
+		It("drops blank lines whose neighbouring keys have changed", func() {
+			actual, err := yamlpatch.Patch{{Op: "add", Path: "/b", Value: nodeOf(2)}}.ApplyWithOptions([]byte("a: 1\n\nc: 3\n"), opts)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(actual)).To(Equal("a: 1\nb: 2\nc: 3\n"))
+
+			actual, err = yamlpatch.Patch{}.ApplyWithOptions([]byte("z: 1\n\na: 2\n"), opts)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(actual)).To(Equal("a: 2\nz: 1\n"))
+		})
+	})
+
+	Describe("Validate", func() {
+		doc := []byte("spec:\n  replicas: 1\n  ports: [80]\n")
+
+		It("returns nothing for a patch that applies", func() {
+			patch, err := yamlpatch.DecodePatch([]byte(`---
+- {op: add, path: /spec/template, value: {}}
+- {op: add, path: /spec/template/image, value: web}
+- {op: replace, path: /spec/replicas, value: 3}
+`))
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(patch.Validate(doc)).To(BeEmpty())
+		})
+
+		It("returns every problem without modifying the document", func() {
+			patch := yamlpatch.Patch{
+				{Op: "replace", Path: "/spec/missing", Value: nodeOf(1)},
+				{Op: "add", Path: "/spec/ports/name", Value: nodeOf("http")},
+				{Op: "replace", Path: "/spec/replicas", Value: nodeOf(3)},
+				{Op: "remove", Path: "spec"},
+				{Op: "test", Path: "/spec/replicas", Value: nodeOf(1)},
+			}
+
+			errs := patch.Validate(doc)
+			Expect(errs).To(HaveLen(4))
+
+			var indices []int
+			for _, err := range errs {
+				applyErr, ok := err.(*yamlpatch.ApplyError)
+				Expect(ok).To(BeTrue())
+				indices = append(indices, applyErr.Operation)
+			}
+			Expect(indices).To(Equal([]int{0, 1, 3, 4}))
+
+			Expect(errs[3]).To(MatchError("operation 4: test failed"))
+			Expect(string(doc)).To(Equal("spec:\n  replicas: 1\n  ports: [80]\n"))
+		})
+
+		It("does not modify the patch", func() {
+			patch, err := yamlpatch.DecodePatch([]byte(`[{op: add, path: [/a, /b], value: [x]}, {op: add, path: /a/-, value: z}]`))
+			Expect(err).NotTo(HaveOccurred())
+
+			before, err := yaml.Marshal(patch)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(patch.Validate([]byte("{}\n"))).To(BeEmpty())
+
+			after, err := yaml.Marshal(patch)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(after)).To(Equal(string(before)))
+		})
+
+		It("returns only the error for a document that cannot be decoded", func() {
+			errs := yamlpatch.Patch{{Op: "remove", Path: "/a"}}.Validate([]byte("a: [b"))
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0]).To(MatchError(HavePrefix("failed unmarshaling doc")))
+		})
+	})
+
+	Describe("on_existing", func() {
+		apply := func(ops string) (string, error) {
+			patch, err := yamlpatch.DecodePatch([]byte(ops))
+			Expect(err).NotTo(HaveOccurred())
+
+			actual, err := patch.Apply([]byte("a: 1\nb: [x]\n"))
+			return string(actual), err
+		}
+
+		DescribeTable("controls an add operation whose path is an existing key",
+			func(ops, expected string) {
+				actual, err := apply(ops)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(actual).To(Equal(expected))
+			},
+			Entry("replacing it by default", `[{op: add, path: /a, value: 2}]`, "a: 2\nb:\n- x\n"),
+			Entry("replacing it", `[{op: add, path: /a, value: 2, on_existing: replace}]`, "a: 2\nb:\n- x\n"),
+			Entry("skipping it", `[{op: add, path: /a, value: 2, on_existing: skip}]`, "a: 1\nb:\n- x\n"),
+			Entry("adding keys that are missing", `[{op: add, path: /c, value: 2, on_existing: error}]`, "a: 1\nb:\n- x\nc: 2\n"),
+			Entry("inserting into arrays", `[{op: add, path: /b/0, value: w, on_existing: error}]`, "a: 1\nb:\n- w\n- x\n"),
+		)
+
+		It("fails for an existing key with error", func() {
+			_, err := apply(`[{op: add, path: /a, value: 2, on_existing: error}]`)
+			Expect(err).To(MatchError("yamlpatch add operation does not apply: doc already has key: /a"))
+		})
+
+		DescribeTable("is rejected when decoding",
+			func(ops, message string) {
+				_, err := yamlpatch.DecodePatch([]byte(ops))
+				Expect(err).To(MatchError(message))
+			},
+			Entry("with an unknown value", `[{op: add, path: /a, value: 1, on_existing: merge}]`, "yamlpatch add operation has an invalid on_existing: merge"),
+			Entry("for an op other than add", `[{op: replace, path: /a, value: 1, on_existing: skip}]`, "yamlpatch replace operation cannot have on_existing: only add operations can"),
+		)
+	})
+
+	Describe("ApplyPatches", func() {
+		It("applies each patch in turn, ordering each by its own priorities", func() {
+			actual, err := yamlpatch.ApplyPatches([]byte("items: []\n"), []yamlpatch.Patch{
+				{{Op: "add", Path: "/items/-", Value: nodeOf("b")}, {Op: "add", Path: "/items/-", Value: nodeOf("a"), Priority: -1}},
+				{{Op: "add", Path: "/items/-", Value: nodeOf("c")}},
+			}, yamlpatch.ApplyOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(actual)).To(Equal("items:\n- a\n- b\n- c\n"))
+		})
+
+		It("returns the error of the first failing patch", func() {
+			_, err := yamlpatch.ApplyPatches([]byte("a: 1\n"), []yamlpatch.Patch{
+				{{Op: "remove", Path: "/a"}},
+				{{Op: "remove", Path: "/a"}},
+			}, yamlpatch.ApplyOptions{})
+			Expect(err).To(MatchError("Unable to remove nonexistent key: a"))
+		})
+
+		It("counts the operations of best-effort errors across the patches", func() {
+			actual, err := yamlpatch.ApplyPatches([]byte("a: 1\n"), []yamlpatch.Patch{
+				{{Op: "add", Path: "/b", Value: nodeOf(2)}, {Op: "remove", Path: "/missing"}},
+				{{Op: "remove", Path: "/missing"}},
+			}, yamlpatch.ApplyOptions{BestEffort: true})
+			Expect(string(actual)).To(Equal("a: 1\nb: 2\n"))
+
+			multi, ok := err.(*yamlpatch.MultiError)
+			Expect(ok).To(BeTrue())
+
+			var indices []int
+			for _, e := range multi.Errors() {
+				indices = append(indices, e.(*yamlpatch.ApplyError).Operation)
+			}
+			Expect(indices).To(Equal([]int{1, 2}))
+		})
+	})
+
+	It("gives the same result when a patch is applied more than once", func() {
+		patch, err := yamlpatch.DecodePatch([]byte(`[{op: add, path: [/a, /b], value: [x]}, {op: add, path: /a/-, value: z}]`))
+		Expect(err).NotTo(HaveOccurred())
+
+		for i := 0; i < 2; i++ {
+			actual, err := patch.Apply([]byte("{}\n"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(actual).To(MatchYAML(`{a: [x, z], b: [x]}`))
+		}
+	})
+
+	DescribeTable("IsReadOnly",
+		func(ops string, expected bool) {
+			patch, err := yamlpatch.DecodePatch([]byte(ops))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(patch.IsReadOnly()).To(Equal(expected))
+		},
+		Entry("for an empty patch", `[]`, true),
+		Entry("for test operations", `[{op: test, path: /a, value: 1}, {op: test, path: /b, value_type: string}]`, true),
+		Entry("for a capture operation", `[{op: capture, path: /a, as: a}, {op: test, path: /b, value: "{{var:a}}"}]`, true),
+		Entry("for a block of test operations", `[{op: block, operations: [{op: test, path: /a, value: 1}]}]`, true),
+		Entry("ignoring disabled operations", `[{op: test, path: /a, value: 1}, {op: remove, path: /a, disabled: true}]`, true),
+		Entry("for a patch that adds a value", `[{op: test, path: /a, value: 1}, {op: add, path: /b, value: 2}]`, false),
+		Entry("for a comment operation, which changes the output", `[{op: comment, path: /a, value: note}]`, false),
+		Entry("for a block that removes a value", `[{op: block, operations: [{op: remove, path: /a}]}]`, false),
+		Entry("for an embedded patch", `[{op: embedded, path: /a, operations: [{op: test, path: /b, value: 1}]}]`, false),
+	)
+
+	Describe("ValidatePath", func() {
+		DescribeTable("accepting well-formed paths",
+			func(path string) {
+				Expect(yamlpatch.ValidatePath(path)).To(Succeed())
+			},
+			Entry("the empty path", ""),
+			Entry("a path to a key", "/a/b"),
+			Entry("an escaped path", "/a~1b/c~0d"),
+			Entry("the end of an array", "/a/-"),
+			Entry("appends to nested arrays", "/a/-/b/-"),
+			Entry("a key=value path", "/items/name=web/image"),
+			Entry("a recursive search", "//image[1]"),
+		)
+
+		DescribeTable("rejecting malformed paths",
+			func(path, message string) {
+				Expect(yamlpatch.ValidatePath(path)).To(MatchError(message))
+			},
+			Entry("a path without a leading slash", "a/b", "path a/b is missing leading '/'"),
+			Entry("a path with an empty segment", "/a//b", "path /a//b has an empty segment at position 1"),
+			Entry("a path with a trailing slash", "/a/", "path /a/ has an empty segment at position 1"),
+			Entry("a path with an invalid escape", "/a~2", "path /a~2 has an invalid escape '~2' in segment 0"),
+			Entry("a path ending in '~'", "/a/b~", "path /a/b~ has an unescaped '~' at the end of segment 1"),
+		)
+
+		It("is used by DecodePatch", func() {
+			_, err := yamlpatch.DecodePatch([]byte(`[{op: copy, from: /a~, path: /b}]`))
+			Expect(err).To(MatchError("yamlpatch copy operation has an invalid path: path /a~ has an unescaped '~' at the end of segment 0"))
+		})
+	})
+
+	Describe("Normalize", func() {
+		It("re-emits the document using the given options", func() {
+			actual, err := yamlpatch.Normalize([]byte(`{b: [1, 2], a10: {y2: x, y10: z}, a2: "quoted"}`), yamlpatch.ApplyOptions{SortKeys: true})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(actual)).To(Equal(`a10:
+  y10: z
+  y2: x
+a2: quoted
+b:
+- 1
+- 2
+`))
+		})
+
+		It("orders keys of different types written the same by their type when SortKeys is set", func() {
+			doc := []byte("\"1\": string\n1: int\n\"true\": string\ntrue: bool\n")
+
+			for i := 0; i < 50; i++ {
+				actual, err := yamlpatch.Normalize(doc, yamlpatch.ApplyOptions{SortKeys: true})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(actual)).To(Equal("1: int\n\"1\": string\ntrue: bool\n\"true\": string\n"))
+			}
+		})
+
+		It("does not change a document that is already normalized", func() {
+			doc := []byte(`a: 1
+b:
+- c: d
+`)
+			actual, err := yamlpatch.Normalize(doc, yamlpatch.ApplyOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(actual).To(Equal(doc))
+		})
+	})
+
+	Describe("Concat", func() {
+		It("returns the operations of both patches in order without modifying either", func() {
+			first, err := yamlpatch.DecodePatch([]byte(`[{op: add, path: /a, value: 1}, {op: capture, path: /a, as: a}]`))
+			Expect(err).NotTo(HaveOccurred())
+
+			second, err := yamlpatch.DecodePatch([]byte(`[{op: add, path: /b, value: "{{var:a}}"}]`))
+			Expect(err).NotTo(HaveOccurred())
+
+			patch := first[:1].Concat(second)
+			Expect(patch).To(HaveLen(2))
+			Expect(first[1].Op).To(Equal(yamlpatch.Op("capture")))
+
+			actual, err := first.Concat(second).Apply([]byte(`{}`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(actual).To(MatchYAML(`{a: 1, b: 1}`))
+		})
+
+		It("orders the operations of the result by priority", func() {
+			first, err := yamlpatch.DecodePatch([]byte(`[{op: add, path: /a, value: first}]`))
+			Expect(err).NotTo(HaveOccurred())
+
+			second, err := yamlpatch.DecodePatch([]byte(`[{op: add, path: /a, value: second, priority: -1}]`))
+			Expect(err).NotTo(HaveOccurred())
+
+			actual, err := yamlpatch.MergePatches(first, second).Apply([]byte(`{}`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(actual).To(MatchYAML(`{a: first}`))
+		})
+	})
+
+	Describe("Slice", func() {
+		patch := yamlpatch.Patch{
+			{Op: "add", Path: "/a"},
+			{Op: "add", Path: "/b"},
+			{Op: "add", Path: "/c"},
+		}
+
+		It("returns the operations from start up to end", func() {
+			Expect(patch.Slice(1, 3)).To(Equal(patch[1:3]))
+		})
+
+		It("clamps the bounds to the patch", func() {
+			Expect(patch.Slice(-1, 10)).To(Equal(patch))
+			Expect(patch.Slice(5, 10)).To(BeEmpty())
+			Expect(patch.Slice(2, 1)).To(BeEmpty())
+		})
+
+		It("does not share its operations with the patch", func() {
+			slice := patch.Slice(0, 1)
+			slice = append(slice, yamlpatch.Operation{Op: "remove"})
+			Expect(patch[1].Op).To(Equal(yamlpatch.Op("add")))
+		})
+	})
+
+	Describe("ParseRange", func() {
+		It("returns the bounds of the range, with -1 for an open end", func() {
+			start, end, err := yamlpatch.ParseRange("2:")
+			Expect(err).NotTo(HaveOccurred())
+			Expect([]int{start, end}).To(Equal([]int{2, -1}))
+
+			start, end, err = yamlpatch.ParseRange(":5")
+			Expect(err).NotTo(HaveOccurred())
+			Expect([]int{start, end}).To(Equal([]int{0, 5}))
+		})
+
+		It("rejects ranges that are malformed or end before they start", func() {
+			_, _, err := yamlpatch.ParseRange("3")
+			Expect(err).To(MatchError("range '3' is not in the form START:END"))
+
+			_, _, err = yamlpatch.ParseRange("3:1")
+			Expect(err).To(MatchError("range '3:1' has an invalid end"))
+		})
+	})
+
+	Describe("Reversed", func() {
+		It("returns the operations in reverse order without modifying the patch", func() {
+			patch := yamlpatch.Patch{
+				{Op: "add", Path: "/a"},
+				{Op: "add", Path: "/b"},
+				{Op: "add", Path: "/c"},
+			}
+
+			reversed := patch.Reversed()
+			Expect(reversed).To(Equal(yamlpatch.Patch{patch[2], patch[1], patch[0]}))
+			Expect(patch[0].Path).To(Equal(yamlpatch.OpPath("/a")))
+		})
+
+		It("applies order-dependent operations last to first", func() {
+			patch := yamlpatch.Patch{
+				{Op: "add", Path: "/items/0", Value: nodeOf("first")},
+				{Op: "add", Path: "/items/0", Value: nodeOf("second")},
+			}
+
+			actual, err := patch.Reversed().Apply([]byte("items: []\n"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(actual)).To(Equal("items:\n- first\n- second\n"))
+		})
+	})
+
+	Describe("DecodePatch", func() {
+		It("returns an empty patch when given nil", func() {
+			patch, err := yamlpatch.DecodePatch(nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(patch).To(HaveLen(0))
+		})
+
+		It("returns a patch with a single op when given a single op", func() {
+			ops := []byte(
+				`---
+- op: add
+  path: /baz
+  value: qux`)
 
 			patch, err := yamlpatch.DecodePatch(ops)
 			Expect(err).NotTo(HaveOccurred())
@@ -603,4 +2799,93 @@ name:
 			}))
 		})
 	})
+
+	Describe("DecodePatch with a list of paths", func() {
+		It("decodes the paths into Paths", func() {
+			patch, err := yamlpatch.DecodePatch([]byte(`[{op: remove, path: [/a, /b]}]`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(patch[0].Paths).To(Equal([]yamlpatch.OpPath{"/a", "/b"}))
+		})
+
+		DescribeTable("rejecting lists that are not allowed",
+			func(ops string) {
+				_, err := yamlpatch.DecodePatch([]byte(ops))
+				Expect(err).To(HaveOccurred())
+			},
+			Entry("a list of froms", `[{op: copy, from: [/a, /b], path: /c}]`),
+			Entry("a move to a list of paths", `[{op: move, from: /a, path: [/b, /c]}]`),
+			Entry("an empty list of paths", `[{op: remove, path: []}]`),
+			Entry("a list of non-string paths", `[{op: remove, path: [{a: b}]}]`),
+			Entry("a list of paths with fallback paths", `[{op: remove, path: [/a, /b], fallback: [/c]}]`),
+		)
+	})
+
+	It("rejects decode on an operation other than test when decoding", func() {
+		_, err := yamlpatch.DecodePatch([]byte(`[{op: add, path: /a, value: aGk=, decode: base64}]`))
+		Expect(err).To(MatchError("yamlpatch add operation cannot have decode: only test operations can"))
+	})
+
+	Describe("DecodePatch with disabled given as a reference", func() {
+		It("decodes the variable into DisabledBy", func() {
+			patch, err := yamlpatch.DecodePatch([]byte(`[{op: remove, path: /a, disabled: "{{var:legacy}}"}]`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(patch[0].Disabled).To(BeFalse())
+			Expect(patch[0].DisabledBy).To(Equal("legacy"))
+		})
+
+		DescribeTable("rejecting values that are not a bool or a variable",
+			func(ops string) {
+				_, err := yamlpatch.DecodePatch([]byte(ops))
+				Expect(err).To(HaveOccurred())
+			},
+			Entry("a string", `[{op: remove, path: /a, disabled: "sometimes"}]`),
+			Entry("a reference to a path", `[{op: remove, path: /a, disabled: "{{path:/b}}"}]`),
+			Entry("a reference within a longer string", `[{op: remove, path: /a, disabled: "not {{var:b}}"}]`),
+			Entry("a list", `[{op: remove, path: /a, disabled: [true]}]`),
+		)
+	})
+
+	Describe("DecodePatchStrict", func() {
+		It("decodes a patch with only known fields", func() {
+			patch, err := yamlpatch.DecodePatchStrict([]byte(`---
+- op: add
+  path: /baz
+  value: qux
+`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(patch).To(HaveLen(1))
+		})
+
+		It("returns an error naming the unknown field and operation index", func() {
+			_, err := yamlpatch.DecodePatchStrict([]byte(`---
+- op: add
+  path: /baz
+  value: qux
+- op: add
+  path: /waldo
+  vaule: fred
+`))
+			Expect(err).To(MatchError("operation 1: unknown field 'vaule'"))
+		})
+
+		It("returns an error naming the unknown field of a nested operation and its index path", func() {
+			_, err := yamlpatch.DecodePatchStrict([]byte(`---
+- op: add
+  path: /baz
+  value: {vaule: qux}
+- op: block
+  operations:
+  - op: add
+    path: /waldo
+    value: fred
+  - op: embedded
+    path: /config
+    operations:
+    - op: add
+      path: /waldo
+      vaule: fred
+`))
+			Expect(err).To(MatchError("operation 1: operation 1: operation 0: unknown field 'vaule'"))
+		})
+	})
 })
@@ -2,6 +2,9 @@ package yamlpatch
 
 import (
 	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -9,6 +12,10 @@ import (
 // (key=value) pointer syntax
 type PathFinder struct {
 	root Container
+
+	// appendToken is a segment other than "-" that refers to the end of the
+	// arrays it is looked up in
+	appendToken string
 }
 
 // NewPathFinder takes an interface that represents a YAML document and returns
@@ -19,9 +26,22 @@ func NewPathFinder(container Container) *PathFinder {
 	}
 }
 
+// newPathFinder returns a new PathFinder with which the given token, when it is
+// not empty, refers to the end of the arrays it is looked up in, as "-" does
+func newPathFinder(container Container, token string) *PathFinder {
+	return &PathFinder{
+		root:        container,
+		appendToken: token,
+	}
+}
+
 // Find expands the given path into all matching paths, returning the canonical
 // versions of those matching paths
 func (p *PathFinder) Find(path string) []string {
+	if strings.HasPrefix(path, "//") {
+		return p.findRecursive(path[2:])
+	}
+
 	parts := strings.Split(path, "/")
 
 	if parts[1] == "" {
@@ -33,7 +53,7 @@ func (p *PathFinder) Find(path string) []string {
 	}
 
 	for _, part := range parts[1:] {
-		routes = find(decodePatchKey(part), routes)
+		routes = p.find(decodePatchKey(part), routes)
 	}
 
 	var paths []string
@@ -44,10 +64,14 @@ func (p *PathFinder) Find(path string) []string {
 	return paths
 }
 
-func find(part string, routes map[string]Container) map[string]Container {
+func (p *PathFinder) find(part string, routes map[string]Container) map[string]Container {
 	matches := map[string]Container{}
 
 	for prefix, container := range routes {
+		if container == nil {
+			continue
+		}
+
 		if part == "-" {
 			for k := range routes {
 				matches[fmt.Sprintf("%s/-", k)] = routes[k]
@@ -55,6 +79,11 @@ func find(part string, routes map[string]Container) map[string]Container {
 			return matches
 		}
 
+		if _, ok := container.(*nodeSlice); ok && p.appendToken != "" && part == p.appendToken {
+			matches[fmt.Sprintf("%s/-", prefix)] = container
+			continue
+		}
+
 		if kv := strings.Split(part, "="); len(kv) == 2 {
 			if newMatches := findAll(prefix, kv[0], kv[1], container); len(newMatches) > 0 {
 				matches = newMatches
@@ -93,7 +122,7 @@ func findAll(prefix, findKey, findValue string, container Container) map[string]
 	switch it := container.(type) {
 	case *nodeMap:
 		for k, v := range *it {
-			for route, match := range findAll(fmt.Sprintf("%s/%s", prefix, k), findKey, findValue, v.Container()) {
+			for route, match := range findAll(fmt.Sprintf("%s/%v", prefix, k), findKey, findValue, v.Container()) {
 				matches[route] = match
 			}
 		}
@@ -107,3 +136,192 @@ func findAll(prefix, findKey, findValue string, container Container) map[string]
 
 	return matches
 }
+
+// recursiveSegmentRegex matches the first segment of a recursive search, e.g.
+// image or image[1]
+var recursiveSegmentRegex = regexp.MustCompile(`^(.+?)(?:\[(\d+)\])?$`)
+
+// findRecursive expands a recursive search such as "image[1]/name", as given
+// by the pointer //image[1]/name, which finds every member of an object with
+// the key "image" anywhere in the document, selects the one at index 1 when
+// an index is given, and resolves the remaining segments relative to it.
+//
+// Members are found depth-first in document order: the members of an object
+// are visited in the order go-yaml emits their keys, in which numbers come
+// before strings and the digits within strings are compared by their value,
+// so that a2 comes before a10, and the elements of an array by index. A
+// member that matches is visited before any members within its value. A
+// member whose pointer refers to another key of its object, such as the
+// integer key 1 of an object that also has the string key "1", cannot be
+// addressed and is not visited.
+func (p *PathFinder) findRecursive(path string) []string {
+	parts := strings.Split(path, "/")
+
+	m := recursiveSegmentRegex.FindStringSubmatch(parts[0])
+	if m == nil {
+		return nil
+	}
+
+	found := search("", p.root, decodePatchKey(m[1]))
+
+	if m[2] != "" {
+		i, err := strconv.Atoi(m[2])
+		if err != nil || i >= len(found) {
+			return nil
+		}
+
+		found = found[i : i+1]
+	}
+
+	if len(parts) == 1 {
+		var paths []string
+		for _, f := range found {
+			paths = append(paths, f.path)
+		}
+
+		return paths
+	}
+
+	routes := map[string]Container{}
+	for _, f := range found {
+		routes[f.path] = f.node.Container()
+	}
+
+	for _, part := range parts[1:] {
+		routes = p.find(decodePatchKey(part), routes)
+	}
+
+	var paths []string
+	for k := range routes {
+		paths = append(paths, k)
+	}
+
+	return paths
+}
+
+type searchResult struct {
+	path string
+	node *Node
+}
+
+// search returns every member of an object within the container whose key is
+// the given key, depth-first in document order
+func search(prefix string, container Container, key string) []searchResult {
+	var found []searchResult
+
+	switch it := container.(type) {
+	case *nodeMap:
+		for _, k := range emitOrder(*it) {
+			text := fmt.Sprint(k)
+			if it.mapKey(text) != k {
+				continue
+			}
+
+			node := (*it)[k]
+
+			path := fmt.Sprintf("%s/%s", prefix, encodePatchKey(text))
+			if text == key {
+				found = append(found, searchResult{path: path, node: node})
+			}
+
+			if node != nil {
+				found = append(found, search(path, node.Container(), key)...)
+			}
+		}
+	case *nodeSlice:
+		for i, v := range *it {
+			if v != nil {
+				found = append(found, search(fmt.Sprintf("%s/%d", prefix, i), v.Container(), key)...)
+			}
+		}
+	}
+
+	return found
+}
+
+// sortByPosition sorts pointers into the container by the position in the
+// document of the values they refer to: at the first segment in which two
+// differ, by index within an array and by the order go-yaml emits the keys of
+// a map. Segments beyond the document come after those within it.
+func sortByPosition(c Container, paths []string) {
+	orders := map[*nodeMap]map[string]int{}
+
+	positions := make(map[string][]int, len(paths))
+	for _, path := range paths {
+		positions[path] = position(c, path, orders)
+	}
+
+	sort.SliceStable(paths, func(i, j int) bool {
+		a, b := positions[paths[i]], positions[paths[j]]
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+
+		if len(a) != len(b) {
+			return len(a) < len(b)
+		}
+
+		return paths[i] < paths[j]
+	})
+}
+
+// position returns the index of each segment of the pointer within the array
+// or map it is looked up in, with the order go-yaml emits the keys of each map
+// cached in orders
+func position(c Container, path string, orders map[*nodeMap]map[string]int) []int {
+	if h, ok := c.(*rootHolder); ok {
+		c = h.root.Container()
+	}
+
+	var pos []int
+	for _, part := range strings.Split(path, "/")[1:] {
+		if part == "" {
+			continue
+		}
+
+		key := decodePatchKey(part)
+		i := -1
+
+		switch it := c.(type) {
+		case *nodeSlice:
+			if n, err := parseIndex(key); err == nil && n < len(*it) {
+				i = n
+			}
+		case *nodeMap:
+			order, ok := orders[it]
+			if !ok {
+				order = map[string]int{}
+				for n, k := range emitOrder(*it) {
+					if text := fmt.Sprint(k); it.mapKey(text) == k {
+						order[text] = n
+					}
+				}
+				orders[it] = order
+			}
+
+			if n, ok := order[key]; ok {
+				i = n
+			}
+		}
+
+		if i < 0 {
+			pos = append(pos, int(^uint(0)>>1))
+			c = nil
+			continue
+		}
+
+		pos = append(pos, i)
+
+		node, err := c.Get(key)
+		if node == nil || err != nil {
+			c = nil
+			continue
+		}
+
+		c = node.Container()
+	}
+
+	return pos
+}
@@ -61,6 +61,15 @@ jobs:
 			Entry("return a route for a single submatch with help using escape ordering", "/jobs/get=C~1D", []string{"/jobs/0/plan/2"}),
 			Entry("return a route when given a pointer with a leaf that does not exist", "/jobs/name=job1/nonexistent", []string{"/jobs/0/nonexistent"}),
 			Entry("return a route when given a pointer with an array thingy", "/jobs/name=job1/plan/-", []string{"/jobs/0/plan/-"}),
+			Entry("return routes for every match of a recursive search", "//get", []string{
+				"/jobs/0/plan/0/get",
+				"/jobs/0/plan/1/get",
+				"/jobs/0/plan/2/get",
+				"/jobs/1/plan/0/aggregate/0/get",
+				"/jobs/1/plan/0/aggregate/1/get",
+			}),
+			Entry("return a route for the n-th match of a recursive search", "//get[3]", []string{"/jobs/1/plan/0/aggregate/0/get"}),
+			Entry("return a route relative to the match of a recursive search", "//args[0]/1/arg", []string{"/jobs/0/plan/0/args/1/arg"}),
 		)
 		DescribeTable(
 			"should not",
@@ -69,6 +78,23 @@ jobs:
 			},
 			Entry("return any routes when given a bad index", "/jobs/2"),
 			Entry("return any routes when given a bad index", "/jobs/-1"),
+			Entry("return any routes when given a recursive search with an index beyond the matches", "//get[5]"),
+			Entry("return any routes when given a recursive search that matches nothing", "//put"),
+		)
+
+		DescribeTable(
+			"should visit the members of an object in the order their keys are emitted",
+			func(doc, path string, expected []string) {
+				var iface interface{}
+				Expect(yaml.Unmarshal([]byte(doc), &iface)).To(Succeed())
+
+				actual := yamlpatch.NewPathFinder(yamlpatch.NewNode(&iface).Container()).Find(path)
+				Expect(actual).To(Equal(expected))
+			},
+			Entry("with digits compared by their value", "{a10: {image: a}, a2: {image: b}}", "//image[0]", []string{"/a2/image"}),
+			Entry("with other characters before letters", "{ab: {image: a}, a_b: {image: b}}", "//image[0]", []string{"/a_b/image"}),
+			Entry("with numbers before strings", "{b: {image: a}, 3: {image: b}}", "//image[0]", []string{"/3/image"}),
+			Entry("leaving out a key its pointer cannot address", "{1: {image: a}, \"1\": {image: b}}", "//image", []string{"/1/image"}),
 		)
 	})
 })
@@ -0,0 +1,158 @@
+package yamlpatch
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// placeholderReadSize is the size of the reads a placeholder reader makes
+// from its source
+const placeholderReadSize = 32 * 1024
+
+// maxPlaceholderLength is the length, including its delimiters, beyond which
+// a placeholder reader stops holding back a placeholder that is not yet
+// complete, so that an opening delimiter that is never closed does not make
+// it buffer the rest of the input
+const maxPlaceholderLength = 4 * 1024
+
+// WrapReader returns a reader of the input with its placeholders wrapped, as
+// Wrap would wrap them, without reading the whole input into memory. Only a
+// placeholder that may continue in input not yet read is held back, so a
+// delimiter split across reads is still detected. A placeholder longer than
+// maxPlaceholderLength may be left as it is where Wrap would wrap it.
+func (w *PlaceholderWrapper) WrapReader(r io.Reader) io.Reader {
+	return &placeholderReader{
+		src:   r,
+		re:    w.unwrappedRegex,
+		repl:  []byte(fmt.Sprintf(` '%s$1%s'`, w.LeftSide, w.RightSide)),
+		open:  w.LeftSide,
+		close: w.RightSide,
+		right: w.RightSide,
+	}
+}
+
+// UnwrapReader returns a reader of the input with its placeholders unwrapped,
+// as Unwrap would unwrap them, without reading the whole input into memory
+func (w *PlaceholderWrapper) UnwrapReader(r io.Reader) io.Reader {
+	return &placeholderReader{
+		src:   r,
+		re:    w.wrappedRegex,
+		repl:  []byte(fmt.Sprintf(` %s$1%s`, w.LeftSide, w.RightSide)),
+		open:  "'" + w.LeftSide,
+		close: w.RightSide + "'",
+		right: w.RightSide,
+	}
+}
+
+// placeholderReader replaces the matches of a placeholder pattern in the
+// input as it is read. A match is whitespace, then open, then one or more
+// bytes that are none of those of right, then close.
+type placeholderReader struct {
+	src   io.Reader
+	re    *regexp.Regexp
+	repl  []byte
+	open  string
+	close string
+	right string
+
+	buf     []byte
+	pending []byte
+	out     []byte
+	err     error
+}
+
+func (r *placeholderReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 && r.err == nil {
+		if r.buf == nil {
+			r.buf = make([]byte, placeholderReadSize)
+		}
+
+		n, err := r.src.Read(r.buf)
+		r.pending = append(r.pending, r.buf[:n]...)
+
+		if err != nil {
+			r.err = err
+			r.out = r.re.ReplaceAll(r.pending, r.repl)
+			r.pending = nil
+			break
+		}
+
+		cut := r.safeCut()
+		r.out = r.re.ReplaceAll(r.pending[:cut], r.repl)
+		r.pending = append([]byte(nil), r.pending[cut:]...)
+	}
+
+	if len(r.out) == 0 {
+		return 0, r.err
+	}
+
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+// safeCut returns the offset up to which the pending input can be replaced
+// as if it were the whole input: the start of the first match that could
+// continue in input not yet read. A match that would be longer than
+// maxPlaceholderLength is not held back, which bounds the pending input, and
+// so the work of each read, whatever the input.
+func (r *placeholderReader) safeCut() int {
+	start := r.lastMatchEnd()
+	if limit := len(r.pending) - maxPlaceholderLength; start < limit {
+		start = limit
+	}
+
+	for s := start; s < len(r.pending); s++ {
+		if isRegexpSpace(r.pending[s]) && r.isOpen(r.pending[s+1:]) {
+			return s
+		}
+	}
+
+	return len(r.pending)
+}
+
+// lastMatchEnd returns the offset of the end of the last match in the pending
+// input, or 0 when there is none
+func (r *placeholderReader) lastMatchEnd() int {
+	matches := r.re.FindAllIndex(r.pending, -1)
+	if len(matches) == 0 {
+		return 0
+	}
+
+	return matches[len(matches)-1][1]
+}
+
+// isOpen returns whether the input following whitespace is the beginning of
+// a match that has not yet been completed
+func (r *placeholderReader) isOpen(rest []byte) bool {
+	if len(rest) <= len(r.open) {
+		return strings.HasPrefix(r.open, string(rest))
+	}
+
+	if !bytes.HasPrefix(rest, []byte(r.open)) {
+		return false
+	}
+
+	body := rest[len(r.open):]
+
+	j := bytes.IndexAny(body, r.right)
+	if j < 0 {
+		return true
+	}
+
+	tail := body[j:]
+	return j > 0 && len(tail) < len(r.close) && strings.HasPrefix(r.close, string(tail))
+}
+
+// isRegexpSpace returns whether the byte is matched by \s
+func isRegexpSpace(b byte) bool {
+	switch b {
+	case ' ', '\t', '\n', '\f', '\r':
+		return true
+	}
+
+	return false
+}
@@ -1,17 +1,21 @@
 package yamlpatch
 
 import (
+	"bytes"
 	"fmt"
 	"regexp"
+	"strings"
 )
 
 // PlaceholderWrapper can be used to wrap placeholders that make YAML invalid
 // in single quotes to make otherwise valid YAML
 type PlaceholderWrapper struct {
-	LeftSide       string
-	RightSide      string
-	unwrappedRegex *regexp.Regexp
-	wrappedRegex   *regexp.Regexp
+	LeftSide         string
+	RightSide        string
+	unwrappedRegex   *regexp.Regexp
+	wrappedRegex     *regexp.Regexp
+	placeholderRegex *regexp.Regexp
+	resolveRegex     *regexp.Regexp
 }
 
 // NewPlaceholderWrapper returns a new PlaceholderWrapper which knows how to
@@ -22,12 +26,16 @@ func NewPlaceholderWrapper(left, right string) *PlaceholderWrapper {
 	escapedRight := regexp.QuoteMeta(right)
 	unwrappedRegex := regexp.MustCompile(`\s` + escapedLeft + `([^` + escapedRight + `]+)` + escapedRight)
 	wrappedRegex := regexp.MustCompile(`\s'` + escapedLeft + `([^` + escapedRight + `]+)` + escapedRight + `'`)
+	placeholderRegex := regexp.MustCompile(escapedLeft + `[^` + escapedRight + `]+` + escapedRight)
+	resolveRegex := regexp.MustCompile(`'` + escapedLeft + `([^` + escapedRight + `]+)` + escapedRight + `'|` + escapedLeft + `([^` + escapedRight + `]+)` + escapedRight)
 
 	return &PlaceholderWrapper{
-		LeftSide:       left,
-		RightSide:      right,
-		unwrappedRegex: unwrappedRegex,
-		wrappedRegex:   wrappedRegex,
+		LeftSide:         left,
+		RightSide:        right,
+		unwrappedRegex:   unwrappedRegex,
+		wrappedRegex:     wrappedRegex,
+		placeholderRegex: placeholderRegex,
+		resolveRegex:     resolveRegex,
 	}
 }
 
@@ -49,3 +57,108 @@ func (w *PlaceholderWrapper) Unwrap(input []byte) []byte {
 
 	return w.wrappedRegex.ReplaceAll(input, []byte(fmt.Sprintf(` %s$1%s`, w.LeftSide, w.RightSide)))
 }
+
+// FindUnresolved returns each distinct placeholder, e.g. {{placeholder}},
+// that remains in the input, wrapped or not, in the order they first appear
+func (w *PlaceholderWrapper) FindUnresolved(input []byte) []string {
+	var found []string
+	seen := map[string]bool{}
+
+	for _, match := range w.placeholderRegex.FindAll(input, -1) {
+		if !seen[string(match)] {
+			seen[string(match)] = true
+			found = append(found, string(match))
+		}
+	}
+
+	return found
+}
+
+// Resolve replaces each placeholder in the input, wrapped or not, with the
+// text the resolver returns for its name, the text between the sides with
+// the surrounding whitespace trimmed, so that {{ image.tag }} is resolved by
+// the name image.tag. The quotes of a wrapped placeholder are replaced along
+// with it. The resolver is also told whether the placeholder makes up a whole
+// YAML scalar, as a wrapped one always does, rather than part of one, as in
+// app:{{ image.tag }}, so that it can return a scalar of its own, such as a
+// quoted string. A placeholder the resolver returns false for is left as it
+// is, to be found by FindUnresolved.
+func (w *PlaceholderWrapper) Resolve(input []byte, resolver func(name string, scalar bool) (string, bool)) []byte {
+	var out []byte
+
+	last := 0
+	for _, loc := range w.resolveRegex.FindAllSubmatchIndex(input, -1) {
+		start, end := loc[0], loc[1]
+
+		wrapped := loc[2] >= 0
+
+		var name []byte
+		if wrapped {
+			name = input[loc[2]:loc[3]]
+		} else {
+			name = input[loc[4]:loc[5]]
+		}
+
+		scalar := wrapped || isWholeScalar(input, start, end)
+
+		text, ok := resolver(strings.TrimSpace(string(name)), scalar)
+		if !ok {
+			continue
+		}
+
+		out = append(out, input[last:start]...)
+		out = append(out, text...)
+		last = end
+	}
+
+	return append(out, input[last:]...)
+}
+
+// isWholeScalar returns whether the text from start to end makes up a whole
+// plain scalar of the YAML input: whether it begins a line, or follows the
+// indicator of a key's value, of a sequence's element or of a flow
+// collection, and ends a line or is followed by a comment, by the indicator
+// of a key's value or by that of a flow collection
+func isWholeScalar(input []byte, start, end int) bool {
+	before := start
+	for before > 0 && (input[before-1] == ' ' || input[before-1] == '\t') {
+		before--
+	}
+
+	if before > 0 {
+		switch input[before-1] {
+		case '\n', '[', '{', ',':
+		case ':':
+			if before == start {
+				return false
+			}
+		case '-':
+			line := bytes.LastIndexByte(input[:before], '\n') + 1
+			if before == start || strings.Trim(string(input[line:before]), " -") != "" {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+
+	after := end
+	for after < len(input) && (input[after] == ' ' || input[after] == '\t') {
+		after++
+	}
+
+	if after == len(input) {
+		return true
+	}
+
+	switch input[after] {
+	case '\r', '\n', ',', ']', '}':
+		return true
+	case '#':
+		return after > end
+	case ':':
+		return after+1 == len(input) || strings.ContainsRune(" \t\r\n", rune(input[after+1]))
+	}
+
+	return false
+}
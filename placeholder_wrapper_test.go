@@ -1,8 +1,16 @@
 package yamlpatch_test
 
 import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing/iotest"
+
 	yamlpatch "github.com/krishicks/yaml-patch"
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
 )
 
@@ -90,4 +98,166 @@ content: |
 			Expect(actual).To(Equal(expected))
 		})
 	})
+
+	Describe("WrapReader and UnwrapReader", func() {
+		inputs := []string{
+			"content without any placeholders",
+			"content with a {{placeholder}}",
+			"image: {{ IMAGE }}\ntag: {{tag}}\nname: web\n",
+			"{{at-start}} is not wrapped, {{ but-this }} is",
+			"unterminated {{placeholder\nand {{another}}",
+			"a wrapped '{{placeholder}}' and {{}} and {{a}b}}",
+			"trailing {{",
+			"trailing {{ partial }",
+		}
+
+		readAll := func(r io.Reader) string {
+			bs, err := ioutil.ReadAll(r)
+			Expect(err).NotTo(HaveOccurred())
+			return string(bs)
+		}
+
+		It("wraps as Wrap does, even when delimiters are split across reads", func() {
+			for _, input := range inputs {
+				expected := string(placeholderWrapper.Wrap([]byte(input)))
+
+				Expect(readAll(placeholderWrapper.WrapReader(bytes.NewReader([]byte(input))))).To(Equal(expected))
+				Expect(readAll(placeholderWrapper.WrapReader(iotest.OneByteReader(bytes.NewReader([]byte(input)))))).To(Equal(expected))
+			}
+		})
+
+		It("unwraps as Unwrap does, even when delimiters are split across reads", func() {
+			for _, input := range inputs {
+				wrapped := placeholderWrapper.Wrap([]byte(input))
+				expected := string(placeholderWrapper.Unwrap(wrapped))
+
+				Expect(readAll(placeholderWrapper.UnwrapReader(bytes.NewReader(wrapped)))).To(Equal(expected))
+				Expect(readAll(placeholderWrapper.UnwrapReader(iotest.OneByteReader(bytes.NewReader(wrapped))))).To(Equal(expected))
+			}
+		})
+
+		It("does not hold back the rest of a large input after an unterminated placeholder", func() {
+			src := &failingReader{r: strings.NewReader("image: {{ IMAGE }}\ntag: {{" + strings.Repeat("a", 1<<20))}
+			r := placeholderWrapper.WrapReader(src)
+
+			// All but what the last read and a placeholder's length may hold
+			// back is returned before the input runs out
+			expected := "image: '{{ IMAGE }}'\ntag: {{" + strings.Repeat("a", 1<<20-64<<10)
+
+			buf := make([]byte, len(expected))
+			_, err := io.ReadFull(r, buf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(src.failed).To(BeFalse())
+			Expect(string(buf)).To(Equal(expected))
+		})
+
+		It("returns output before the input has been read in full", func() {
+			pr, pw := io.Pipe()
+			defer pw.Close()
+
+			go pw.Write([]byte("image: {{ IMAGE }}\ntag: {{"))
+
+			r := placeholderWrapper.WrapReader(pr)
+
+			buf := make([]byte, 64)
+			n, err := io.ReadAtLeast(r, buf, len("image: '{{ IMAGE }}'\ntag:"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(buf[:n])).To(Equal("image: '{{ IMAGE }}'\ntag:"))
+		})
+	})
+
+	Describe("FindUnresolved", func() {
+		It("returns nothing when the content contains no placeholders", func() {
+			input := []byte(`content without any placeholders`)
+			Expect(placeholderWrapper.FindUnresolved(input)).To(BeEmpty())
+		})
+
+		It("returns each distinct placeholder in the order they appear", func() {
+			input := []byte(`
+image: {{ IMAGE }}
+tag: '{{tag}}'
+other: {{ IMAGE }}
+`)
+			Expect(placeholderWrapper.FindUnresolved(input)).To(Equal([]string{"{{ IMAGE }}", "{{tag}}"}))
+		})
+
+		It("supports alternate placeholders", func() {
+			placeholderWrapper = yamlpatch.NewPlaceholderWrapper("((", "))")
+			input := []byte(`content with an ((alternate-placeholder)) and a {{placeholder}}`)
+			Expect(placeholderWrapper.FindUnresolved(input)).To(Equal([]string{"((alternate-placeholder))"}))
+		})
+	})
+
+	Describe("IsReservedPlaceholder", func() {
+		It("returns whether a patch resolves the placeholder itself", func() {
+			for _, placeholder := range []string{"{{var:tag}}", "{{ path:/spec/image }}", "{{index}}", "{{key}}"} {
+				Expect(yamlpatch.IsReservedPlaceholder(placeholder)).To(BeTrue(), placeholder)
+			}
+
+			for _, placeholder := range []string{"{{ image.tag }}", "{{index}} and more", "{{variable}}"} {
+				Expect(yamlpatch.IsReservedPlaceholder(placeholder)).To(BeFalse(), placeholder)
+			}
+		})
+	})
+
+	Describe("Resolve", func() {
+		resolver := func(name string, scalar bool) (string, bool) {
+			values := map[string]string{"image.tag": "v1.2", "replicas": "3"}
+			text, ok := values[name]
+			return text, ok
+		}
+
+		It("replaces each placeholder the resolver resolves, wrapped or not", func() {
+			input := []byte("image: app:{{ image.tag }}\nreplicas: '{{replicas}}'\n")
+			Expect(string(placeholderWrapper.Resolve(input, resolver))).To(Equal("image: app:v1.2\nreplicas: 3\n"))
+		})
+
+		It("leaves the placeholders the resolver does not resolve", func() {
+			input := []byte("image: {{ image.tag }}\nname: '{{ name }}'\n")
+
+			resolved := placeholderWrapper.Resolve(input, resolver)
+			Expect(string(resolved)).To(Equal("image: v1.2\nname: '{{ name }}'\n"))
+			Expect(placeholderWrapper.FindUnresolved(resolved)).To(Equal([]string{"{{ name }}"}))
+		})
+
+		DescribeTable("tells the resolver whether the placeholder makes up a whole scalar",
+			func(input string, expected bool) {
+				var scalar bool
+				placeholderWrapper.Resolve([]byte(input), func(name string, s bool) (string, bool) {
+					scalar = s
+					return "", true
+				})
+				Expect(scalar).To(Equal(expected))
+			},
+			Entry("the value of a key", "a: {{x}}\n", true),
+			Entry("a wrapped value of a key", "a: '{{x}}'\n", true),
+			Entry("a value followed by a comment", "a: {{x}} # note\n", true),
+			Entry("an element of a sequence", "- - {{x}}\n", true),
+			Entry("an element of a flow sequence", "a: [b, {{x}}]\n", true),
+			Entry("a key", "{{x}}: a\n", true),
+			Entry("the end of the input", "a: {{x}}", true),
+			Entry("part of a value", "a: app:{{x}}\n", false),
+			Entry("part of a value after a space", "a: b {{x}}\n", false),
+			Entry("part of a value before a space", "a: {{x}} b\n", false),
+			Entry("part of a double-quoted string", "a: \"b {{x}}\"\n", false),
+			Entry("part of a value beginning with a dash", "a: -{{x}}\n", false),
+		)
+	})
 })
+
+// failingReader reads from r and then fails rather than returning io.EOF,
+// recording that it was read past the end of r
+type failingReader struct {
+	r      io.Reader
+	failed bool
+}
+
+func (f *failingReader) Read(p []byte) (int, error) {
+	n, err := f.r.Read(p)
+	if err == io.EOF {
+		f.failed = true
+		return n, errors.New("read past the end of the input")
+	}
+
+	return n, err
+}
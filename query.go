@@ -0,0 +1,489 @@
+package yamlpatch
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Query returns the values within the document that the JSONPath-like
+// expression selects, without modifying the document. The supported subset
+// of JSONPath is:
+//
+//	$               the root of the document, with which expressions start
+//	.key, ['key']   the value of a key of a map
+//	[n]             an element of an array, counting from the end when negative
+//	[start:end]     the elements of an array from start up to but not including end
+//	.*, [*]         every value of a map or element of an array
+//	..key, ..*      the values of the key, or every value, at any depth
+//	[?(@.a.b == v)] the elements whose value at a relative path is equal to,
+//	                or with !=, not equal to, a string, number, bool or null;
+//	                without a comparison, the elements that have the path
+//
+// The values of a map are selected in the order of their keys.
+func Query(doc []byte, expr string) ([]interface{}, error) {
+	steps, err := parseQuery(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := defaultCodec.unmarshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed unmarshaling doc: %s\n\n%s", string(doc), err)
+	}
+
+	results := []interface{}{v}
+	for _, step := range steps {
+		var next []interface{}
+		for _, v := range results {
+			next = append(next, step.apply(v)...)
+		}
+		results = next
+	}
+
+	return results, nil
+}
+
+// queryStep selects values from a value
+type queryStep interface {
+	apply(v interface{}) []interface{}
+}
+
+type childStep struct {
+	key string
+}
+
+func (s childStep) apply(v interface{}) []interface{} {
+	if m, ok := v.(map[interface{}]interface{}); ok {
+		if val, ok := LookupKey(m, s.key); ok {
+			return []interface{}{val}
+		}
+	}
+
+	return nil
+}
+
+type indexStep struct {
+	index int
+}
+
+func (s indexStep) apply(v interface{}) []interface{} {
+	ary, ok := v.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	i := s.index
+	if i < 0 {
+		i += len(ary)
+	}
+
+	if i < 0 || i >= len(ary) {
+		return nil
+	}
+
+	return []interface{}{ary[i]}
+}
+
+type sliceStep struct {
+	start, end       int
+	hasStart, hasEnd bool
+}
+
+func (s sliceStep) apply(v interface{}) []interface{} {
+	ary, ok := v.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	start, end := 0, len(ary)
+	if s.hasStart {
+		start = clampIndex(s.start, len(ary))
+	}
+	if s.hasEnd {
+		end = clampIndex(s.end, len(ary))
+	}
+
+	if start >= end {
+		return nil
+	}
+
+	return append([]interface{}{}, ary[start:end]...)
+}
+
+// clampIndex returns the index within an array of the given length, counting
+// from the end when negative
+func clampIndex(i, length int) int {
+	if i < 0 {
+		i += length
+	}
+
+	if i < 0 {
+		return 0
+	}
+
+	if i > length {
+		return length
+	}
+
+	return i
+}
+
+type wildcardStep struct{}
+
+func (wildcardStep) apply(v interface{}) []interface{} {
+	switch it := v.(type) {
+	case map[interface{}]interface{}:
+		var vals []interface{}
+		for _, k := range sortedKeys(it) {
+			vals = append(vals, it[k])
+		}
+		return vals
+	case []interface{}:
+		return append([]interface{}{}, it...)
+	}
+
+	return nil
+}
+
+// descendantStep applies its step to the value and to every value nested
+// within it
+type descendantStep struct {
+	step queryStep
+}
+
+func (s descendantStep) apply(v interface{}) []interface{} {
+	vals := s.step.apply(v)
+
+	for _, child := range (wildcardStep{}).apply(v) {
+		vals = append(vals, s.apply(child)...)
+	}
+
+	return vals
+}
+
+type filterStep struct {
+	path    []string
+	op      string
+	literal interface{}
+}
+
+func (s filterStep) apply(v interface{}) []interface{} {
+	var vals []interface{}
+	for _, elem := range (wildcardStep{}).apply(v) {
+		if s.matches(elem) {
+			vals = append(vals, elem)
+		}
+	}
+
+	return vals
+}
+
+func (s filterStep) matches(v interface{}) bool {
+	for _, key := range s.path {
+		m, ok := v.(map[interface{}]interface{})
+		if !ok {
+			return false
+		}
+
+		v, ok = LookupKey(m, key)
+		if !ok {
+			return false
+		}
+	}
+
+	switch s.op {
+	case "==":
+		return queryEqual(v, s.literal)
+	case "!=":
+		return !queryEqual(v, s.literal)
+	}
+
+	return true
+}
+
+// queryEqual compares values, treating numbers of any type as equal when their
+// values are
+func queryEqual(a, b interface{}) bool {
+	if fa, ok := toFloat(a); ok {
+		fb, ok := toFloat(b)
+		return ok && fa == fb
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+
+	return 0, false
+}
+
+// LookupKey returns the value of the key of a decoded map, matching integer
+// keys to keys that are written as integers, as Query does
+func LookupKey(m map[interface{}]interface{}, key string) (interface{}, bool) {
+	if v, ok := m[key]; ok {
+		return v, true
+	}
+
+	if i, err := strconv.Atoi(key); err == nil {
+		if v, ok := m[i]; ok {
+			return v, true
+		}
+	}
+
+	return nil, false
+}
+
+func sortedKeys(m map[interface{}]interface{}) []interface{} {
+	keys := make([]interface{}, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keyLess(keys[i], keys[j])
+	})
+
+	return keys
+}
+
+// queryParser parses a query expression into its steps
+type queryParser struct {
+	expr string
+	pos  int
+}
+
+func parseQuery(expr string) ([]queryStep, error) {
+	p := &queryParser{expr: expr}
+
+	if !strings.HasPrefix(expr, "$") {
+		return nil, p.errorf("expression must start with $")
+	}
+	p.pos = 1
+
+	var steps []queryStep
+	for p.pos < len(p.expr) {
+		step, err := p.step()
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, step)
+	}
+
+	return steps, nil
+}
+
+func (p *queryParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid query %s at offset %d: %s", p.expr, p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *queryParser) step() (queryStep, error) {
+	rest := p.expr[p.pos:]
+
+	switch {
+	case strings.HasPrefix(rest, ".."):
+		p.pos += 2
+		if p.pos < len(p.expr) && p.expr[p.pos] == '[' {
+			step, err := p.bracket()
+			return descendantStep{step: step}, err
+		}
+
+		step, err := p.dotted()
+		return descendantStep{step: step}, err
+	case strings.HasPrefix(rest, "."):
+		p.pos++
+		return p.dotted()
+	case strings.HasPrefix(rest, "["):
+		return p.bracket()
+	}
+
+	return nil, p.errorf("expected . or [")
+}
+
+// dotted parses the key or * following a .
+func (p *queryParser) dotted() (queryStep, error) {
+	start := p.pos
+	for p.pos < len(p.expr) && p.expr[p.pos] != '.' && p.expr[p.pos] != '[' {
+		p.pos++
+	}
+
+	key := p.expr[start:p.pos]
+	if key == "" {
+		return nil, p.errorf("expected a key")
+	}
+
+	if key == "*" {
+		return wildcardStep{}, nil
+	}
+
+	return childStep{key: key}, nil
+}
+
+// bracket parses a [...] selector
+func (p *queryParser) bracket() (queryStep, error) {
+	p.pos++
+
+	end := p.closingBracket()
+	if end < 0 {
+		return nil, p.errorf("unterminated [")
+	}
+
+	inner := strings.TrimSpace(p.expr[p.pos:end])
+	step, err := p.selector(inner)
+	if err != nil {
+		return nil, err
+	}
+
+	p.pos = end + 1
+	return step, nil
+}
+
+// closingBracket returns the offset of the ] that closes the bracket at the
+// current position, skipping any within quotes
+func (p *queryParser) closingBracket() int {
+	var quote byte
+	for i := p.pos; i < len(p.expr); i++ {
+		c := p.expr[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == ']':
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (p *queryParser) selector(inner string) (queryStep, error) {
+	switch {
+	case inner == "*":
+		return wildcardStep{}, nil
+	case isQuoted(inner):
+		return childStep{key: inner[1 : len(inner)-1]}, nil
+	case strings.HasPrefix(inner, "?(") && strings.HasSuffix(inner, ")"):
+		return p.filter(strings.TrimSpace(inner[2 : len(inner)-1]))
+	case strings.Contains(inner, ":"):
+		return p.slice(inner)
+	}
+
+	i, err := strconv.Atoi(inner)
+	if err != nil {
+		return nil, p.errorf("unsupported selector [%s]", inner)
+	}
+
+	return indexStep{index: i}, nil
+}
+
+func (p *queryParser) slice(inner string) (queryStep, error) {
+	bounds := strings.SplitN(inner, ":", 2)
+
+	var s sliceStep
+	var err error
+
+	if b := strings.TrimSpace(bounds[0]); b != "" {
+		s.start, err = strconv.Atoi(b)
+		if err != nil {
+			return nil, p.errorf("invalid slice start %s", b)
+		}
+		s.hasStart = true
+	}
+
+	if b := strings.TrimSpace(bounds[1]); b != "" {
+		s.end, err = strconv.Atoi(b)
+		if err != nil {
+			return nil, p.errorf("invalid slice end %s", b)
+		}
+		s.hasEnd = true
+	}
+
+	return s, nil
+}
+
+func (p *queryParser) filter(cond string) (queryStep, error) {
+	var s filterStep
+
+	lhs := cond
+	if i := comparisonIndex(cond); i >= 0 {
+		lhs = strings.TrimSpace(cond[:i])
+		s.op = cond[i : i+2]
+
+		literal, err := parseLiteral(strings.TrimSpace(cond[i+2:]))
+		if err != nil {
+			return nil, p.errorf("%s", err)
+		}
+		s.literal = literal
+	}
+
+	if lhs != "@" && !strings.HasPrefix(lhs, "@.") {
+		return nil, p.errorf("filter must refer to the element as @")
+	}
+
+	if lhs != "@" {
+		s.path = strings.Split(lhs[2:], ".")
+	}
+
+	return s, nil
+}
+
+// comparisonIndex returns the offset of the first == or != in the condition
+// of a filter that is not within a quoted literal, or -1 when there is none
+func comparisonIndex(cond string) int {
+	var quote byte
+	for i := 0; i < len(cond); i++ {
+		c := cond[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case (c == '=' || c == '!') && i+1 < len(cond) && cond[i+1] == '=':
+			return i
+		}
+	}
+
+	return -1
+}
+
+// parseLiteral parses a literal in a filter, which is a quoted string, a
+// number, true, false or null
+func parseLiteral(s string) (interface{}, error) {
+	switch {
+	case isQuoted(s):
+		return s[1 : len(s)-1], nil
+	case s == "true":
+		return true, nil
+	case s == "false":
+		return false, nil
+	case s == "null":
+		return nil, nil
+	}
+
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f, nil
+	}
+
+	return nil, fmt.Errorf("invalid literal %s", s)
+}
+
+func isQuoted(s string) bool {
+	return len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0]
+}
@@ -0,0 +1,87 @@
+package yamlpatch_test
+
+import (
+	yamlpatch "github.com/krishicks/yaml-patch"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Query", func() {
+	doc := []byte(`---
+kind: Deployment
+spec:
+  replicas: 2
+  containers:
+  - name: web
+    image: web:1
+    ports: [80, 443]
+  - name: worker
+    image: worker:2
+  - name: sidecar
+    image: proxy:3
+    ports: [9090]
+`)
+
+	DescribeTable("selecting values",
+		func(expr string, expected ...interface{}) {
+			actual, err := yamlpatch.Query(doc, expr)
+			Expect(err).NotTo(HaveOccurred())
+			if len(expected) == 0 {
+				Expect(actual).To(BeEmpty())
+			} else {
+				Expect(actual).To(Equal(expected))
+			}
+		},
+		Entry("a key", "$.kind", "Deployment"),
+		Entry("a quoted key", "$['spec']['replicas']", 2),
+		Entry("every element of an array", "$.spec.containers[*].image", "web:1", "worker:2", "proxy:3"),
+		Entry("an index", "$.spec.containers[1].name", "worker"),
+		Entry("a negative index", "$.spec.containers[-1].name", "sidecar"),
+		Entry("a slice", "$.spec.containers[0:2].name", "web", "worker"),
+		Entry("a recursive descent", "$..ports[0]", 80, 9090),
+		Entry("a filter by equality", "$.spec.containers[?(@.name == 'worker')].image", "worker:2"),
+		Entry("a filter by inequality", "$.spec.containers[?(@.name != 'worker')].name", "web", "sidecar"),
+		Entry("a filter by existence", "$.spec.containers[?(@.ports)].name", "web", "sidecar"),
+		Entry("a filter by inequality to a literal holding ==", "$.spec.containers[?(@.name != 'web==1')].name", "web", "worker", "sidecar"),
+		Entry("a filter by equality to a literal holding !=", "$.spec.containers[?(@.name == 'web!=1')].name"),
+		Entry("a missing key", "$.metadata.name"),
+		Entry("a wildcard over a map, in key order", "$.spec.containers[0].*", "web:1", "web", []interface{}{80, 443}),
+	)
+
+	DescribeTable("rejecting invalid expressions",
+		func(expr string) {
+			_, err := yamlpatch.Query(doc, expr)
+			Expect(err).To(MatchError(ContainSubstring("invalid query")))
+		},
+		Entry("without a root", "spec.replicas"),
+		Entry("with an unterminated bracket", "$.spec[0"),
+		Entry("with an unsupported selector", "$.spec[a]"),
+		Entry("with an empty key", "$.spec."),
+		Entry("with an invalid literal", "$.spec.containers[?(@.name == web)]"),
+	)
+
+	It("selects the values of keys written the same in a stable order", func() {
+		for i := 0; i < 10; i++ {
+			actual, err := yamlpatch.Query([]byte(`{"1": string, 1: int, true: bool, "true": text}`), "$.*")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(actual).To(Equal([]interface{}{"int", "string", "bool", "text"}))
+		}
+	})
+})
+
+var _ = Describe("LookupKey", func() {
+	It("matches string keys, then integer keys written as the key", func() {
+		m := map[interface{}]interface{}{"a": 1, 8080: "http", "1": "string", 1: "int"}
+
+		for key, expected := range map[string]interface{}{"a": 1, "8080": "http", "1": "string"} {
+			v, ok := yamlpatch.LookupKey(m, key)
+			Expect(ok).To(BeTrue())
+			Expect(v).To(Equal(expected))
+		}
+
+		_, ok := yamlpatch.LookupKey(m, "b")
+		Expect(ok).To(BeFalse())
+	})
+})
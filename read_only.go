@@ -0,0 +1,38 @@
+package yamlpatch
+
+// readOnlyOps are the ops that never modify the document: a test operation
+// only asserts a value, and a capture operation only binds it to a variable
+var readOnlyOps = map[Op]bool{
+	opTest:    true,
+	opCapture: true,
+}
+
+// IsReadOnly returns whether applying the patch leaves the document as it is,
+// because every operation of it is a test or capture operation, or a block or
+// with operation of only those, so that the patch can be used as a validation
+// gate. Disabled operations are never performed, and so are not considered.
+// Operations registered with RegisterOperation may modify the document, so a
+// patch with one is not read-only.
+func (p Patch) IsReadOnly() bool {
+	for i := range p {
+		if !p[i].isReadOnly() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isReadOnly returns whether the operation never modifies the document
+func (o *Operation) isReadOnly() bool {
+	if o.Disabled {
+		return true
+	}
+
+	switch o.Op {
+	case opBlock, opWith:
+		return o.Operations.IsReadOnly()
+	}
+
+	return readOnlyOps[o.Op]
+}
@@ -0,0 +1,41 @@
+package yamlpatch
+
+import "sync"
+
+// OperationFunc performs a custom operation on the document
+type OperationFunc func(doc *Node, op Operation) error
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[Op]OperationFunc{}
+)
+
+// RegisterOperation registers a function that performs operations with the
+// given op verb, e.g. "op: uppercase-keys", extending the verbs a patch can
+// use. It is typically called from an init function. Built-in verbs take
+// precedence, so registering one of them has no effect. Registering a verb
+// that is already registered replaces its function.
+func RegisterOperation(name string, fn OperationFunc) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	registry[Op(name)] = fn
+}
+
+// UnregisterOperation removes the function registered for the given op verb,
+// so that patches using it fail again as they did before it was registered.
+// It lets tests that register operations leave the registry as they found it.
+func UnregisterOperation(name string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	delete(registry, Op(name))
+}
+
+func registeredOperation(op Op) (OperationFunc, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	fn, ok := registry[op]
+	return fn, ok
+}
@@ -0,0 +1,63 @@
+package yamlpatch_test
+
+import (
+	"errors"
+
+	yamlpatch "github.com/krishicks/yaml-patch"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RegisterOperation", func() {
+	BeforeEach(func() {
+		yamlpatch.RegisterOperation("set-all", func(doc *yamlpatch.Node, op yamlpatch.Operation) error {
+			for _, key := range []string{"a", "b"} {
+				err := doc.Container().Set(key, op.Value)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+
+		yamlpatch.RegisterOperation("fail", func(doc *yamlpatch.Node, op yamlpatch.Operation) error {
+			return errors.New("failed on purpose")
+		})
+	})
+
+	AfterEach(func() {
+		yamlpatch.UnregisterOperation("set-all")
+		yamlpatch.UnregisterOperation("fail")
+	})
+
+	It("dispatches registered verbs to their functions", func() {
+		patch, err := yamlpatch.DecodePatch([]byte(`---
+- op: set-all
+  value: x
+`))
+		Expect(err).NotTo(HaveOccurred())
+
+		actual, err := patch.Apply([]byte(`{c: z}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actual).To(MatchYAML(`{a: x, b: x, c: z}`))
+	})
+
+	It("returns the error from a registered function", func() {
+		patch, err := yamlpatch.DecodePatch([]byte(`[{op: fail}]`))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = patch.Apply([]byte(`{c: z}`))
+		Expect(err).To(MatchError("failed on purpose"))
+	})
+
+	It("fails operations with a verb once it is unregistered", func() {
+		yamlpatch.UnregisterOperation("fail")
+
+		patch, err := yamlpatch.DecodePatch([]byte(`[{op: fail}]`))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = patch.Apply([]byte(`{c: z}`))
+		Expect(err).To(MatchError("Unexpected op: fail"))
+	})
+})
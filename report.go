@@ -0,0 +1,81 @@
+package yamlpatch
+
+import "fmt"
+
+// SkipReason is the reason an operation was skipped rather than performed
+type SkipReason string
+
+// Skip reasons
+const (
+	// SkipDisabled is the reason for skipping an operation that is disabled
+	SkipDisabled SkipReason = "Disabled"
+
+	// SkipConditionFalse is the reason for skipping an operation whose
+	// if_kind does not match the document
+	SkipConditionFalse SkipReason = "ConditionFalse"
+
+	// SkipPathMissing is the reason for skipping an operation whose scope
+	// matched nothing in the document, or whose for_each collection is empty,
+	// leaving no path to perform it at
+	SkipPathMissing SkipReason = "PathMissing"
+
+	// SkipNotTargeted is the reason for skipping an operation whose target is
+	// another document of the stream
+	SkipNotTargeted SkipReason = "NotTargeted"
+
+	// SkipAlreadyPresent is the reason for skipping an add operation with an
+	// on_existing of skip whose path is an existing key, at every path it
+	// expanded to
+	SkipAlreadyPresent SkipReason = "AlreadyPresent"
+)
+
+// skippedError is returned by an operation that was skipped at its path for
+// the given reason rather than performed. It is recorded as a skip rather than
+// returned.
+type skippedError struct {
+	reason SkipReason
+}
+
+func (e *skippedError) Error() string {
+	return fmt.Sprintf("operation skipped: %s", e.reason)
+}
+
+// SkipInfo records an operation that was skipped when a patch was applied
+type SkipInfo struct {
+	// Operation is the index of the operation within the patch
+	Operation int
+
+	// Nested, for an operation nested in a block or with operation, holds
+	// the index of the operation within it, preceded by the index of each
+	// block it is in turn nested in within the one at Operation
+	Nested []int
+
+	Reason SkipReason
+}
+
+func (s SkipInfo) String() string {
+	index := fmt.Sprint(s.Operation)
+	for _, i := range s.Nested {
+		index += fmt.Sprintf(".%d", i)
+	}
+
+	return fmt.Sprintf("operation %s skipped: %s", index, s.Reason)
+}
+
+// Report describes how a patch was applied to a document
+type Report struct {
+	// Skipped holds the operations that were skipped, in the order they
+	// would have been performed
+	Skipped []SkipInfo
+}
+
+// ApplyWithReport is ApplyWithOptions, additionally returning a report of
+// the operations that were skipped and why. The report is returned even when
+// the patch fails, covering the operations before the failure.
+func (p Patch) ApplyWithReport(doc []byte, opts ApplyOptions) ([]byte, *Report, error) {
+	ctx := newApplyContext(opts)
+
+	bs, err := p.applyDocument(doc, ctx)
+
+	return bs, &Report{Skipped: ctx.skipped}, err
+}
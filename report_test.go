@@ -0,0 +1,101 @@
+package yamlpatch_test
+
+import (
+	yamlpatch "github.com/krishicks/yaml-patch"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ApplyWithReport", func() {
+	It("reports each skipped operation with its reason", func() {
+		patch, err := yamlpatch.DecodePatch([]byte(`---
+- op: add
+  path: /a
+  value: 1
+  disabled: true
+- op: add
+  path: /b
+  value: 2
+  if_kind: Service
+- op: add
+  path: /c
+  value: 3
+  scope: /items/name=missing
+- op: add
+  path: /d
+  value: 4
+  if_kind: Deployment
+`))
+		Expect(err).NotTo(HaveOccurred())
+
+		actual, report, err := patch.ApplyWithReport([]byte("kind: Deployment\nitems: []\n"), yamlpatch.ApplyOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(actual)).To(Equal("d: 4\nitems: []\nkind: Deployment\n"))
+
+		Expect(report.Skipped).To(Equal([]yamlpatch.SkipInfo{
+			{Operation: 0, Reason: yamlpatch.SkipDisabled},
+			{Operation: 1, Reason: yamlpatch.SkipConditionFalse},
+			{Operation: 2, Reason: yamlpatch.SkipPathMissing},
+		}))
+		Expect(report.Skipped[2].String()).To(Equal("operation 2 skipped: PathMissing"))
+	})
+
+	It("reports an add skipped at every path because its key exists", func() {
+		patch, err := yamlpatch.DecodePatch([]byte(`---
+- {op: add, path: /a, value: 2, on_existing: skip}
+- {op: add, path: [/a, /b], value: 3, on_existing: skip}
+`))
+		Expect(err).NotTo(HaveOccurred())
+
+		var performed []int
+		actual, report, err := patch.ApplyWithReport([]byte("a: 1\n"), yamlpatch.ApplyOptions{
+			OnOperation: func(index int, op yamlpatch.Operation, err error) {
+				performed = append(performed, index)
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(actual)).To(Equal("a: 1\nb: 3\n"))
+
+		Expect(report.Skipped).To(Equal([]yamlpatch.SkipInfo{{Operation: 0, Reason: yamlpatch.SkipAlreadyPresent}}))
+		Expect(performed).To(Equal([]int{1}))
+	})
+
+	It("reports operations skipped within blocks by the index of the block", func() {
+		patch, err := yamlpatch.DecodePatch([]byte(`---
+- op: add
+  path: /a
+  value: 1
+- op: block
+  operations:
+  - op: add
+    path: /b
+    value: 2
+  - op: block
+    operations:
+    - op: remove
+      path: /c
+      disabled: true
+`))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, report, err := patch.ApplyWithReport([]byte("c: 3\n"), yamlpatch.ApplyOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(report.Skipped).To(Equal([]yamlpatch.SkipInfo{
+			{Operation: 1, Nested: []int{1, 0}, Reason: yamlpatch.SkipDisabled},
+		}))
+		Expect(report.Skipped[0].String()).To(Equal("operation 1.1.0 skipped: Disabled"))
+	})
+
+	It("returns the operations skipped before a failure", func() {
+		patch := yamlpatch.Patch{
+			{Op: "add", Path: "/a", Value: nodeOf(1), Disabled: true},
+			{Op: "remove", Path: "/missing"},
+		}
+
+		_, report, err := patch.ApplyWithReport([]byte("b: 2\n"), yamlpatch.ApplyOptions{})
+		Expect(err).To(HaveOccurred())
+		Expect(report.Skipped).To(HaveLen(1))
+	})
+})
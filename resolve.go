@@ -0,0 +1,53 @@
+package yamlpatch
+
+import (
+	"fmt"
+	"sort"
+)
+
+// PathResolution holds the concrete paths that an operation of a patch
+// resolves to within a document
+type PathResolution struct {
+	// Index is the index of the operation within the patch
+	Index     int
+	Operation Operation
+
+	// Paths are the concrete paths the operation would be performed at, in
+	// sorted order. It is empty when the operation would be skipped.
+	Paths []string
+}
+
+// ResolvePaths returns the concrete paths that each operation of the patch
+// resolves to within the document, expanding scopes and key=value pointers,
+// without performing any operations. Every operation is resolved against the
+// document as given, not as it would be after the operations before it.
+func (p Patch) ResolvePaths(doc []byte) ([]PathResolution, error) {
+	iface, err := defaultCodec.unmarshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed unmarshaling doc: %s\n\n%s", string(doc), err)
+	}
+
+	c := NewNode(&iface).Container()
+
+	resolutions := make([]PathResolution, len(p))
+	for i, op := range p {
+		resolutions[i] = PathResolution{Index: i, Operation: op}
+
+		if !op.appliesTo(c) {
+			continue
+		}
+
+		ops, err := op.expand(c, "")
+		if err != nil {
+			return nil, fmt.Errorf("operation %d: %s", i, err)
+		}
+
+		for _, expanded := range ops {
+			resolutions[i].Paths = append(resolutions[i].Paths, string(expanded.Path))
+		}
+
+		sort.Strings(resolutions[i].Paths)
+	}
+
+	return resolutions, nil
+}
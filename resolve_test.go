@@ -0,0 +1,77 @@
+package yamlpatch_test
+
+import (
+	yamlpatch "github.com/krishicks/yaml-patch"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ResolvePaths", func() {
+	doc := []byte(`---
+kind: List
+items:
+- kind: Pod
+  name: a
+- kind: Service
+  name: b
+- kind: Pod
+  name: c
+`)
+
+	It("returns the concrete paths each operation resolves to", func() {
+		patch, err := yamlpatch.DecodePatch([]byte(`---
+- op: replace
+  path: /items/0/name
+  value: z
+- op: remove
+  path: /kind=Pod
+- op: add
+  path: /labels
+  value: {}
+  scope: /kind=Service
+- op: add
+  path: /spec
+  value: {}
+  if_kind: Deployment
+`))
+		Expect(err).NotTo(HaveOccurred())
+
+		resolutions, err := patch.ResolvePaths(doc)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resolutions).To(HaveLen(4))
+
+		Expect(resolutions[0].Index).To(Equal(0))
+		Expect(resolutions[0].Operation).To(Equal(patch[0]))
+		Expect(resolutions[0].Paths).To(Equal([]string{"/items/0/name"}))
+		Expect(resolutions[1].Paths).To(Equal([]string{"/items/0", "/items/2"}))
+		Expect(resolutions[2].Paths).To(Equal([]string{"/items/1/labels"}))
+		Expect(resolutions[3].Paths).To(BeEmpty())
+	})
+
+	It("lists disabled operations without any paths", func() {
+		patch, err := yamlpatch.DecodePatch([]byte(`---
+- op: remove
+  path: /items/kind=Pod
+  disabled: true
+`))
+		Expect(err).NotTo(HaveOccurred())
+
+		resolutions, err := patch.ResolvePaths(doc)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resolutions).To(HaveLen(1))
+		Expect(resolutions[0].Operation.Disabled).To(BeTrue())
+		Expect(resolutions[0].Paths).To(BeEmpty())
+	})
+
+	It("returns an error when a pointer cannot be expanded", func() {
+		patch, err := yamlpatch.DecodePatch([]byte(`---
+- op: remove
+  path: /items/kind=Deployment
+`))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = patch.ResolvePaths(doc)
+		Expect(err).To(MatchError(ContainSubstring("operation 0")))
+	})
+})
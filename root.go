@@ -0,0 +1,105 @@
+package yamlpatch
+
+import "fmt"
+
+// rootHolder is a Container whose only key is the empty pointer "", which RFC
+// 6901 defines as referring to the whole document. It lets operations replace
+// and test the root of a document, whatever its kind. Other keys are looked up
+// in the root's own container.
+type rootHolder struct {
+	root *Node
+}
+
+func (h *rootHolder) container(key string) (Container, error) {
+	c := h.root.Container()
+	if c == nil {
+		return nil, fmt.Errorf("path does not exist: /%s", key)
+	}
+
+	return c, nil
+}
+
+func (h *rootHolder) Get(key string) (*Node, error) {
+	if key == "" {
+		return h.root, nil
+	}
+
+	c := h.root.Container()
+	if c == nil {
+		return nil, nil
+	}
+
+	return c.Get(key)
+}
+
+func (h *rootHolder) Set(key string, val *Node) error {
+	if key == "" {
+		h.setRoot(val)
+		return nil
+	}
+
+	c, err := h.container(key)
+	if err != nil {
+		return err
+	}
+
+	return c.Set(key, val)
+}
+
+func (h *rootHolder) Add(key string, val *Node) error {
+	if key == "" {
+		h.setRoot(val)
+		return nil
+	}
+
+	c, err := h.container(key)
+	if err != nil {
+		return err
+	}
+
+	return c.Add(key, val)
+}
+
+func (h *rootHolder) Remove(key string) error {
+	if key == "" {
+		h.setRoot(nil)
+		return nil
+	}
+
+	c, err := h.container(key)
+	if err != nil {
+		return err
+	}
+
+	return c.Remove(key)
+}
+
+// setRoot replaces the root in place with a copy of the value, so that later
+// operations on the document do not affect the operation's value
+func (h *rootHolder) setRoot(val *Node) {
+	v := deepCopy(val.plain())
+
+	h.root.raw = &v
+	h.root.container = nil
+}
+
+// deepCopy returns a copy of a decoded value that shares no maps or slices
+// with it
+func deepCopy(v interface{}) interface{} {
+	switch it := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[interface{}]interface{}, len(it))
+		for k, v := range it {
+			m[k] = deepCopy(v)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(it))
+		for i, v := range it {
+			s[i] = deepCopy(v)
+		}
+		return s
+	}
+
+	return v
+}
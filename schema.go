@@ -0,0 +1,74 @@
+package yamlpatch
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// SchemaValidator validates a JSON document against a JSON Schema. It is the
+// extension point for plugging a JSON Schema implementation into
+// ApplyAndValidate, keeping yamlpatch itself free of a schema dependency.
+type SchemaValidator interface {
+	// Validate returns the ways in which the document does not conform to the
+	// schema, or an error if the validation could not be performed, e.g.
+	// because the schema is invalid
+	Validate(schema, document []byte) ([]SchemaError, error)
+}
+
+// SchemaError describes a single way in which a document does not conform to
+// a schema
+type SchemaError struct {
+	// Path is the JSON pointer to the offending value
+	Path    string
+	Message string
+}
+
+func (e SchemaError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// SchemaErrors is returned by ApplyAndValidate when the patched document does
+// not conform to the schema
+type SchemaErrors []SchemaError
+
+func (e SchemaErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i := range e {
+		msgs[i] = e[i].Error()
+	}
+
+	return fmt.Sprintf("document does not conform to schema:\n%s", strings.Join(msgs, "\n"))
+}
+
+// ApplyAndValidate returns a YAML document that has been mutated per the
+// patch, after validating the result against the given JSON Schema with the
+// given validator. When the result does not conform to the schema the error
+// is a SchemaErrors.
+func (p Patch) ApplyAndValidate(doc, schema []byte, validator SchemaValidator) ([]byte, error) {
+	iface, err := defaultCodec.unmarshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed unmarshaling doc: %s\n\n%s", string(doc), err)
+	}
+
+	root, err := p.apply(&iface, ApplyOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	js, err := json.Marshal(JSONValue(root.plain()))
+	if err != nil {
+		return nil, err
+	}
+
+	errs, err := validator.Validate(schema, js)
+	if err != nil {
+		return nil, fmt.Errorf("failed validating doc: %s", err)
+	}
+
+	if len(errs) > 0 {
+		return nil, SchemaErrors(errs)
+	}
+
+	return ApplyOptions{}.marshal(root, findExplicitTags(doc, defaultCodec))
+}
@@ -0,0 +1,72 @@
+package yamlpatch_test
+
+import (
+	"encoding/json"
+
+	yamlpatch "github.com/krishicks/yaml-patch"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// requiredFieldsValidator treats the schema as a JSON list of top-level
+// fields that the document must contain
+type requiredFieldsValidator struct{}
+
+func (requiredFieldsValidator) Validate(schema, document []byte) ([]yamlpatch.SchemaError, error) {
+	var required []string
+	if err := json.Unmarshal(schema, &required); err != nil {
+		return nil, err
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(document, &doc); err != nil {
+		return nil, err
+	}
+
+	var errs []yamlpatch.SchemaError
+	for _, field := range required {
+		if _, ok := doc[field]; !ok {
+			errs = append(errs, yamlpatch.SchemaError{Path: "/" + field, Message: "is required"})
+		}
+	}
+
+	return errs, nil
+}
+
+var _ = Describe("Schema", func() {
+	Describe("ApplyAndValidate", func() {
+		var patch yamlpatch.Patch
+
+		BeforeEach(func() {
+			var err error
+			patch, err = yamlpatch.DecodePatch([]byte(`---
+- op: remove
+  path: /baz
+`))
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("returns the patched document when it conforms to the schema", func() {
+			actual, err := patch.ApplyAndValidate([]byte(`{foo: bar, baz: qux}`), []byte(`["foo"]`), requiredFieldsValidator{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(actual).To(MatchYAML(`foo: bar`))
+		})
+
+		It("returns schema errors with paths when the patched document does not conform", func() {
+			_, err := patch.ApplyAndValidate([]byte(`{foo: bar, baz: qux}`), []byte(`["foo", "baz"]`), requiredFieldsValidator{})
+			Expect(err).To(HaveOccurred())
+
+			schemaErrs, ok := err.(yamlpatch.SchemaErrors)
+			Expect(ok).To(BeTrue())
+			Expect(schemaErrs).To(Equal(yamlpatch.SchemaErrors{
+				{Path: "/baz", Message: "is required"},
+			}))
+		})
+
+		It("returns an error when the schema cannot be used", func() {
+			_, err := patch.ApplyAndValidate([]byte(`{foo: bar, baz: qux}`), []byte(`not json`), requiredFieldsValidator{})
+			Expect(err).To(MatchError(ContainSubstring("failed validating doc")))
+		})
+	})
+})
@@ -0,0 +1,168 @@
+package yamlpatch
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// semverOperators are the comparisons a value_semver can make, longest first
+// so that >= is not read as >
+var semverOperators = []string{">=", "<=", "==", "!=", ">", "<", "="}
+
+// semver is a semantic version. Missing minor and patch numbers are 0, and
+// build metadata is ignored.
+type semver struct {
+	numbers    [3]int
+	prerelease []string
+}
+
+// parseSemver parses a semantic version, with or without a leading v, such as
+// v1.2, 1.2.3 or 1.2.3-rc.1+build.5
+func parseSemver(s string) (semver, error) {
+	var v semver
+
+	rest := strings.TrimPrefix(s, "v")
+	if i := strings.Index(rest, "+"); i >= 0 {
+		rest = rest[:i]
+	}
+
+	if i := strings.Index(rest, "-"); i >= 0 {
+		v.prerelease = strings.Split(rest[i+1:], ".")
+		rest = rest[:i]
+
+		for _, id := range v.prerelease {
+			if id == "" {
+				return semver{}, fmt.Errorf("invalid semantic version %q", s)
+			}
+		}
+	}
+
+	parts := strings.Split(rest, ".")
+	if len(parts) > 3 {
+		return semver{}, fmt.Errorf("invalid semantic version %q", s)
+	}
+
+	for i, part := range parts {
+		n, err := parseIndex(part)
+		if err != nil {
+			return semver{}, fmt.Errorf("invalid semantic version %q", s)
+		}
+		v.numbers[i] = n
+	}
+
+	return v, nil
+}
+
+// compare returns -1, 0 or 1 as the version has a lower, the same or a higher
+// precedence than the other
+func (v semver) compare(other semver) int {
+	for i := range v.numbers {
+		if c := compareInts(v.numbers[i], other.numbers[i]); c != 0 {
+			return c
+		}
+	}
+
+	// A version without a prerelease has a higher precedence than one with
+	switch {
+	case len(v.prerelease) == 0 && len(other.prerelease) == 0:
+		return 0
+	case len(v.prerelease) == 0:
+		return 1
+	case len(other.prerelease) == 0:
+		return -1
+	}
+
+	for i := 0; i < len(v.prerelease) && i < len(other.prerelease); i++ {
+		if c := comparePrerelease(v.prerelease[i], other.prerelease[i]); c != 0 {
+			return c
+		}
+	}
+
+	return compareInts(len(v.prerelease), len(other.prerelease))
+}
+
+// comparePrerelease compares prerelease identifiers, numerically when both
+// are numbers. Numbers have a lower precedence than other identifiers.
+func comparePrerelease(a, b string) int {
+	na, errA := strconv.Atoi(a)
+	nb, errB := strconv.Atoi(b)
+
+	switch {
+	case errA == nil && errB == nil:
+		return compareInts(na, nb)
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+
+	return strings.Compare(a, b)
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+
+	return 0
+}
+
+// testSemver compares the version at a test operation's path with its
+// value_semver, an operator followed by a version, such as >= v1.2
+func testSemver(op *Operation, val *Node) error {
+	var operator string
+	for _, o := range semverOperators {
+		if strings.HasPrefix(op.ValueSemver, o) {
+			operator = o
+			break
+		}
+	}
+
+	if operator == "" {
+		return fmt.Errorf("test operation does not apply: invalid value_semver %q: expected an operator such as >= followed by a version", op.ValueSemver)
+	}
+
+	target, err := parseSemver(strings.TrimSpace(op.ValueSemver[len(operator):]))
+	if err != nil {
+		return fmt.Errorf("test operation does not apply: value_semver: %s", err)
+	}
+
+	s, ok := val.plain().(string)
+	if !ok {
+		return fmt.Errorf("test operation does not apply: value is not a version string: %s", op.Path)
+	}
+
+	actual, err := parseSemver(s)
+	if err != nil {
+		return fmt.Errorf("test operation does not apply: %s at %s", err, op.Path)
+	}
+
+	c := actual.compare(target)
+
+	var matched bool
+	switch operator {
+	case ">=":
+		matched = c >= 0
+	case "<=":
+		matched = c <= 0
+	case ">":
+		matched = c > 0
+	case "<":
+		matched = c < 0
+	case "==", "=":
+		matched = c == 0
+	case "!=":
+		matched = c != 0
+	}
+
+	if !matched {
+		return errors.New("test failed")
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package yamlpatch_test
+
+import (
+	"fmt"
+
+	yamlpatch "github.com/krishicks/yaml-patch"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("value_semver", func() {
+	test := func(version, comparison string) error {
+		patch, err := yamlpatch.DecodePatch([]byte(fmt.Sprintf(`[{op: test, path: /version, value_semver: %q}]`, comparison)))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = patch.Apply([]byte(fmt.Sprintf("version: %q\n", version)))
+		return err
+	}
+
+	DescribeTable("passes when the version satisfies the comparison",
+		func(version, comparison string) {
+			Expect(test(version, comparison)).To(Succeed())
+		},
+		Entry("at least, equal", "v1.2", ">= v1.2"),
+		Entry("at least, greater", "v1.10.0", ">=v1.9"),
+		Entry("less than", "1.2.3", "< 1.3"),
+		Entry("a prerelease is less than its release", "1.3.0-rc.1", "< 1.3.0"),
+		Entry("prereleases compare numerically", "1.3.0-rc.10", "> 1.3.0-rc.2"),
+		Entry("build metadata is ignored", "1.3.0+build.7", "== 1.3"),
+		Entry("not equal", "2.0", "!= 1.0"),
+	)
+
+	DescribeTable("fails when the version does not satisfy the comparison",
+		func(version, comparison string) {
+			Expect(test(version, comparison)).To(MatchError("test failed"))
+		},
+		Entry("at least", "v1.1.9", ">= v1.2"),
+		Entry("greater than", "1.2", "> 1.2.0"),
+		Entry("a release is not less than its prerelease", "1.3.0", "< 1.3.0-beta"),
+	)
+
+	It("fails clearly for an invalid version", func() {
+		Expect(test("latest", ">= 1.0")).To(MatchError(`test operation does not apply: invalid semantic version "latest" at /version`))
+		Expect(test("1.0", ">= 1.x")).To(MatchError(`test operation does not apply: value_semver: invalid semantic version "1.x"`))
+		Expect(test("1.0", "~1.0")).To(MatchError(`test operation does not apply: invalid value_semver "~1.0": expected an operator such as >= followed by a version`))
+	})
+
+	It("gates the operations of a block that rolls back", func() {
+		patch, err := yamlpatch.DecodePatch([]byte(`---
+- op: block
+  on_error: rollback
+  operations:
+  - op: test
+    path: /version
+    value_semver: ">= v1.2"
+  - op: add
+    path: /featureGate
+    value: true
+`))
+		Expect(err).NotTo(HaveOccurred())
+
+		actual, err := patch.Apply([]byte("version: v1.1\n"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(actual)).To(Equal("version: v1.1\n"))
+
+		actual, err = patch.Apply([]byte("version: v1.2.1\n"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(actual)).To(Equal("featureGate: true\nversion: v1.2.1\n"))
+	})
+})
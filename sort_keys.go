@@ -0,0 +1,84 @@
+package yamlpatch
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// trySortKeys records that the keys of the map at the operation's path, and
+// of every map within it, are emitted in lexical order, as SortKeys does for
+// the whole document. The keys are sorted when the document is marshaled, so
+// that keys later operations add to the map are sorted with the others.
+func trySortKeys(doc Container, op *Operation, ctx *applyContext) error {
+	con, key, err := findContainer(doc, &op.Path)
+	if err != nil {
+		return pathError(err, "yamlpatch sort_keys operation does not apply: doc is missing path: %s", op.Path)
+	}
+
+	val, err := con.Get(key)
+	if val == nil || err != nil {
+		return fmt.Errorf("yamlpatch sort_keys operation does not apply: doc is missing key: %s", op.Path)
+	}
+
+	if _, ok := val.Container().(*nodeMap); !ok {
+		return fmt.Errorf("yamlpatch sort_keys operation does not apply: value is not a map: %s", pathOrRoot(string(op.Path)))
+	}
+
+	path := string(op.Path)
+	for _, p := range ctx.sorted {
+		if p == path {
+			return nil
+		}
+	}
+
+	ctx.sorted = append(ctx.sorted, path)
+
+	return nil
+}
+
+// sortRecordedKeys replaces the value of each map that a sort_keys operation
+// named with its value with the keys sorted, returning a function that
+// restores them once the document has been marshaled. Maps within others are
+// sorted first, so that sorting the outer map leaves them sorted. A map that a
+// later operation removed is not sorted.
+func (c *applyContext) sortRecordedKeys(root *Node) func() {
+	paths := append([]string(nil), c.sorted...)
+	sort.SliceStable(paths, func(i, j int) bool {
+		return strings.Count(paths[i], "/") > strings.Count(paths[j], "/")
+	})
+
+	var saved []Node
+	var nodes []*Node
+
+	for _, path := range paths {
+		p := OpPath(path)
+
+		con, key, err := findContainer(&rootHolder{root: root}, &p)
+		if err != nil {
+			continue
+		}
+
+		node, err := con.Get(key)
+		if node == nil || err != nil {
+			continue
+		}
+
+		if _, ok := node.Container().(*nodeMap); !ok {
+			continue
+		}
+
+		saved = append(saved, *node)
+		nodes = append(nodes, node)
+
+		v := sortKeys(node.plain())
+		node.raw = &v
+		node.container = nil
+	}
+
+	return func() {
+		for i := len(nodes) - 1; i >= 0; i-- {
+			*nodes[i] = saved[i]
+		}
+	}
+}
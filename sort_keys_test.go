@@ -0,0 +1,62 @@
+package yamlpatch_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("sort_keys", func() {
+	DescribeTable("emits the keys of the map at its path in lexical order",
+		func(ops, doc, expected string) {
+			actual, err := applyPatch(ops, doc)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(actual).To(Equal(expected))
+		},
+		Entry("leaving other maps in natural order",
+			`[{op: sort_keys, path: /sorted}]`,
+			"sorted: {a10: 1, a2: 2}\nother: {a10: 1, a2: 2}\n",
+			"other:\n  a2: 2\n  a10: 1\nsorted:\n  a10: 1\n  a2: 2\n"),
+		Entry("within maps nested in it",
+			`[{op: sort_keys, path: /sorted}]`,
+			"sorted: {b: [{a10: 1, a2: 2}]}\n",
+			"sorted:\n  b:\n  - a10: 1\n    a2: 2\n"),
+		Entry("for the whole document",
+			`[{op: sort_keys, path: ""}]`,
+			"a10: 1\na2: 2\n",
+			"a10: 1\na2: 2\n"),
+		Entry("with keys later operations add",
+			`[{op: sort_keys, path: /sorted}, {op: add, path: /sorted/a10, value: 1}]`,
+			"sorted: {a2: 2}\n",
+			"sorted:\n  a10: 1\n  a2: 2\n"),
+		Entry("for maps both within and around another",
+			`[{op: sort_keys, path: /sorted/a10}, {op: sort_keys, path: /sorted}]`,
+			"sorted: {a10: {a10: 1, a2: 2}, a2: 2}\n",
+			"sorted:\n  a10:\n    a10: 1\n    a2: 2\n  a2: 2\n"),
+		Entry("unless a later operation removes the map",
+			`[{op: sort_keys, path: /sorted}, {op: remove, path: /sorted}]`,
+			"sorted: {a10: 1}\nother: {a10: 1, a2: 2}\n",
+			"other:\n  a2: 2\n  a10: 1\n"),
+	)
+
+	It("keeps the keys sorted by a block's operations only when the block succeeds", func() {
+		actual, err := applyPatch(`---
+- op: block
+  on_error: rollback
+  operations:
+  - {op: sort_keys, path: /sorted}
+  - {op: test, path: /sorted/a2, value: 3}
+`, "sorted: {a10: 1, a2: 2}\n")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actual).To(Equal("sorted:\n  a2: 2\n  a10: 1\n"))
+	})
+
+	DescribeTable("fails",
+		func(ops, message string) {
+			_, err := applyPatch(ops, "sorted: {a: 1}\nlist: [1]\n")
+			Expect(err).To(MatchError(message))
+		},
+		Entry("for a value that is not a map", `[{op: sort_keys, path: /list}]`, "yamlpatch sort_keys operation does not apply: value is not a map: /list"),
+		Entry("for a missing key", `[{op: sort_keys, path: /missing}]`, "yamlpatch sort_keys operation does not apply: doc is missing key: /missing"),
+	)
+})
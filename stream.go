@@ -0,0 +1,143 @@
+package yamlpatch
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// ApplyStream applies the patch to each document in a multi-document YAML
+// stream, returning the mutated documents as a stream
+func (p Patch) ApplyStream(stream []byte) ([]byte, error) {
+	return p.ApplyStreamWithOptions(stream, ApplyOptions{})
+}
+
+// ApplyStreamWithOptions applies the patch to each document in a
+// multi-document YAML stream using the given options, returning the mutated
+// documents as a stream. Empty documents are dropped from the output, as
+// are those the patch leaves unchanged when the options are OnlyChanged.
+// Directives and explicit end markers (...) are kept with the documents they
+// belong to. Operations with a target are performed only on the document it
+// names; the others are performed on every document.
+//
+// Copy and move operations can name documents of the stream by index in
+// both their from and path, as in $0/spec/x and $1/config/x, to copy or move
+// a value from one document to another. These are performed, in the order
+// the patch gives them, once the other operations have been performed on
+// every document. A failing one is reported as an *ApplyError with a
+// Document of -1.
+//
+// A document that fails does not stop the remaining documents from being
+// patched: the errors of every failing document are returned together as a
+// *MultiError, each an *ApplyError giving the index of the document and of
+// the failing operation. Unless the options are best-effort, no output is
+// returned when any document fails. When they are, the documents are returned
+// with the failing operations skipped, leaving out any that cannot be decoded.
+func (p Patch) ApplyStreamWithOptions(stream []byte, opts ApplyOptions) ([]byte, error) {
+	var out bytes.Buffer
+	errs := &MultiError{}
+
+	docs := splitDocuments(stream)
+
+	roots := make([]*Node, len(docs))
+	ctxs := make([]*applyContext, len(docs))
+
+	for i, doc := range docs {
+		iface, err := opts.codec().unmarshal(doc.text)
+		if err != nil {
+			errs.append(&ApplyError{Document: i, Operation: -1, Err: fmt.Errorf("failed unmarshaling document: %s", err)})
+			continue
+		}
+
+		if iface == nil {
+			continue
+		}
+
+		ctx := newApplyContext(opts)
+		ctx.document, ctx.last = i, len(docs)-1
+		ctx.stream = true
+
+		root, err := p.applyContext(&iface, ctx)
+		if err != nil {
+			if m, ok := err.(*MultiError); ok {
+				for _, e := range m.errs {
+					e.(*ApplyError).Document = i
+					errs.append(e)
+				}
+			} else {
+				errs.append(&ApplyError{Document: i, Operation: ctx.failed, Err: err})
+			}
+
+			if !opts.BestEffort || root == nil {
+				continue
+			}
+		}
+
+		roots[i], ctxs[i] = root, ctx
+	}
+
+	ctx := newApplyContext(opts)
+	for _, i := range p.order() {
+		op := p[i]
+		if !op.isCrossDocument() || op.Disabled {
+			continue
+		}
+
+		if opts.BeforeOperation != nil {
+			opts.BeforeOperation(i, op)
+		}
+
+		err := op.performCrossDocument(roots, ctx)
+
+		if opts.OnOperation != nil {
+			opts.OnOperation(i, op, err)
+		}
+
+		if err != nil {
+			errs.append(&ApplyError{Document: -1, Operation: i, Err: err})
+		}
+	}
+
+	for i, doc := range docs {
+		if roots[i] == nil {
+			continue
+		}
+
+		if opts.OnlyChanged && !ctxs[i].changed(roots[i], doc.text) {
+			continue
+		}
+
+		bs, err := ctxs[i].marshal(roots[i], doc.text, findExplicitTags(doc.text, ctxs[i].opts.codec()))
+		if err != nil {
+			return nil, err
+		}
+
+		out.Write(opts.framing(doc).frame(bs, true))
+	}
+
+	if len(errs.errs) > 0 && !opts.BestEffort {
+		return nil, errs
+	}
+
+	err := opts.checkOutputSize(out.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), errs.errorOrNil()
+}
+
+// changed returns whether the patch changed the document with the given
+// root, whose source is doc: whether its value differs from the source's, or
+// it was given comments or had its keys sorted
+func (c *applyContext) changed(root *Node, doc []byte) bool {
+	if len(c.comments) > 0 || len(c.sorted) > 0 {
+		return true
+	}
+
+	iface, err := c.opts.codec().unmarshal(doc)
+	if err != nil {
+		return true
+	}
+
+	return !root.Equal(NewNode(&iface))
+}
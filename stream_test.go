@@ -0,0 +1,431 @@
+package yamlpatch_test
+
+import (
+	yamlpatch "github.com/krishicks/yaml-patch"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Stream", func() {
+	Describe("ApplyStream", func() {
+		It("applies the patch to every document in the stream", func() {
+			patch, err := yamlpatch.DecodePatch([]byte(`---
+- op: add
+  path: /metadata/namespace
+  value: prod
+`))
+			Expect(err).NotTo(HaveOccurred())
+
+			actual, err := patch.ApplyStream([]byte(`---
+kind: Deployment
+metadata:
+  name: app
+---
+kind: Service
+metadata:
+  name: app
+`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(actual)).To(Equal(`---
+kind: Deployment
+metadata:
+  name: app
+  namespace: prod
+---
+kind: Service
+metadata:
+  name: app
+  namespace: prod
+`))
+		})
+
+		It("gives each document a value of its own", func() {
+			patch, err := yamlpatch.DecodePatch([]byte(`---
+- op: add
+  path: /list
+  value: [a]
+- op: add
+  path: /list/-
+  value: b
+`))
+			Expect(err).NotTo(HaveOccurred())
+
+			actual, err := patch.ApplyStream([]byte(`---
+name: one
+---
+name: two
+`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(actual)).To(Equal(`---
+list:
+- a
+- b
+name: one
+---
+list:
+- a
+- b
+name: two
+`))
+		})
+
+		It("skips operations gated by if_kind for documents of another kind", func() {
+			patch, err := yamlpatch.DecodePatch([]byte(`---
+- op: replace
+  path: /spec/replicas
+  value: 3
+  if_kind: Deployment
+`))
+			Expect(err).NotTo(HaveOccurred())
+
+			actual, err := patch.ApplyStream([]byte(`---
+kind: Deployment
+spec:
+  replicas: 1
+---
+kind: Service
+spec:
+  type: ClusterIP
+`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(actual)).To(Equal(`---
+kind: Deployment
+spec:
+  replicas: 3
+---
+kind: Service
+spec:
+  type: ClusterIP
+`))
+		})
+
+		It("returns an error naming the document that failed", func() {
+			patch, err := yamlpatch.DecodePatch([]byte(`---
+- op: remove
+  path: /spec
+`))
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = patch.ApplyStream([]byte(`---
+spec: {}
+---
+kind: Service
+`))
+			Expect(err).To(MatchError(ContainSubstring("document 1")))
+		})
+
+		It("preserves directives and explicit end markers", func() {
+			patch, err := yamlpatch.DecodePatch([]byte(`---
+- op: add
+  path: /b
+  value: 2
+`))
+			Expect(err).NotTo(HaveOccurred())
+
+			actual, err := patch.ApplyStream([]byte(`%YAML 1.1
+%TAG !e! tag:example.com,2000:
+--- # first
+a: 1
+...
+%YAML 1.1
+---
+c: 3
+...
+---
+d: 4
+`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(actual)).To(Equal(`%YAML 1.1
+%TAG !e! tag:example.com,2000:
+---
+a: 1
+b: 2
+...
+%YAML 1.1
+---
+b: 2
+c: 3
+...
+---
+b: 2
+d: 4
+`))
+		})
+
+		It("does not split documents at markers within block scalars", func() {
+			patch := yamlpatch.Patch{{Op: "add", Path: "/b", Value: nodeOf(2)}}
+
+			actual, err := patch.ApplyStream([]byte("---\nscript: |\n  ---\n  ...\n  %YAML 1.1\n  # not a comment\n---\nc: 3\n"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(actual)).To(Equal("---\nb: 2\nscript: |\n  ---\n  ...\n  %YAML 1.1\n  # not a comment\n---\nb: 2\nc: 3\n"))
+		})
+
+		It("strips directives with StripDirectives", func() {
+			patch := yamlpatch.Patch{{Op: "add", Path: "/b", Value: nodeOf(2)}}
+
+			actual, err := patch.ApplyStreamWithOptions([]byte("%YAML 1.1\n%TAG !e! tag:example.com,2000:\n---\na: 1\n...\n%YAML 1.1\n---\nc: 3\n"), yamlpatch.ApplyOptions{StripDirectives: true})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(actual)).To(Equal("---\na: 1\nb: 2\n...\n---\nb: 2\nc: 3\n"))
+		})
+
+		It("drops the byte order marks of documents concatenated from files that begin with one", func() {
+			patch := yamlpatch.Patch{{Op: "add", Path: "/b", Value: nodeOf(2)}}
+
+			actual, err := patch.ApplyStream([]byte("\ufeff%YAML 1.1\n---\na: 1\n\ufeff---\nc: 3\n"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(actual)).To(Equal("%YAML 1.1\n---\na: 1\nb: 2\n---\nb: 2\nc: 3\n"))
+		})
+
+		It("performs operations with a target only on the document it names", func() {
+			patch, err := yamlpatch.DecodePatch([]byte(`---
+- op: add
+  path: /first
+  value: true
+  target: first
+- op: add
+  path: /second
+  value: true
+  target: "1"
+- op: add
+  path: /last
+  value: true
+  target: last
+- op: add
+  path: /all
+  value: true
+`))
+			Expect(err).NotTo(HaveOccurred())
+
+			actual, err := patch.ApplyStream([]byte(`---
+a: 1
+---
+b: 2
+---
+c: 3
+`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(actual)).To(Equal(`---
+a: 1
+all: true
+first: true
+---
+all: true
+b: 2
+second: true
+---
+all: true
+c: 3
+last: true
+`))
+		})
+
+		It("treats a single document as both the first and the last", func() {
+			patch := yamlpatch.Patch{
+				{Op: "add", Path: "/first", Value: nodeOf(1), Target: "first"},
+				{Op: "add", Path: "/last", Value: nodeOf(2), Target: "last"},
+				{Op: "add", Path: "/other", Value: nodeOf(3), Target: "1"},
+			}
+
+			actual, err := patch.Apply([]byte("a: 0\n"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(actual)).To(Equal("a: 0\nfirst: 1\nlast: 2\n"))
+		})
+
+		It("rejects targets that are not first, last or an index", func() {
+			_, err := yamlpatch.DecodePatch([]byte(`[{op: add, path: /a, value: 1, target: middle}]`))
+			Expect(err).To(MatchError("yamlpatch add operation has an invalid target: middle"))
+		})
+	})
+
+	Describe("operations naming documents", func() {
+		apply := func(ops string, opts yamlpatch.ApplyOptions) (string, error) {
+			patch, err := yamlpatch.DecodePatch([]byte(ops))
+			Expect(err).NotTo(HaveOccurred())
+
+			actual, err := patch.ApplyStreamWithOptions([]byte(`---
+spec:
+  a: 1
+  b: 2
+---
+config:
+  z: 3
+`), opts)
+			return string(actual), err
+		}
+
+		It("moves a value from one document to another", func() {
+			actual, err := apply(`[{op: move, from: $0/spec/a, path: $1/config/a}]`, yamlpatch.ApplyOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(actual).To(Equal(`---
+spec:
+  b: 2
+---
+config:
+  a: 1
+  z: 3
+`))
+		})
+
+		It("does not overwrite an existing key of another document with NoClobber", func() {
+			_, err := apply(`[{op: copy, from: $0/spec/a, path: $1/config/z}]`, yamlpatch.ApplyOptions{NoClobber: true})
+			Expect(err).To(MatchError(ContainSubstring("yamlpatch copy operation does not apply: NoClobber does not allow overwriting existing key: $1/config/z")))
+
+			_, err = apply(`[{op: move, from: $0/spec/a, path: $0/spec/b}]`, yamlpatch.ApplyOptions{NoClobber: true})
+			Expect(err).To(MatchError(ContainSubstring("yamlpatch move operation does not apply: NoClobber does not allow overwriting existing key: $0/spec/b")))
+
+			actual, err := apply(`[{op: copy, from: $0/spec/a, path: $1/config/a}]`, yamlpatch.ApplyOptions{NoClobber: true})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(actual).To(ContainSubstring("config:\n  a: 1\n  z: 3\n"))
+		})
+
+		It("copies a value from one document to another", func() {
+			actual, err := apply(`[{op: copy, from: $0/spec, path: $1/spec}]`, yamlpatch.ApplyOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(actual).To(Equal(`---
+spec:
+  a: 1
+  b: 2
+---
+config:
+  z: 3
+spec:
+  a: 1
+  b: 2
+`))
+		})
+
+		It("inserts a value at an index of an array of another document", func() {
+			patch, err := yamlpatch.DecodePatch([]byte(`[{op: copy, from: $0/item, path: $1/items/0}]`))
+			Expect(err).NotTo(HaveOccurred())
+
+			actual, err := patch.ApplyStreamWithOptions([]byte("---\nitem: a\n---\nitems: [b, c]\n"), yamlpatch.ApplyOptions{NoClobber: true})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(actual)).To(Equal("---\nitem: a\n---\nitems:\n- a\n- b\n- c\n"))
+		})
+
+		It("performs them once the other operations have been performed on every document", func() {
+			actual, err := apply(`---
+- {op: move, from: $0/spec/w, path: $1/config/w}
+- {op: add, path: /spec/w, value: 0, target: first}
+`, yamlpatch.ApplyOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(actual).To(Equal(`---
+spec:
+  a: 1
+  b: 2
+---
+config:
+  w: 0
+  z: 3
+`))
+		})
+
+		It("moves a value within a single document", func() {
+			actual, err := apply(`[{op: move, from: $1/config/z, path: $1/z}]`, yamlpatch.ApplyOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(actual).To(Equal(`---
+spec:
+  a: 1
+  b: 2
+---
+config: {}
+z: 3
+`))
+		})
+
+		It("leaves both documents as they were when a move fails", func() {
+			actual, err := apply(`---
+- {op: move, from: $0/spec/a, path: $1/missing/a}
+- {op: add, path: /spec/v, value: 0, target: first}
+`, yamlpatch.ApplyOptions{BestEffort: true})
+			Expect(err).To(MatchError("operation 0: yamlpatch move operation does not apply: document 1 is missing destination path: /missing/a"))
+			Expect(actual).To(Equal(`---
+spec:
+  a: 1
+  b: 2
+  v: 0
+---
+config:
+  z: 3
+`))
+		})
+
+		It("returns no output when one fails", func() {
+			actual, err := apply(`[{op: copy, from: $0/spec, path: $2/spec}]`, yamlpatch.ApplyOptions{})
+			Expect(err).To(MatchError("operation 0: yamlpatch copy operation does not apply: stream has no document 2"))
+			Expect(actual).To(BeEmpty())
+		})
+
+		It("fails outside of a stream", func() {
+			patch, err := yamlpatch.DecodePatch([]byte(`[{op: copy, from: $0/a, path: $1/a}]`))
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = patch.Apply([]byte("a: 1\n"))
+			Expect(err).To(MatchError("yamlpatch copy operation does not apply: paths naming a document, such as $0/a, can only be used when applying a stream"))
+		})
+
+		DescribeTable("rejects operations that name documents in an unsupported way",
+			func(ops, message string) {
+				_, err := yamlpatch.DecodePatch([]byte(ops))
+				Expect(err).To(MatchError(message))
+			},
+			Entry("an op other than copy or move", `[{op: remove, path: $0/a}]`, "yamlpatch remove operation cannot name a document: only copy and move operations can"),
+			Entry("only one of from and path", `[{op: move, from: $0/a, path: /a}]`, "yamlpatch move operation names a document in only one of from and path"),
+			Entry("no index", `[{op: move, from: $x/a, path: $1/a}]`, "yamlpatch move operation has an invalid path: path $x/a does not begin with a document index such as $0"),
+		)
+	})
+
+	Describe("OnlyChanged", func() {
+		It("emits only the documents the patch changed, in order", func() {
+			patch, err := yamlpatch.DecodePatch([]byte(`---
+- {op: replace, path: /spec/replicas, value: 3, if_kind: Deployment}
+- {op: comment, path: /data, value: kept, if_kind: ConfigMap}
+`))
+			Expect(err).NotTo(HaveOccurred())
+
+			actual, err := patch.ApplyStreamWithOptions([]byte(`---
+kind: Deployment
+spec:
+  replicas: 1
+---
+kind: Service
+---
+kind: Deployment
+spec:
+  replicas: 3
+---
+kind: ConfigMap
+data: {}
+---
+kind: Deployment
+spec:
+  replicas: 2
+`), yamlpatch.ApplyOptions{OnlyChanged: true})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(actual)).To(Equal(`---
+kind: Deployment
+spec:
+  replicas: 3
+---
+# kept
+data: {}
+kind: ConfigMap
+---
+kind: Deployment
+spec:
+  replicas: 3
+`))
+		})
+
+		It("emits nothing when no document changed", func() {
+			patch, err := yamlpatch.DecodePatch([]byte(`[{op: replace, path: /a, value: 1}]`))
+			Expect(err).NotTo(HaveOccurred())
+
+			actual, err := patch.ApplyStreamWithOptions([]byte("---\na: 1\n---\na: 1\n"), yamlpatch.ApplyOptions{OnlyChanged: true})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(actual).To(BeEmpty())
+		})
+	})
+})
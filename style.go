@@ -0,0 +1,167 @@
+package yamlpatch
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// KeyQuoting is how the keys of maps are quoted in the output
+type KeyQuoting string
+
+const (
+	// QuoteNoKeys quotes only the keys that must be quoted to be read back
+	// as the same key, as go-yaml does. It is the default.
+	QuoteNoKeys KeyQuoting = ""
+
+	// QuoteAllKeys double-quotes every key that is a string
+	QuoteAllKeys KeyQuoting = "all"
+
+	// QuoteNonIdentifierKeys double-quotes the keys that are strings other
+	// than identifiers, which are made of ASCII letters, digits and
+	// underscores and do not begin with a digit
+	QuoteNonIdentifierKeys KeyQuoting = "non-identifier"
+)
+
+// identifier matches the keys that QuoteNonIdentifierKeys leaves unquoted
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// quotes returns whether the key quoting double-quotes the given string key
+func (q KeyQuoting) quotes(key string) bool {
+	switch q {
+	case QuoteAllKeys:
+		return true
+	case QuoteNonIdentifierKeys:
+		return !identifier.MatchString(key)
+	}
+
+	return false
+}
+
+// styled returns whether the options require styles to be set on individual
+// nodes of the output
+func (o ApplyOptions) styled() bool {
+	return o.QuoteStrings || o.QuoteKeys != QuoteNoKeys
+}
+
+// restyle re-emits a marshaled document with the styles required by the
+// options applied to its nodes and the given explicit tags restored. go-yaml
+// v2 cannot set the style or tag of an individual node, so the document is
+// decoded into a yaml.v3 Node tree, which keeps the order it was emitted in,
+// styled, and encoded again.
+func (o ApplyOptions) restyle(bs []byte, tags explicitTags) ([]byte, error) {
+	var doc yamlv3.Node
+	err := yamlv3.Unmarshal(bs, &doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed restyling doc: %s", err)
+	}
+
+	o.applyStyles(&doc)
+	if len(tags) > 0 {
+		tags.restore(&doc, "")
+	}
+
+	var buf bytes.Buffer
+	encoder := yamlv3.NewEncoder(&buf)
+	encoder.SetIndent(2)
+
+	err = encoder.Encode(&doc)
+	if err != nil {
+		return nil, err
+	}
+
+	err = encoder.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return compactSequences(buf.Bytes()), nil
+}
+
+// compactSequences returns the document yaml.v3 emitted with each sequence
+// that is the value of a map key dedented to the column of the key, as
+// go-yaml v2 emits it, so that styling the output does not re-indent it
+func compactSequences(bs []byte) []byte {
+	var doc yamlv3.Node
+	if yamlv3.Unmarshal(bs, &doc) != nil {
+		return bs
+	}
+
+	lines := bytes.SplitAfter(bs, []byte("\n"))
+	dedents := make([]int, len(lines))
+	findIndentedSequences(&doc, lines, dedents)
+
+	var buf bytes.Buffer
+	for i, line := range lines {
+		n := dedents[i]
+		if indent := len(line) - len(bytes.TrimLeft(line, " ")); n > indent {
+			n = indent
+		}
+
+		buf.Write(line[n:])
+	}
+
+	return buf.Bytes()
+}
+
+// findIndentedSequences adds to the dedent of each line the amount by which
+// the sequences within n that are values of map keys, and which the line is
+// part of, are indented beyond their keys
+func findIndentedSequences(n *yamlv3.Node, lines [][]byte, dedents []int) {
+	if n.Kind == yamlv3.MappingNode {
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			if value.Kind != yamlv3.SequenceNode || value.Style&yamlv3.FlowStyle != 0 || len(value.Content) == 0 {
+				continue
+			}
+
+			first := value.Content[0].Line - 1
+			if first <= key.Line-1 || first >= len(lines) {
+				continue
+			}
+
+			keyIndent := key.Column - 1
+			shift := len(lines[first]) - len(bytes.TrimLeft(lines[first], " ")) - keyIndent
+			if shift <= 0 {
+				continue
+			}
+
+			for j := key.Line; j < len(lines); j++ {
+				line := bytes.TrimRight(lines[j], "\n")
+				if len(bytes.TrimSpace(line)) > 0 && len(line)-len(bytes.TrimLeft(line, " ")) <= keyIndent {
+					break
+				}
+
+				dedents[j] += shift
+			}
+		}
+	}
+
+	for _, child := range n.Content {
+		findIndentedSequences(child, lines, dedents)
+	}
+}
+
+func (o ApplyOptions) applyStyles(n *yamlv3.Node) {
+	switch n.Kind {
+	case yamlv3.DocumentNode, yamlv3.SequenceNode:
+		for _, child := range n.Content {
+			o.applyStyles(child)
+		}
+	case yamlv3.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key := n.Content[i]
+			if key.Kind == yamlv3.ScalarNode && key.ShortTag() == "!!str" && o.QuoteKeys.quotes(key.Value) {
+				key.Style = yamlv3.DoubleQuotedStyle
+			}
+
+			o.applyStyles(n.Content[i+1])
+		}
+	case yamlv3.ScalarNode:
+		if o.QuoteStrings && n.ShortTag() == "!!str" {
+			n.Style = yamlv3.DoubleQuotedStyle
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package yamlpatch
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// explicitTag is a scalar that was written in the source document with an
+// explicit standard tag, e.g. "!!binary aGVsbG8=". go-yaml v2 decodes such
+// scalars into plain values and does not re-emit their tags, so that a
+// !!binary scalar would be emitted decoded and a !!float 1 as an int.
+type explicitTag struct {
+	tag   string
+	value string
+	style yamlv3.Style
+
+	// emitted and emittedTag are the value and resolved tag of the scalar as
+	// go-yaml v2 emits it once decoded, by which the scalar is recognized in
+	// the output when it is untouched
+	emitted    string
+	emittedTag string
+}
+
+// explicitTags are the explicitly tagged scalars of a document, keyed by their
+// pointers
+type explicitTags map[string]explicitTag
+
+// findExplicitTags returns the scalars within the document that have explicit
+// standard tags, as the given codec decodes and emits them. Documents without
+// any are not decoded a second time.
+func findExplicitTags(doc []byte, codec yamlCodec) explicitTags {
+	if !bytes.Contains(doc, []byte("!!")) {
+		return nil
+	}
+
+	var n yamlv3.Node
+	if yamlv3.Unmarshal(doc, &n) != nil {
+		return nil
+	}
+
+	tags := explicitTags{}
+	tags.find(&n, "", codec)
+
+	if len(tags) == 0 {
+		return nil
+	}
+
+	return tags
+}
+
+func (t explicitTags) find(n *yamlv3.Node, path string, codec yamlCodec) {
+	switch n.Kind {
+	case yamlv3.DocumentNode:
+		for _, child := range n.Content {
+			t.find(child, path, codec)
+		}
+	case yamlv3.SequenceNode:
+		for i, child := range n.Content {
+			t.find(child, path+"/"+strconv.Itoa(i), codec)
+		}
+	case yamlv3.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key := n.Content[i]
+			if key.Kind != yamlv3.ScalarNode || key.Value == "<<" {
+				continue
+			}
+
+			t.find(n.Content[i+1], path+"/"+encodePatchKey(key.Value), codec)
+		}
+	case yamlv3.ScalarNode:
+		if n.Style&yamlv3.TaggedStyle == 0 || !strings.HasPrefix(n.ShortTag(), "!!") {
+			return
+		}
+
+		emitted, ok := emittedScalar(n, codec)
+		if !ok {
+			return
+		}
+
+		t[path] = explicitTag{
+			tag:        n.Tag,
+			value:      n.Value,
+			style:      n.Style,
+			emitted:    emitted.Value,
+			emittedTag: emitted.ShortTag(),
+		}
+	}
+}
+
+// emittedScalar returns the scalar as it appears in the output after being
+// decoded and emitted by the codec
+func emittedScalar(n *yamlv3.Node, codec yamlCodec) (*yamlv3.Node, bool) {
+	src, err := yamlv3.Marshal(n)
+	if err != nil {
+		return nil, false
+	}
+
+	v, err := codec.unmarshal(src)
+	if err != nil {
+		return nil, false
+	}
+
+	out, err := codec.marshal(v)
+	if err != nil {
+		return nil, false
+	}
+
+	var emitted yamlv3.Node
+	if yamlv3.Unmarshal(out, &emitted) != nil || len(emitted.Content) != 1 {
+		return nil, false
+	}
+
+	return emitted.Content[0], true
+}
+
+// restore gives every untouched scalar in the output its original tag
+func (t explicitTags) restore(n *yamlv3.Node, path string) {
+	switch n.Kind {
+	case yamlv3.DocumentNode:
+		for _, child := range n.Content {
+			t.restore(child, path)
+		}
+	case yamlv3.SequenceNode:
+		for i, child := range n.Content {
+			t.restore(child, path+"/"+strconv.Itoa(i))
+		}
+	case yamlv3.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			t.restore(n.Content[i+1], path+"/"+encodePatchKey(n.Content[i].Value))
+		}
+	case yamlv3.ScalarNode:
+		if tagged, ok := t.restoreScalar(n, path, ""); ok {
+			n.Tag, n.Value, n.Style = tagged.Tag, tagged.Value, tagged.Style
+		}
+	}
+}
+
+// restoreScalar is the edit that gives a scalar of the output its original
+// tag when it is untouched
+func (t explicitTags) restoreScalar(n *yamlv3.Node, path, text string) (*yamlv3.Node, bool) {
+	tag, ok := t[path]
+	if !ok || n.Value != tag.emitted || n.ShortTag() != tag.emittedTag {
+		return nil, false
+	}
+
+	return &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: tag.tag, Value: tag.value, Style: tag.style}, true
+}
@@ -0,0 +1,43 @@
+package yamlpatch
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Targets of operations within a stream
+const (
+	targetFirst = "first"
+	targetLast  = "last"
+)
+
+// targets returns whether the operation targets the document at the given
+// index of a stream whose last document is at index last
+func (o *Operation) targets(document, last int) bool {
+	switch o.Target {
+	case "":
+		return true
+	case targetFirst:
+		return document == 0
+	case targetLast:
+		return document == last
+	}
+
+	i, err := strconv.Atoi(o.Target)
+	return err == nil && i == document
+}
+
+// validateTarget returns an error when the operation's target is neither
+// first, last nor the index of a document
+func (o *Operation) validateTarget() error {
+	switch o.Target {
+	case "", targetFirst, targetLast:
+		return nil
+	}
+
+	if i, err := strconv.Atoi(o.Target); err != nil || i < 0 {
+		return fmt.Errorf("yamlpatch %s operation has an invalid target: %s", o.Op, o.Target)
+	}
+
+	return nil
+}
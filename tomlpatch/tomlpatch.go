@@ -0,0 +1,45 @@
+// Package tomlpatch decodes yamlpatch patches written in TOML. It is kept
+// separate from yamlpatch so that users who only author operations in YAML do
+// not depend on a TOML parser.
+package tomlpatch
+
+import (
+	"fmt"
+
+	yamlpatch "github.com/ACCELERATOR-SANDBOX/yaml-patch"
+	"github.com/BurntSushi/toml"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// DecodePatch decodes the passed TOML document into a patch. TOML documents
+// must be tables, so operations are given as an array of tables named
+// operations:
+//
+//	[[operations]]
+//	op = "add"
+//	path = "/baz/waldo"
+//	value = "fred"
+//
+// The operations have the same fields and semantics as in YAML. TOML has no
+// null, so null values cannot be expressed.
+func DecodePatch(bs []byte) (yamlpatch.Patch, error) {
+	var doc struct {
+		Operations []map[string]interface{} `toml:"operations"`
+	}
+
+	_, err := toml.Decode(string(bs), &doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed decoding TOML patch: %s", err)
+	}
+
+	if len(doc.Operations) == 0 {
+		return nil, nil
+	}
+
+	ops, err := yaml.Marshal(doc.Operations)
+	if err != nil {
+		return nil, err
+	}
+
+	return yamlpatch.DecodePatch(ops)
+}
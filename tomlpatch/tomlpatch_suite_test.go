@@ -0,0 +1,13 @@
+package tomlpatch_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestTomlPatch(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "TomlPatch Suite")
+}
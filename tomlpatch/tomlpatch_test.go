@@ -0,0 +1,48 @@
+package tomlpatch_test
+
+import (
+	yamlpatch "github.com/ACCELERATOR-SANDBOX/yaml-patch"
+	"github.com/ACCELERATOR-SANDBOX/yaml-patch/tomlpatch"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DecodePatch", func() {
+	It("decodes operations from an array of tables", func() {
+		patch, err := tomlpatch.DecodePatch([]byte(`
+[[operations]]
+op = "add"
+path = "/baz/waldo"
+value = "fred"
+
+[[operations]]
+op = "replace"
+path = "/spec"
+value = { replicas = 3, args = ["a", "b"] }
+`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(patch).To(HaveLen(2))
+
+		actual, err := patch.Apply([]byte(`{baz: {}, spec: {}}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actual).To(MatchYAML(`
+baz:
+  waldo: fred
+spec:
+  replicas: 3
+  args: [a, b]
+`))
+	})
+
+	It("returns an empty patch when given no operations", func() {
+		patch, err := tomlpatch.DecodePatch(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(patch).To(Equal(yamlpatch.Patch(nil)))
+	})
+
+	It("returns an error when given invalid TOML", func() {
+		_, err := tomlpatch.DecodePatch([]byte(`[[operations]`))
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,84 @@
+package yamlpatch
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Transforms
+const (
+	transformTrim  = "trim"
+	transformLower = "lower"
+	transformUpper = "upper"
+	transformTitle = "title"
+	transformRegex = "regex"
+)
+
+// tryTransform replaces the string at the operation's path with the result of
+// applying the operation's transform to it
+func tryTransform(doc Container, op *Operation) error {
+	con, key, err := findContainer(doc, &op.Path)
+	if err != nil {
+		return pathError(err, "yamlpatch transform operation does not apply: doc is missing path: %s", op.Path)
+	}
+
+	val, err := con.Get(key)
+	if val == nil || err != nil {
+		return fmt.Errorf("yamlpatch transform operation does not apply: doc is missing key: %s", op.Path)
+	}
+
+	transformed, err := transformNode(op, val)
+	if err != nil {
+		return fmt.Errorf("yamlpatch transform operation does not apply: %s: %s", err, op.Path)
+	}
+
+	return con.Set(key, transformed)
+}
+
+// transformNode returns a new Node holding the result of applying the
+// operation's transform to the string value of the given node
+func transformNode(op *Operation, n *Node) (*Node, error) {
+	s, ok := n.plain().(string)
+	if !ok {
+		return nil, fmt.Errorf("cannot transform a value that is not a string")
+	}
+
+	var v interface{}
+	switch op.Transform {
+	case transformTrim:
+		v = strings.TrimSpace(s)
+	case transformLower:
+		v = strings.ToLower(s)
+	case transformUpper:
+		v = strings.ToUpper(s)
+	case transformTitle:
+		v = title(s)
+	case transformRegex:
+		re, err := regexp.Compile(op.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern: %s", err)
+		}
+		v = re.ReplaceAllString(s, op.Replacement)
+	default:
+		return nil, fmt.Errorf("unknown transform '%s'", op.Transform)
+	}
+
+	return NewNode(&v), nil
+}
+
+// title returns s with the first letter of each word in upper case
+func title(s string) string {
+	prev := ' '
+
+	return strings.Map(func(r rune) rune {
+		defer func() { prev = r }()
+
+		if unicode.IsSpace(prev) || unicode.IsPunct(prev) {
+			return unicode.ToTitle(r)
+		}
+
+		return r
+	}, s)
+}
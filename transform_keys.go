@@ -0,0 +1,157 @@
+package yamlpatch
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Key transforms
+const (
+	keyTransformCamelToSnake = "camelToSnake"
+	keyTransformSnakeToCamel = "snakeToCamel"
+	keyTransformLower        = "lower"
+	keyTransformUpper        = "upper"
+)
+
+// tryTransformKeys renames every key of the maps within the value at the
+// operation's path, at any depth, with the result of applying the operation's
+// key transform to it. Keys that are not strings are left as they are.
+func tryTransformKeys(doc Container, op *Operation) error {
+	fn, err := keyTransform(op.Transform)
+	if err != nil {
+		return fmt.Errorf("yamlpatch transform_keys operation does not apply: %s", err)
+	}
+
+	con, key, err := findContainer(doc, &op.Path)
+	if err != nil {
+		return pathError(err, "yamlpatch transform_keys operation does not apply: doc is missing path: %s", op.Path)
+	}
+
+	val, err := con.Get(key)
+	if val == nil || err != nil {
+		return fmt.Errorf("yamlpatch transform_keys operation does not apply: doc is missing key: %s", op.Path)
+	}
+
+	v, err := transformKeys(val.plain(), fn, string(op.Path))
+	if err != nil {
+		return fmt.Errorf("yamlpatch transform_keys operation does not apply: %s", err)
+	}
+
+	return con.Set(key, NewNode(&v))
+}
+
+// keyTransform returns the function of the named key transform
+func keyTransform(name string) (func(string) string, error) {
+	switch name {
+	case keyTransformCamelToSnake:
+		return camelToSnake, nil
+	case keyTransformSnakeToCamel:
+		return snakeToCamel, nil
+	case keyTransformLower:
+		return strings.ToLower, nil
+	case keyTransformUpper:
+		return strings.ToUpper, nil
+	}
+
+	return nil, fmt.Errorf("unknown key transform '%s'", name)
+}
+
+// transformKeys returns a copy of the decoded value with the string keys of
+// its maps transformed. It is an error for two keys of a map to be
+// transformed to the same key.
+func transformKeys(v interface{}, fn func(string) string, path string) (interface{}, error) {
+	switch it := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[interface{}]interface{}, len(it))
+		from := make(map[interface{}]interface{}, len(it))
+
+		for _, k := range sortedKeys(it) {
+			newKey := k
+			if s, ok := k.(string); ok {
+				newKey = fn(s)
+			}
+
+			if other, ok := from[newKey]; ok {
+				return nil, fmt.Errorf("keys %v and %v both transform to %v at %s", other, k, newKey, pathOrRoot(path))
+			}
+			from[newKey] = k
+
+			child, err := transformKeys(it[k], fn, path+"/"+encodePatchKey(fmt.Sprint(newKey)))
+			if err != nil {
+				return nil, err
+			}
+			m[newKey] = child
+		}
+
+		return m, nil
+	case []interface{}:
+		s := make([]interface{}, len(it))
+		for i, elem := range it {
+			child, err := transformKeys(elem, fn, fmt.Sprintf("%s/%d", path, i))
+			if err != nil {
+				return nil, err
+			}
+			s[i] = child
+		}
+
+		return s, nil
+	}
+
+	return v, nil
+}
+
+// pathOrRoot returns the path, or / for the empty path of the whole document
+func pathOrRoot(path string) string {
+	if path == "" {
+		return "/"
+	}
+
+	return path
+}
+
+// camelToSnake returns the camelCase key in snake_case, keeping runs of
+// capitals together, so that "httpServerURL" becomes "http_server_url"
+func camelToSnake(s string) string {
+	runes := []rune(s)
+
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteRune('_')
+			}
+		}
+
+		b.WriteRune(unicode.ToLower(r))
+	}
+
+	return b.String()
+}
+
+// snakeToCamel returns the snake_case key in camelCase, so that
+// "http_server_url" becomes "httpServerUrl". Leading underscores are kept.
+func snakeToCamel(s string) string {
+	trimmed := strings.TrimLeft(s, "_")
+	prefix := s[:len(s)-len(trimmed)]
+
+	parts := strings.Split(trimmed, "_")
+
+	var b strings.Builder
+	b.WriteString(prefix)
+	for i, part := range parts {
+		if i == 0 || part == "" {
+			b.WriteString(part)
+			continue
+		}
+
+		runes := []rune(part)
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+
+	return b.String()
+}
@@ -0,0 +1,68 @@
+package yamlpatch_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("transform_keys", func() {
+	It("transforms every key of the document with the empty path", func() {
+		actual, err := applyPatch(`[{op: transform_keys, path: "", transform: camelToSnake}]`, `---
+apiVersion: v1
+spec:
+  maxReplicas: 3
+  containers:
+  - imagePullPolicy: Always
+    httpServerURL: example.com
+`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actual).To(Equal(`api_version: v1
+spec:
+  containers:
+  - http_server_url: example.com
+    image_pull_policy: Always
+  max_replicas: 3
+`))
+	})
+
+	It("transforms only the keys under the path", func() {
+		actual, err := applyPatch(`[{op: transform_keys, path: /spec, transform: snakeToCamel}]`, `---
+top_level: 1
+spec:
+  max_replicas: 3
+  _private_key: 4
+`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actual).To(Equal(`spec:
+  _privateKey: 4
+  maxReplicas: 3
+top_level: 1
+`))
+	})
+
+	DescribeTable("transforms",
+		func(transform, doc, expected string) {
+			actual, err := applyPatch(`[{op: transform_keys, path: "", transform: `+transform+`}]`, doc)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(actual).To(Equal(expected))
+		},
+		Entry("lower", "lower", "Name: a\nMeta: {Label: b}\n", "meta:\n  label: b\nname: a\n"),
+		Entry("upper", "upper", "name: a\n", "NAME: a\n"),
+		Entry("leaves values and non-string keys alone", "upper", "1: camelCase\n", "1: camelCase\n"),
+	)
+
+	It("fails when two keys transform to the same key", func() {
+		_, err := applyPatch(`[{op: transform_keys, path: /spec, transform: camelToSnake}]`, `---
+spec:
+  maxReplicas: 3
+  max_replicas: 4
+`)
+		Expect(err).To(MatchError("yamlpatch transform_keys operation does not apply: keys maxReplicas and max_replicas both transform to max_replicas at /spec"))
+	})
+
+	It("fails for an unknown transform", func() {
+		_, err := applyPatch(`[{op: transform_keys, path: "", transform: kebab}]`, "a: 1\n")
+		Expect(err).To(MatchError("yamlpatch transform_keys operation does not apply: unknown key transform 'kebab'"))
+	})
+})
@@ -0,0 +1,70 @@
+package yamlpatch
+
+import "fmt"
+
+// tryUpsert performs an add operation with a key. The value is a map, or a
+// list of maps that are each upserted in turn, into the array at the path.
+func tryUpsert(doc Container, op *Operation) error {
+	con, key, err := findContainer(doc, &op.Path)
+	if err != nil {
+		return pathError(err, "yamlpatch add operation does not apply: doc is missing path: %s", op.Path)
+	}
+
+	target, err := con.Get(key)
+	if target == nil || err != nil {
+		return fmt.Errorf("yamlpatch add operation does not apply: doc is missing key: %s", op.Path)
+	}
+
+	ary, ok := target.Container().(*nodeSlice)
+	if !ok {
+		return fmt.Errorf("yamlpatch add operation with key %s requires an array at the path: %s", op.Key, op.Path)
+	}
+
+	if op.Value.Empty() {
+		return fmt.Errorf("yamlpatch add operation with key %s requires a value: %s", op.Key, op.Path)
+	}
+
+	vals := []*Node{op.Value}
+	if list, ok := op.Value.Container().(*nodeSlice); ok {
+		vals = *list
+	}
+
+	for _, val := range vals {
+		err := upsert(ary, op.Key, val)
+		if err != nil {
+			return fmt.Errorf("yamlpatch add operation does not apply: %s: %s", err, op.Path)
+		}
+	}
+
+	return nil
+}
+
+// upsert replaces the first element of the array whose key has the same value
+// as the given map's, or appends the map when no element does
+func upsert(ary *nodeSlice, key string, val *Node) error {
+	m, ok := val.Container().(*nodeMap)
+	if !ok {
+		return fmt.Errorf("value to upsert by %s is not a map", key)
+	}
+
+	want, _ := m.Get(key)
+	if want == nil {
+		return fmt.Errorf("value to upsert is missing the key %s", key)
+	}
+
+	for i, elem := range *ary {
+		em, ok := elem.Container().(*nodeMap)
+		if !ok {
+			continue
+		}
+
+		got, _ := em.Get(key)
+		if got != nil && got.Equal(want) {
+			(*ary)[i] = val
+			return nil
+		}
+	}
+
+	*ary = append(*ary, val)
+	return nil
+}
@@ -0,0 +1,47 @@
+package yamlpatch
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Validate checks the patch against the document without modifying either,
+// returning every problem found rather than only the first. Each operation
+// is checked for malformed paths and then performed, in the order the patch
+// would apply it, against a copy of the document as the operations before it
+// left it, so that a path an earlier operation creates is not reported as
+// missing. Each error is an *ApplyError naming the operation, and they are
+// ordered by operation. When the document cannot be decoded, that is the only
+// error.
+func (p Patch) Validate(doc []byte) []error {
+	iface, err := defaultCodec.unmarshal(doc)
+	if err != nil {
+		return []error{fmt.Errorf("failed unmarshaling doc: %s\n\n%s", string(doc), err)}
+	}
+
+	var errs []error
+
+	// Operations with malformed paths are reported as such and disabled
+	// rather than being performed, which would fail again
+	checked := MergePatches(p)
+	for i := range checked {
+		err := checked[i].validate()
+		if err != nil {
+			errs = append(errs, &ApplyError{Document: -1, Operation: i, Err: err})
+			checked[i].Disabled = true
+		}
+	}
+
+	_, err = checked.apply(&iface, ApplyOptions{BestEffort: true})
+	if multi, ok := err.(*MultiError); ok {
+		errs = append(errs, multi.Errors()...)
+	} else if err != nil {
+		errs = append(errs, &ApplyError{Document: -1, Operation: -1, Err: err})
+	}
+
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs[i].(*ApplyError).Operation < errs[j].(*ApplyError).Operation
+	})
+
+	return errs
+}
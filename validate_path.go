@@ -0,0 +1,99 @@
+package yamlpatch
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidatePath returns an error describing how the path is malformed, or nil
+// when it is well-formed. A well-formed path is either the empty path "",
+// referring to the whole document, or begins with '/' and has no empty
+// segments, except for the leading empty segment of a recursive search such
+// as //image. A '~' must be escaped as "~0" and a '/' within a key as "~1".
+// ValidatePath does not check that the path exists in any document.
+func ValidatePath(path string) error {
+	if path == "" {
+		return nil
+	}
+
+	if !strings.HasPrefix(path, "/") {
+		return fmt.Errorf("path %s is missing leading '/'", path)
+	}
+
+	segments := strings.Split(path, "/")[1:]
+	if strings.HasPrefix(path, "//") && len(segments) > 1 {
+		segments = segments[1:]
+	}
+
+	for i, segment := range segments {
+		if segment == "" {
+			return fmt.Errorf("path %s has an empty segment at position %d", path, i)
+		}
+
+		for j := 0; j < len(segment); j++ {
+			if segment[j] != '~' {
+				continue
+			}
+
+			if j == len(segment)-1 {
+				return fmt.Errorf("path %s has an unescaped '~' at the end of segment %d", path, i)
+			}
+
+			if next := segment[j+1]; next != '0' && next != '1' {
+				return fmt.Errorf("path %s has an invalid escape '~%c' in segment %d", path, next, i)
+			}
+
+			j++
+		}
+	}
+
+	return nil
+}
+
+// validate returns an error for the first of the operation's paths that is
+// malformed, or for its target, on_existing or decode
+func (o *Operation) validate() error {
+	err := o.validateDocumentPaths()
+	if err != nil {
+		return err
+	}
+
+	err = o.validatePaths()
+	if err != nil {
+		return err
+	}
+
+	err = o.validateTarget()
+	if err != nil {
+		return err
+	}
+
+	err = o.validateOnExisting()
+	if err != nil {
+		return err
+	}
+
+	return o.validateDecode()
+}
+
+// validatePaths returns an error naming the first of the operation's paths
+// that is malformed
+func (o *Operation) validatePaths() error {
+	paths := []OpPath{o.Path, o.From, o.Scope, o.ForEach}
+	paths = append(paths, o.Paths...)
+	paths = append(paths, o.Fallback...)
+
+	for _, path := range paths {
+		if isDocumentPath(path) {
+			// Validated with the document they name
+			continue
+		}
+
+		err := ValidatePath(string(path))
+		if err != nil {
+			return fmt.Errorf("yamlpatch %s operation has an invalid path: %s", o.Op, err)
+		}
+	}
+
+	return nil
+}
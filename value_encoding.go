@@ -0,0 +1,57 @@
+package yamlpatch
+
+import (
+	"encoding/base64"
+	"fmt"
+)
+
+const encodingBase64 = "base64"
+
+// encodeValue returns a new Node holding the string value of the given node
+// encoded with the named encoding
+func encodeValue(encoding string, n *Node) (*Node, error) {
+	s, ok := n.plain().(string)
+	if !ok {
+		return nil, fmt.Errorf("cannot %s encode a value that is not a string", encoding)
+	}
+
+	switch encoding {
+	case encodingBase64:
+		var v interface{} = base64.StdEncoding.EncodeToString([]byte(s))
+		return NewNode(&v), nil
+	}
+
+	return nil, fmt.Errorf("unknown encoding: %s", encoding)
+}
+
+// validateDecode returns an error for a decode on an operation other than
+// test, the only operation that reads a value from the document to compare
+func (o *Operation) validateDecode() error {
+	if o.Decode != "" && o.Op != opTest {
+		return fmt.Errorf("yamlpatch %s operation cannot have decode: only test operations can", o.Op)
+	}
+
+	return nil
+}
+
+// decodeValue returns a new Node holding the string value of the given node
+// decoded with the named encoding
+func decodeValue(encoding string, n *Node) (*Node, error) {
+	s, ok := n.plain().(string)
+	if !ok {
+		return nil, fmt.Errorf("cannot %s decode a value that is not a string", encoding)
+	}
+
+	switch encoding {
+	case encodingBase64:
+		bs, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, err
+		}
+
+		var v interface{} = string(bs)
+		return NewNode(&v), nil
+	}
+
+	return nil, fmt.Errorf("unknown encoding: %s", encoding)
+}
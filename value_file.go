@@ -0,0 +1,36 @@
+package yamlpatch
+
+import (
+	"fmt"
+	"io/ioutil"
+)
+
+// readValueFile returns the value an operation reads from its value_from_file.
+// The file's contents are a string unless the operation's format is yaml or
+// json, in which case they are parsed as a document by the given codec.
+func readValueFile(op *Operation, codec yamlCodec) (*Node, error) {
+	if !op.Value.Empty() {
+		return nil, fmt.Errorf("value and value_from_file are mutually exclusive")
+	}
+
+	bs, err := ioutil.ReadFile(op.ValueFromFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading value_from_file: %s", err)
+	}
+
+	var v interface{}
+
+	switch op.Format {
+	case "":
+		v = string(bs)
+	case formatYAML, formatJSON:
+		v, err = codec.unmarshal(bs)
+		if err != nil {
+			return nil, fmt.Errorf("failed parsing value_from_file %s: %s", op.ValueFromFile, err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown value_from_file format: %s", op.Format)
+	}
+
+	return NewNode(&v), nil
+}
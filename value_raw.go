@@ -0,0 +1,189 @@
+package yamlpatch
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// rawValue is a value given as value_raw, whose text is emitted in the output
+// as written instead of being marshaled
+type rawValue struct {
+	node *Node
+	text string
+
+	// value is the value parsed from the text, with which the node's value
+	// is compared to find whether a later operation modified it
+	value interface{}
+
+	// collection is whether the text is a block map or sequence, which begins
+	// on the line after a key rather than on the key's line
+	collection bool
+}
+
+// readRawValue parses the operation's value_raw into its value, recording its
+// text so that it can be spliced into the output
+func readRawValue(op *Operation, ctx *applyContext) (*Node, error) {
+	if !op.Value.Empty() || op.ValueFromFile != "" {
+		return nil, fmt.Errorf("value_raw is mutually exclusive with value and value_from_file")
+	}
+
+	text := dedent(op.ValueRaw)
+
+	v, err := ctx.opts.codec().unmarshal([]byte(text))
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing value_raw: %s", err)
+	}
+
+	node := NewNode(&v)
+
+	_, isMap := v.(map[interface{}]interface{})
+	_, isSlice := v.([]interface{})
+	flow := strings.HasPrefix(text, "{") || strings.HasPrefix(text, "[")
+
+	ctx.raws = append(ctx.raws, rawValue{
+		node:       node,
+		text:       text,
+		value:      deepCopy(v),
+		collection: (isMap || isSlice) && !flow,
+	})
+
+	return node, nil
+}
+
+// dedent removes the indentation common to the non-blank lines of the text,
+// and any leading and trailing blank lines
+func dedent(text string) string {
+	lines := strings.Split(strings.Trim(text, "\n"), "\n")
+
+	common := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		if common == -1 || indent < common {
+			common = indent
+		}
+	}
+
+	for i, line := range lines {
+		if len(line) >= common {
+			lines[i] = line[common:]
+		} else {
+			lines[i] = ""
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// marshal marshals the document patched from the source document doc,
+// emitting the text of each raw value that is still in it in place of its
+// value. A raw value that a later operation modified is marshaled like any
+// other.
+func (c *applyContext) marshal(root *Node, doc []byte, tags explicitTags) ([]byte, error) {
+	// Operations such as unflatten can nest the document more deeply than
+	// it was, so it is checked again before it is marshaled
+	err := checkDepth(root.plain(), c.opts.maxDepth())
+	if err != nil {
+		return nil, err
+	}
+
+	var raws []rawValue
+	var placeholders []string
+
+	for i, raw := range c.raws {
+		if !reflect.DeepEqual(raw.node.plain(), raw.value) {
+			continue
+		}
+
+		placeholder := fmt.Sprintf("__yamlpatch_value_raw_%d__", i)
+		saved := *raw.node
+
+		var v interface{} = placeholder
+		raw.node.raw = &v
+		raw.node.container = nil
+		defer func(node *Node) { *node = saved }(raw.node)
+
+		raws = append(raws, raw)
+		placeholders = append(placeholders, placeholder)
+	}
+
+	if len(c.sorted) > 0 {
+		defer c.sortRecordedKeys(root)()
+	}
+
+	bs, err := c.opts.marshal(root, tags)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(c.comments) > 0 {
+		bs, err = c.comments.apply(bs, root)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if c.opts.PreserveBlankLines {
+		bs = preserveBlankLines(doc, bs)
+	}
+
+	if len(raws) == 0 {
+		return bs, nil
+	}
+
+	lines := bytes.Split(bs, []byte("\n"))
+	for i, line := range lines {
+		for j, placeholder := range placeholders {
+			if spliced, ok := spliceRaw(string(line), placeholder, raws[j]); ok {
+				lines[i] = []byte(spliced)
+				break
+			}
+		}
+	}
+
+	return bytes.Join(lines, []byte("\n")), nil
+}
+
+// spliceRaw replaces the placeholder at the end of the line with the raw
+// value's text. The text of a map value is indented two spaces deeper than
+// the key, and that of a sequence element to the element's column. A block
+// map or sequence given as a map value begins on the line after the key.
+func spliceRaw(line, placeholder string, raw rawValue) (string, bool) {
+	prefix := strings.TrimSuffix(line, placeholder)
+	if prefix == line {
+		prefix = strings.TrimSuffix(line, `"`+placeholder+`"`)
+		if prefix == line {
+			return "", false
+		}
+	}
+
+	// The column of the key or element the value belongs to, past any
+	// indentation and the "- " of enclosing sequences
+	column := 0
+	for column < len(prefix) && (prefix[column] == ' ' || strings.HasPrefix(prefix[column:], "- ")) {
+		column++
+	}
+
+	lines := strings.Split(raw.text, "\n")
+
+	var indent string
+	switch trimmed := strings.TrimRight(prefix, " "); {
+	case strings.HasSuffix(trimmed, ":"):
+		indent = strings.Repeat(" ", column+2)
+
+		if raw.collection {
+			return trimmed + "\n" + indent + strings.Join(lines, "\n"+indent), true
+		}
+	case strings.TrimSpace(prefix) == "":
+		indent = prefix
+	default:
+		indent = strings.Repeat(" ", len(prefix))
+	}
+
+	return prefix + strings.Join(lines, "\n"+indent), true
+}
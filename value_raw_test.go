@@ -0,0 +1,87 @@
+package yamlpatch_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("value_raw", func() {
+	It("emits a map fragment as written beneath its key", func() {
+		actual, err := applyPatch(`---
+- op: add
+  path: /spec/template
+  value_raw: |
+    # the pod template
+    metadata: {labels: {app: web}}
+    containers:
+    - name: web
+      image: "nginx:1.25"
+`, "spec:\n  replicas: 1\n")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actual).To(Equal(`spec:
+  replicas: 1
+  template:
+    # the pod template
+    metadata: {labels: {app: web}}
+    containers:
+    - name: web
+      image: "nginx:1.25"
+`))
+	})
+
+	It("emits a scalar fragment on the key's line", func() {
+		actual, err := applyPatch(`---
+- op: add
+  path: /script
+  value_raw: |
+    |
+      echo one
+      echo two
+`, "a: 1\n")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actual).To(Equal("a: 1\nscript: |\n    echo one\n    echo two\n"))
+	})
+
+	It("indents a fragment appended to a sequence to the element's column", func() {
+		actual, err := applyPatch(`---
+- op: add
+  path: /items/-
+  value_raw: |
+    name: b   # added
+    value: 'two'
+`, "items:\n- name: a\n")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actual).To(Equal("items:\n- name: a\n- name: b   # added\n  value: 'two'\n"))
+	})
+
+	It("provides the parsed value to later operations", func() {
+		actual, err := applyPatch(`---
+- op: add
+  path: /b
+  value_raw: "x: 'quoted'"
+- op: copy
+  from: /b/x
+  path: /c
+`, "a: 1\n")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actual).To(Equal("a: 1\nb:\n  x: 'quoted'\nc: quoted\n"))
+	})
+
+	It("is marshaled like any other value when a later operation modifies it", func() {
+		actual, err := applyPatch(`---
+- op: add
+  path: /b
+  value_raw: "x: 'quoted'"
+- op: add
+  path: /b/z
+  value: 2
+`, "a: 1\n")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actual).To(Equal("a: 1\nb:\n  x: quoted\n  z: 2\n"))
+	})
+
+	It("cannot be combined with a value", func() {
+		_, err := applyPatch(`[{op: add, path: /b, value: 1, value_raw: "2"}]`, "a: 1\n")
+		Expect(err).To(MatchError("yamlpatch add operation does not apply: value_raw is mutually exclusive with value and value_from_file"))
+	})
+})
@@ -0,0 +1,62 @@
+package yamlpatch
+
+import "fmt"
+
+// valueTypes are the types a test operation's value_type can name, and the
+// kinds kindName returns for values of them
+var valueTypes = map[string]string{
+	"int":    "integer",
+	"float":  "float",
+	"string": "string",
+	"bool":   "bool",
+	"null":   "null",
+	"map":    "map",
+	"slice":  "array",
+}
+
+// unmarshalNullValueType sets the operation's value_type to null when it is
+// given unquoted, as in value_type: null, which decodes as the empty string
+func (o *Operation) unmarshalNullValueType(unmarshal func(interface{}) error) error {
+	if o.ValueType != "" {
+		return nil
+	}
+
+	var fields map[string]interface{}
+	err := unmarshal(&fields)
+	if err != nil {
+		return err
+	}
+
+	if v, ok := fields["value_type"]; ok && v == nil {
+		o.ValueType = "null"
+	}
+
+	return nil
+}
+
+// testValueType checks that the value at a test operation's path, as
+// decoded, has the type its value_type names, so that the integer 80 and the
+// string "80" are told apart
+func testValueType(op *Operation, val *Node) error {
+	want, ok := valueTypes[op.ValueType]
+	if !ok {
+		return fmt.Errorf("test operation does not apply: unknown value_type %s", op.ValueType)
+	}
+
+	if val == nil {
+		return fmt.Errorf("test failed: doc is missing key: %s", op.Path)
+	}
+
+	got := kindName(val.plain())
+	if got == want {
+		return nil
+	}
+
+	for name, kind := range valueTypes {
+		if kind == got {
+			got = name
+		}
+	}
+
+	return fmt.Errorf("test failed: %s is %s, not %s", pathOrRoot(string(op.Path)), got, op.ValueType)
+}
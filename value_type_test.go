@@ -0,0 +1,63 @@
+package yamlpatch_test
+
+import (
+	"fmt"
+
+	yamlpatch "github.com/krishicks/yaml-patch"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("value_type", func() {
+	doc := []byte(`---
+port: 80
+quoted: "80"
+ratio: 0.5
+enabled: true
+none: ~
+labels: {app: web}
+args: [a]
+`)
+
+	test := func(path, valueType string) error {
+		patch, err := yamlpatch.DecodePatch([]byte(fmt.Sprintf(`[{op: test, path: %s, value_type: %s}]`, path, valueType)))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = patch.Apply(doc)
+		return err
+	}
+
+	DescribeTable("passes when the value has the type",
+		func(path, valueType string) {
+			Expect(test(path, valueType)).To(Succeed())
+		},
+		Entry("int", "/port", "int"),
+		Entry("string", "/quoted", "string"),
+		Entry("float", "/ratio", "float"),
+		Entry("bool", "/enabled", "bool"),
+		Entry("null", "/none", "null"),
+		Entry("map", "/labels", "map"),
+		Entry("slice", "/args", "slice"),
+	)
+
+	DescribeTable("fails when it does not",
+		func(path, valueType, message string) {
+			Expect(test(path, valueType)).To(MatchError(message))
+		},
+		Entry("a quoted number", "/quoted", "int", "test failed: /quoted is string, not int"),
+		Entry("an int", "/port", "string", "test failed: /port is int, not string"),
+		Entry("a slice", "/args", "map", "test failed: /args is slice, not map"),
+		Entry("a missing key", "/missing", "null", "test failed: doc is missing key: /missing"),
+		Entry("an unknown type", "/port", "number", "test operation does not apply: unknown value_type number"),
+	)
+
+	It("combines with value", func() {
+		patch, err := yamlpatch.DecodePatch([]byte(`[{op: test, path: /port, value_type: int, value: 8080}]`))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = patch.Apply(doc)
+		Expect(err).To(MatchError("test failed"))
+	})
+})
@@ -0,0 +1,155 @@
+package yamlpatch
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// referenceRegex matches a reference to a captured variable, e.g.
+// {{var:tag}}, or to a path of the document, e.g. {{path:/spec/image}}
+var referenceRegex = regexp.MustCompile(`\{\{\s*(var|path):([^}\s]+)\s*\}\}`)
+
+// IsReservedPlaceholder returns whether the placeholder, such as {{var:tag}}
+// or {{index}}, is one that a patch resolves itself when it is applied: a
+// reference to a captured variable or to a path of the document, or the
+// index or key of the current item of a for_each operation. Tools that fill
+// in placeholders of their own before decoding a patch leave these alone.
+func IsReservedPlaceholder(placeholder string) bool {
+	for _, re := range []*regexp.Regexp{referenceRegex, itemRegex} {
+		loc := re.FindStringIndex(placeholder)
+		if loc != nil && loc[0] == 0 && loc[1] == len(placeholder) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// tryCapture binds the value at the operation's path to the variable named by
+// the operation's As field, for use by later operations in the patch
+func tryCapture(doc Container, op *Operation, ctx *applyContext) error {
+	if op.As == "" {
+		return fmt.Errorf("yamlpatch capture operation is missing a variable name: %s", op.Path)
+	}
+
+	con, key, err := findContainer(doc, &op.Path)
+	if err != nil {
+		return pathError(err, "yamlpatch capture operation does not apply: doc is missing path: %s", op.Path)
+	}
+
+	val, err := con.Get(key)
+	if val == nil || err != nil {
+		return fmt.Errorf("yamlpatch capture operation does not apply: doc is missing key: %s", op.Path)
+	}
+
+	ctx.vars[op.As] = val.plain()
+	return nil
+}
+
+// resolveReferences returns a Node with every reference within the given
+// node's value replaced by the value it refers to: {{var:name}} refers to a
+// captured variable and {{path:/a/b}} to the value at a path of the document
+// as it is when the operation is performed. A string that consists of only a
+// reference is replaced by the referenced value, keeping its type, so that a
+// map or sequence can be copied; references within a longer string are
+// interpolated. A reference to a variable that is not defined, or to a path
+// that does not exist, is an error. The original node is returned when it
+// contains no references.
+func resolveReferences(n *Node, ctx *applyContext) (*Node, error) {
+	if n.Empty() {
+		return n, nil
+	}
+
+	v, changed, err := resolveReferencesIn(n.plain(), ctx)
+	if err != nil || !changed {
+		return n, err
+	}
+
+	return NewNode(&v), nil
+}
+
+// lookupReference returns the value referred to by the kind and name of a
+// reference
+func lookupReference(kind, name string, ctx *applyContext) (interface{}, error) {
+	if kind == "var" {
+		val, ok := ctx.vars[name]
+		if !ok {
+			return nil, fmt.Errorf("undefined variable: %s", name)
+		}
+		return val, nil
+	}
+
+	var c Container
+	if ctx.root != nil {
+		c = ctx.root.Container()
+	}
+
+	path := OpPath(name)
+	if c == nil {
+		return nil, fmt.Errorf("referenced path does not exist: %s", path)
+	}
+
+	con, key, err := findContainer(c, &path)
+	if err != nil {
+		return nil, pathError(err, "referenced path does not exist: %s", path)
+	}
+
+	val, err := con.Get(key)
+	if val == nil || err != nil {
+		return nil, fmt.Errorf("referenced path does not exist: %s", path)
+	}
+
+	return val.plain(), nil
+}
+
+func resolveReferencesIn(v interface{}, ctx *applyContext) (interface{}, bool, error) {
+	switch it := v.(type) {
+	case string:
+		if m := referenceRegex.FindStringSubmatch(it); m != nil && m[0] == it {
+			val, err := lookupReference(m[1], m[2], ctx)
+			if err != nil {
+				return nil, false, err
+			}
+			return val, true, nil
+		}
+
+		var err error
+		s := referenceRegex.ReplaceAllStringFunc(it, func(ref string) string {
+			m := referenceRegex.FindStringSubmatch(ref)
+			val, lookupErr := lookupReference(m[1], m[2], ctx)
+			if lookupErr != nil {
+				err = lookupErr
+				return ref
+			}
+			return fmt.Sprint(val)
+		})
+
+		return s, s != it, err
+	case map[interface{}]interface{}:
+		m := make(map[interface{}]interface{}, len(it))
+		changed := false
+		for k, v := range it {
+			val, c, err := resolveReferencesIn(v, ctx)
+			if err != nil {
+				return nil, false, err
+			}
+			m[k] = val
+			changed = changed || c
+		}
+		return m, changed, nil
+	case []interface{}:
+		s := make([]interface{}, len(it))
+		changed := false
+		for i := range it {
+			val, c, err := resolveReferencesIn(it[i], ctx)
+			if err != nil {
+				return nil, false, err
+			}
+			s[i] = val
+			changed = changed || c
+		}
+		return s, changed, nil
+	}
+
+	return v, false, nil
+}
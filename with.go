@@ -0,0 +1,70 @@
+package yamlpatch
+
+import "fmt"
+
+// tryWith performs the nested operations of a with operation as a block, with
+// their paths relative to the value at the with operation's path. Like any
+// other path, it can select an element of an array by one of its fields, as
+// /spec/containers/name=app does, and a with operation whose path selects
+// several values is performed for each of them in turn.
+func tryWith(c Container, op *Operation, ctx *applyContext) error {
+	if op.Path != "" {
+		con, key, err := findContainer(c, &op.Path)
+		if err != nil {
+			return pathError(err, "yamlpatch with operation does not apply: doc is missing path: %s", op.Path)
+		}
+
+		val, err := con.Get(key)
+		if val == nil || err != nil {
+			return fmt.Errorf("yamlpatch with operation does not apply: doc is missing key: %s", op.Path)
+		}
+	}
+
+	block := *op
+	block.Operations = make(Patch, len(op.Operations))
+	for i, nested := range op.Operations {
+		block.Operations[i] = nested.rebased(string(op.Path))
+	}
+
+	return tryBlock(&block, ctx)
+}
+
+// rebased returns a copy of the operation with its paths, and those of the
+// operations of a nested block, relative to the given base path
+func (o Operation) rebased(base string) Operation {
+	// From, scope and for_each are left empty when they are not given
+	prefix := func(paths ...*OpPath) {
+		for _, path := range paths {
+			if *path != "" {
+				*path = OpPath(base + string(*path))
+			}
+		}
+	}
+
+	if o.Op == opBlock {
+		nested := make(Patch, len(o.Operations))
+		for i, op := range o.Operations {
+			nested[i] = op.rebased(base)
+		}
+		o.Operations = nested
+
+		return o
+	}
+
+	o.Paths = append([]OpPath(nil), o.Paths...)
+	o.Fallback = append([]OpPath(nil), o.Fallback...)
+
+	// The empty path refers to the base itself
+	o.Path = OpPath(base + string(o.Path))
+	prefix(&o.From, &o.Scope, &o.ForEach)
+
+	for i, path := range o.Paths {
+		o.Paths[i] = OpPath(base + string(path))
+	}
+
+	for i, path := range o.Fallback {
+		o.Fallback[i] = OpPath(base + string(path))
+	}
+
+	return o
+}
@@ -0,0 +1,118 @@
+package yamlpatch_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("with operations", func() {
+	containers := `spec:
+  containers:
+  - image: proxy:1
+    name: sidecar
+  - image: app:1
+    name: app
+`
+
+	It("performs the operations relative to the element the path selects", func() {
+		actual, err := applyPatch(`---
+- op: with
+  path: /spec/containers/name=app
+  operations:
+  - op: replace
+    path: /image
+    value: app:2
+  - op: add
+    path: /ports
+    value: [8080]
+  - op: copy
+    from: /name
+    path: /hostname
+`, containers)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actual).To(Equal(`spec:
+  containers:
+  - image: proxy:1
+    name: sidecar
+  - hostname: app
+    image: app:2
+    name: app
+    ports:
+    - 8080
+`))
+	})
+
+	It("refers to the element itself with the empty path", func() {
+		actual, err := applyPatch(`---
+- op: with
+  path: /spec/containers/name=sidecar
+  operations:
+  - op: test
+    path: /image
+    value: proxy:1
+  - op: replace
+    path: ""
+    value: {name: sidecar, image: proxy:2}
+`, containers)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actual).To(Equal(`spec:
+  containers:
+  - image: proxy:2
+    name: sidecar
+  - image: app:1
+    name: app
+`))
+	})
+
+	It("performs the operations for each element the path selects", func() {
+		actual, err := applyPatch(`---
+- op: with
+  path: /items/kind=Pod
+  operations:
+  - {op: add, path: /metadata/labels, value: {app: web}}
+`, `items:
+- {kind: Pod, metadata: {name: a}}
+- {kind: Service, metadata: {name: b}}
+- {kind: Pod, metadata: {name: c}}
+`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actual).To(Equal(`items:
+- kind: Pod
+  metadata:
+    labels:
+      app: web
+    name: a
+- kind: Service
+  metadata:
+    name: b
+- kind: Pod
+  metadata:
+    labels:
+      app: web
+    name: c
+`))
+	})
+
+	It("rolls back like a block", func() {
+		actual, err := applyPatch(`---
+- op: with
+  path: /spec/containers/name=app
+  on_error: rollback
+  operations:
+  - {op: replace, path: /image, value: app:2}
+  - {op: remove, path: /missing}
+`, containers)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actual).To(Equal(containers))
+	})
+
+	It("fails when the path selects nothing", func() {
+		_, err := applyPatch(`[{op: with, path: /spec/missing, operations: [{op: remove, path: /a}]}]`, containers)
+		Expect(err).To(MatchError("yamlpatch with operation does not apply: doc is missing key: /spec/missing"))
+	})
+
+	It("fails naming the nested operation that failed", func() {
+		_, err := applyPatch(`[{op: with, path: /spec/containers/name=app, operations: [{op: remove, path: /missing}]}]`, containers)
+		Expect(err).To(MatchError(HavePrefix("yamlpatch with operation failed at operation 0: ")))
+	})
+})
@@ -0,0 +1,56 @@
+package yamlpatch
+
+import "fmt"
+
+// tryWrap replaces the value at the operation's path with an array holding
+// it as its only element. An array is left as it is, so that wrapping values
+// that are sometimes arrays normalizes them.
+func tryWrap(doc Container, op *Operation) error {
+	con, key, err := findContainer(doc, &op.Path)
+	if err != nil {
+		return pathError(err, "yamlpatch wrap operation does not apply: doc is missing path: %s", op.Path)
+	}
+
+	val, err := con.Get(key)
+	if val == nil || err != nil {
+		return fmt.Errorf("yamlpatch wrap operation does not apply: doc is missing key: %s", op.Path)
+	}
+
+	if _, ok := val.Container().(*nodeSlice); ok {
+		return nil
+	}
+
+	var v interface{} = []interface{}{val.plain()}
+	return con.Set(key, NewNode(&v))
+}
+
+// tryUnwrap replaces the array at the operation's path with its only element.
+// An array with more elements fails unless the operation takes the first of
+// them, and an empty array always fails. A value that is not an array is left
+// as it is, so that unwrapping values that are sometimes arrays normalizes
+// them.
+func tryUnwrap(doc Container, op *Operation) error {
+	con, key, err := findContainer(doc, &op.Path)
+	if err != nil {
+		return pathError(err, "yamlpatch unwrap operation does not apply: doc is missing path: %s", op.Path)
+	}
+
+	val, err := con.Get(key)
+	if val == nil || err != nil {
+		return fmt.Errorf("yamlpatch unwrap operation does not apply: doc is missing key: %s", op.Path)
+	}
+
+	ary, ok := val.Container().(*nodeSlice)
+	if !ok {
+		return nil
+	}
+
+	switch {
+	case len(*ary) == 0:
+		return fmt.Errorf("yamlpatch unwrap operation does not apply: array is empty: %s", pathOrRoot(string(op.Path)))
+	case len(*ary) > 1 && !op.TakeFirst:
+		return fmt.Errorf("yamlpatch unwrap operation does not apply: array has %d elements, not 1: %s", len(*ary), pathOrRoot(string(op.Path)))
+	}
+
+	return con.Set(key, (*ary)[0])
+}
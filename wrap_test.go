@@ -0,0 +1,42 @@
+package yamlpatch_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("wrap and unwrap", func() {
+	DescribeTable("wrap replaces a value with an array holding it",
+		func(doc, expected string) {
+			actual, err := applyPatch(`[{op: wrap, path: /args}]`, doc)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(actual).To(Equal(expected))
+		},
+		Entry("for a scalar", "args: foo\n", "args:\n- foo\n"),
+		Entry("for a map", "args: {a: 1}\n", "args:\n- a: 1\n"),
+		Entry("leaving an array as it is", "args: [foo, bar]\n", "args:\n- foo\n- bar\n"),
+	)
+
+	DescribeTable("unwrap replaces an array of one element with the element",
+		func(ops, doc, expected string) {
+			actual, err := applyPatch(ops, doc)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(actual).To(Equal(expected))
+		},
+		Entry("for an array of one element", `[{op: unwrap, path: /args}]`, "args: [foo]\n", "args: foo\n"),
+		Entry("taking the first of several elements", `[{op: unwrap, path: /args, take_first: true}]`, "args: [foo, bar]\n", "args: foo\n"),
+		Entry("leaving a scalar as it is", `[{op: unwrap, path: /args}]`, "args: foo\n", "args: foo\n"),
+		Entry("undoing wrap", `[{op: wrap, path: /args}, {op: unwrap, path: /args}]`, "args: {a: 1}\n", "args:\n  a: 1\n"),
+	)
+
+	DescribeTable("unwrap fails",
+		func(ops, doc, message string) {
+			_, err := applyPatch(ops, doc)
+			Expect(err).To(MatchError(message))
+		},
+		Entry("for an array of several elements", `[{op: unwrap, path: /args}]`, "args: [foo, bar]\n", "yamlpatch unwrap operation does not apply: array has 2 elements, not 1: /args"),
+		Entry("for an empty array", `[{op: unwrap, path: /args, take_first: true}]`, "args: []\n", "yamlpatch unwrap operation does not apply: array is empty: /args"),
+		Entry("for a missing key", `[{op: unwrap, path: /other}]`, "args: []\n", "yamlpatch unwrap operation does not apply: doc is missing key: /other"),
+	)
+})
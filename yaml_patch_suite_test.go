@@ -1,6 +1,8 @@
 package yamlpatch_test
 
 import (
+	yamlpatch "github.com/krishicks/yaml-patch"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
@@ -11,3 +13,12 @@ func TestYamlPatch(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "YamlPatch Suite")
 }
+
+// applyPatch decodes the operations and applies them to the document
+func applyPatch(ops, doc string) (string, error) {
+	patch, err := yamlpatch.DecodePatch([]byte(ops))
+	Expect(err).NotTo(HaveOccurred())
+
+	actual, err := patch.Apply([]byte(doc))
+	return string(actual), err
+}